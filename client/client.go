@@ -0,0 +1,236 @@
+// Package client is the Go client of Meteor. It talks the binary protocol, keeps a pool of connections and
+// offers typed methods for the common commands and transactions bound to one connection.
+//
+//	c, err := client.New("localhost:7653", nil)
+//	...
+//	err = c.Put(ctx, "user:1", "alice")
+//	value, err := c.Get(ctx, "user:1")
+//
+//	err = c.RunTx(ctx, client.SnapshotIsolation, func(tx *client.Tx) error {
+//		balance, err := tx.Get(ctx, "balance")
+//		...
+//		return tx.Put(ctx, "balance", newBalance)
+//	})
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"meteor/internal/clientapi"
+	"sync"
+	"time"
+)
+
+// Options configures a Client. Zero values use the defaults.
+type Options struct {
+	// PoolSize is the maximum number of open connections, 10 by default
+	PoolSize int
+	// DialTimeout limits connecting to the server, 5s by default
+	DialTimeout time.Duration
+	// MaxRetries is how often a single command or RunTx is retried after a write-write conflict or deadlock,
+	// 3 by default. Negative values disable retries.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, doubled for every further retry. 10ms by default.
+	RetryBackoff time.Duration
+	// Username and Password authenticate every connection with AUTH, if the server has users
+	Username string
+	Password string
+	// TLSConfig connects with TLS if set. Its certificates authenticate the client with servers requiring mTLS.
+	TLSConfig *tls.Config
+}
+
+// Client is a pool of connections to a Meteor server. It is safe for concurrent use.
+type Client struct {
+	addr string
+	opts Options
+
+	// slots holds a value for every connection in use, so at most PoolSize are open
+	slots chan struct{}
+	idle  chan *conn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// KeyValue is a key and its value, as returned by Scan and RGet
+type KeyValue = clientapi.KeyValue
+
+// Page is a page of results. Cursor is set when a limit cut the results short and continues after the page.
+type Page = clientapi.Page
+
+// ReadOptions page the results of Scan and RGet
+type ReadOptions = clientapi.ReadOptions
+
+// New returns a client of the server at addr. Connections are opened when they are needed.
+func New(addr string, opts *Options) (*Client, error) {
+	if addr == "" {
+		return nil, errors.New("meteor: address is empty")
+	}
+
+	c := &Client{addr: addr}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.PoolSize <= 0 {
+		c.opts.PoolSize = 10
+	}
+	if c.opts.DialTimeout <= 0 {
+		c.opts.DialTimeout = 5 * time.Second
+	}
+	if c.opts.MaxRetries == 0 {
+		c.opts.MaxRetries = 3
+	}
+	if c.opts.RetryBackoff <= 0 {
+		c.opts.RetryBackoff = 10 * time.Millisecond
+	}
+
+	c.slots = make(chan struct{}, c.opts.PoolSize)
+	c.idle = make(chan *conn, c.opts.PoolSize)
+	return c, nil
+}
+
+// Close closes the idle connections. Connections in use are closed when they are released.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) acquire(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	cn, err := dial(ctx, c.addr, c.opts.DialTimeout, c.opts.TLSConfig)
+	if err == nil && c.opts.Username != "" {
+		// The server keeps the user of a connection, so each connection authenticates once
+		if _, err = cn.do(ctx, "AUTH", c.opts.Username, c.opts.Password); err != nil {
+			cn.close()
+		}
+	}
+	if err != nil {
+		<-c.slots
+		return nil, err
+	}
+	return cn, nil
+}
+
+// release returns a connection to the pool, or closes it if it is broken or the client is closed
+func (c *Client) release(cn *conn) {
+	defer func() { <-c.slots }()
+
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if cn.broken || closed {
+		cn.close()
+		return
+	}
+
+	select {
+	case c.idle <- cn:
+	default:
+		cn.close()
+	}
+}
+
+// Do runs any command on a pooled connection and returns its text result
+func (c *Client) Do(ctx context.Context, name string, args ...string) (string, error) {
+	var result string
+	err := c.retry(ctx, func() error {
+		cn, err := c.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer c.release(cn)
+
+		result, err = cn.do(ctx, name, args...)
+		return err
+	})
+	return result, err
+}
+
+// retry runs fn again after write-write conflicts and deadlocks, up to MaxRetries times
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	backoff := c.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isRetryable(err) || attempt >= c.opts.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// Get returns the value of a key, or ErrNotFound. Numbers, bools and JSON documents are returned as their JSON text.
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	return clientapi.Get(bind(ctx, c.Do), key)
+}
+
+// Put sets the value of a key
+func (c *Client) Put(ctx context.Context, key, value string) error {
+	_, err := c.Do(ctx, "PUT", key, value)
+	return err
+}
+
+// Delete deletes a key
+func (c *Client) Delete(ctx context.Context, key string) error {
+	_, err := c.Do(ctx, "DELETE", key)
+	return err
+}
+
+// Scan returns the keys and values matching a condition, e.g. "$key LIKE 'user:%' AND $value > 10", in key order
+func (c *Client) Scan(ctx context.Context, condition string, opts *ReadOptions) (*Page, error) {
+	return clientapi.ReadPage(bind(ctx, c.Do), "SCAN", []string{condition}, opts)
+}
+
+// RGet returns the keys and values from startKey to endKey, both included, in key order
+func (c *Client) RGet(ctx context.Context, startKey, endKey string, opts *ReadOptions) (*Page, error) {
+	return clientapi.ReadPage(bind(ctx, c.Do), "RGET", []string{startKey, endKey}, opts)
+}
+
+// Count returns the number of keys matching a condition, "*" counts all keys
+func (c *Client) Count(ctx context.Context, condition string) (int64, error) {
+	return clientapi.Count(bind(ctx, c.Do), condition)
+}
+
+// doFunc sends a command, either on a pooled connection or in a transaction
+type doFunc func(ctx context.Context, name string, args ...string) (string, error)
+
+// bind runs the commands of the shared typed methods with ctx
+func bind(ctx context.Context, do doFunc) clientapi.ExecFunc {
+	return func(name string, args ...string) (string, error) {
+		return do(ctx, name, args...)
+	}
+}
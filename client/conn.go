@@ -0,0 +1,144 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"meteor/internal/parser"
+	"net"
+	"time"
+)
+
+// conn is a connection speaking the binary protocol. Requests are sent one at a time, so a conn is only used
+// by one caller, which the pool ensures.
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	nextId  uint32
+	// broken is set when a request was interrupted, the connection may still have a response on the way
+	broken bool
+}
+
+func dial(ctx context.Context, addr string, timeout time.Duration, tlsConfig *tls.Config) (*conn, error) {
+	netDialer := &net.Dialer{Timeout: timeout}
+	var netConn net.Conn
+	var err error
+	if tlsConfig != nil {
+		netConn, err = (&tls.Dialer{NetDialer: netDialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		netConn, err = netDialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &conn{netConn: netConn, reader: bufio.NewReader(netConn)}
+	if err := c.handshake(ctx, timeout); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *conn) handshake(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	c.netConn.SetDeadline(deadline)
+	defer c.netConn.SetDeadline(time.Time{})
+
+	hello := append([]byte{}, parser.BinaryMagic[:]...)
+	hello = binary.BigEndian.AppendUint16(hello, parser.BinaryProtocolVersion)
+	hello = binary.BigEndian.AppendUint16(hello, parser.BinaryProtocolVersion)
+	if _, err := c.netConn.Write(hello); err != nil {
+		return err
+	}
+
+	var reply [6]byte
+	if _, err := io.ReadFull(c.reader, reply[:]); err != nil {
+		return err
+	}
+	if [4]byte(reply[:4]) != parser.BinaryMagic {
+		return errors.New("meteor: server doesn't speak the binary protocol")
+	}
+	if version := binary.BigEndian.Uint16(reply[4:]); version != parser.BinaryProtocolVersion {
+		return fmt.Errorf("meteor: server doesn't support protocol version %d", parser.BinaryProtocolVersion)
+	}
+	return nil
+}
+
+// do sends a command and waits for its result. The deadline and cancellation of ctx interrupt the request,
+// which leaves the connection broken.
+func (c *conn) do(ctx context.Context, name string, args ...string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		c.netConn.SetDeadline(deadline)
+		defer c.netConn.SetDeadline(time.Time{})
+	}
+	stop := context.AfterFunc(ctx, func() {
+		c.netConn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	result, err := c.roundTrip(name, args)
+	if err != nil {
+		var serverError *Error
+		if errors.As(err, &serverError) {
+			return "", err
+		}
+		c.broken = true
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	return result, nil
+}
+
+func (c *conn) roundTrip(name string, args []string) (string, error) {
+	c.nextId++
+	requestId := c.nextId
+
+	encodedArgs := make([]any, len(args))
+	for i, arg := range args {
+		encodedArgs[i] = arg
+	}
+	frame, err := parser.EncodeBinaryRequest(requestId, 0, name, encodedArgs...)
+	if err != nil {
+		return "", err
+	}
+	if _, err := c.netConn.Write(frame); err != nil {
+		return "", err
+	}
+
+	body, err := parser.ReadBinaryFrame(c.reader, parser.MaxBinaryResponseLength)
+	if err != nil {
+		return "", err
+	}
+	if len(body) < 5 {
+		return "", errors.New("meteor: truncated response")
+	}
+	if binary.BigEndian.Uint32(body) != requestId {
+		return "", errors.New("meteor: response to another request")
+	}
+
+	payload := body[5:]
+	if body[4] == parser.BinaryStatusOk {
+		return string(payload), nil
+	}
+	if len(payload) < 2 {
+		return "", errors.New("meteor: truncated error response")
+	}
+	return "", &Error{Code: ErrorCode(binary.BigEndian.Uint16(payload)), Message: string(payload[2:])}
+}
+
+func (c *conn) close() error {
+	return c.netConn.Close()
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"errors"
+	"meteor/internal/clientapi"
+	"meteor/internal/common"
+)
+
+// ErrorCode classifies the errors returned by the server
+type ErrorCode = common.ErrorCode
+
+const (
+	CodeGeneric         = common.ErrorCodeGeneric
+	CodeUnknownCommand  = common.ErrorCodeUnknownCommand
+	CodeInvalidArgument = common.ErrorCodeInvalidArgument
+	CodeTransaction     = common.ErrorCodeTransaction
+	CodeConflict        = common.ErrorCodeConflict
+	CodeDeadlock        = common.ErrorCodeDeadlock
+	CodeLockTimeout     = common.ErrorCodeLockTimeout
+	CodeConditionNotMet = common.ErrorCodeConditionNotMet
+	CodeProtocol        = common.ErrorCodeProtocol
+	CodeAuthentication  = common.ErrorCodeAuthentication
+	CodePermission      = common.ErrorCodePermission
+)
+
+// Error is an error returned by the server. It matches the Err* values of its code with errors.Is, e.g.
+// errors.Is(err, client.ErrConflict).
+type Error = clientapi.Error
+
+var (
+	ErrUnknownCommand  = clientapi.ErrUnknownCommand
+	ErrInvalidArgument = clientapi.ErrInvalidArgument
+	ErrTransaction     = clientapi.ErrTransaction
+	ErrConflict        = clientapi.ErrConflict
+	ErrDeadlock        = clientapi.ErrDeadlock
+	ErrLockTimeout     = clientapi.ErrLockTimeout
+	ErrConditionNotMet = clientapi.ErrConditionNotMet
+	ErrProtocol        = clientapi.ErrProtocol
+	// ErrAuthentication is returned for wrong credentials and by servers with users if none were given
+	ErrAuthentication = clientapi.ErrAuthentication
+	// ErrPermissionDenied is returned for commands the user isn't granted on their keys
+	ErrPermissionDenied = clientapi.ErrPermissionDenied
+
+	// ErrNotFound is returned by Get for keys that don't exist
+	ErrNotFound = clientapi.ErrNotFound
+	// ErrClosed is returned by the methods of a closed Client
+	ErrClosed = errors.New("meteor: client is closed")
+	// ErrTxDone is returned by the methods of a Tx after Commit or Rollback
+	ErrTxDone = clientapi.ErrTxDone
+)
+
+// isRetryable reports whether a transaction failed only because of concurrent transactions, so running it
+// again may succeed. The server rolls the transaction back on these errors.
+func isRetryable(err error) bool {
+	return errors.Is(err, ErrConflict) || errors.Is(err, ErrDeadlock)
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&Error{Code: CodeConflict, Message: "write-write conflict"}, true},
+		{&Error{Code: CodeDeadlock, Message: "deadlock detected"}, true},
+		{fmt.Errorf("commit: %w", &Error{Code: CodeConflict}), true},
+		{&Error{Code: CodeLockTimeout, Message: "lock timeout"}, false},
+		{&Error{Code: CodeInvalidArgument, Message: "invalid"}, false},
+		{ErrClosed, false},
+		{errors.New("connection reset"), false},
+	}
+
+	for _, test := range tests {
+		if got := isRetryable(test.err); got != test.want {
+			t.Errorf("isRetryable(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
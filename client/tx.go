@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"meteor/internal/clientapi"
+	"meteor/internal/common"
+	"time"
+)
+
+// Isolation is the isolation level of a transaction
+type Isolation = clientapi.Isolation
+
+const (
+	ReadCommitted     = clientapi.ReadCommitted
+	RepeatableRead    = clientapi.RepeatableRead
+	SnapshotIsolation = clientapi.SnapshotIsolation
+	Serializable      = clientapi.Serializable
+)
+
+// Tx is a transaction. Transactions belong to the connection they were started on, so a Tx holds a pooled
+// connection until Commit or Rollback. A Tx must not be used concurrently.
+type Tx struct {
+	client        *Client
+	conn          *conn
+	transactionId string
+}
+
+// Begin starts a transaction. Commit or Rollback must be called to release its connection.
+func (c *Client) Begin(ctx context.Context, isolation Isolation) (*Tx, error) {
+	cn, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	if isolation != "" {
+		args = append(args, string(isolation))
+	}
+	transactionId, err := cn.do(ctx, "BEGIN", args...)
+	if err != nil {
+		c.release(cn)
+		return nil, err
+	}
+	return &Tx{client: c, conn: cn, transactionId: transactionId}, nil
+}
+
+// RunTx runs fn in a transaction and commits it. If fn returns an error the transaction is rolled back.
+// Transactions failing with a write-write conflict or deadlock are run again, up to MaxRetries times, so
+// fn must not have side effects besides the transaction.
+func (c *Client) RunTx(ctx context.Context, isolation Isolation, fn func(tx *Tx) error) error {
+	return c.retry(ctx, func() error {
+		tx, err := c.Begin(ctx, isolation)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			tx.Rollback(ctx)
+			return err
+		}
+		return tx.Commit(ctx)
+	})
+}
+
+// Do runs any command in the transaction and returns its text result. The transaction id is appended to args, after TXN for MGET, MDEL and AGG.
+func (tx *Tx) Do(ctx context.Context, name string, args ...string) (string, error) {
+	if tx.conn == nil {
+		return "", ErrTxDone
+	}
+	result, err := tx.conn.do(ctx, name, common.AppendTransactionId(name, args, tx.transactionId)...)
+	if tx.conn.broken {
+		// The connection and with it the transaction is lost
+		tx.release()
+	}
+	return result, err
+}
+
+// Commit commits the transaction. The transaction can't be used afterwards, even if the commit failed.
+func (tx *Tx) Commit(ctx context.Context) error {
+	return tx.end(ctx, "COMMIT")
+}
+
+// Rollback rolls the transaction back. Transactions the server already rolled back after an error are
+// rolled back without error.
+func (tx *Tx) Rollback(ctx context.Context) error {
+	err := tx.end(ctx, "ROLLBACK")
+	if errors.Is(err, ErrTransaction) {
+		return nil
+	}
+	return err
+}
+
+func (tx *Tx) end(ctx context.Context, operation string) error {
+	if tx.conn == nil {
+		return ErrTxDone
+	}
+	defer tx.release()
+
+	// Ending the transaction releases its locks, so it is attempted briefly even if ctx is already done
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+	}
+	_, err := tx.conn.do(ctx, operation, tx.transactionId)
+	return err
+}
+
+func (tx *Tx) release() {
+	if tx.conn != nil {
+		tx.client.release(tx.conn)
+		tx.conn = nil
+	}
+}
+
+// Get returns the value of a key as seen by the transaction, or ErrNotFound
+func (tx *Tx) Get(ctx context.Context, key string) (string, error) {
+	return clientapi.Get(bind(ctx, tx.Do), key)
+}
+
+// Put sets the value of a key when the transaction commits
+func (tx *Tx) Put(ctx context.Context, key, value string) error {
+	_, err := tx.Do(ctx, "PUT", key, value)
+	return err
+}
+
+// Delete deletes a key when the transaction commits
+func (tx *Tx) Delete(ctx context.Context, key string) error {
+	_, err := tx.Do(ctx, "DELETE", key)
+	return err
+}
+
+// Scan returns the keys and values matching a condition as seen by the transaction
+func (tx *Tx) Scan(ctx context.Context, condition string, opts *ReadOptions) (*Page, error) {
+	return clientapi.ReadPage(bind(ctx, tx.Do), "SCAN", []string{condition}, opts)
+}
+
+// RGet returns the keys and values from startKey to endKey as seen by the transaction
+func (tx *Tx) RGet(ctx context.Context, startKey, endKey string, opts *ReadOptions) (*Page, error) {
+	return clientapi.ReadPage(bind(ctx, tx.Do), "RGET", []string{startKey, endKey}, opts)
+}
+
+// Count returns the number of keys matching a condition as seen by the transaction
+func (tx *Tx) Count(ctx context.Context, condition string) (int64, error) {
+	return clientapi.Count(bind(ctx, tx.Do), condition)
+}
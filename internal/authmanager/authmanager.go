@@ -0,0 +1,327 @@
+package authmanager
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meteor/internal/common"
+	"meteor/internal/parser"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Command categories. Grants give a category of commands on the keys of a prefix.
+const (
+	CategoryRead  = "read"
+	CategoryWrite = "write"
+	CategoryAdmin = "admin"
+	// CategorySession commands, e.g. the transaction commands, are allowed for every authenticated user
+	CategorySession = "session"
+	// CategoryPublic commands, i.e. AUTH, are allowed before authenticating
+	CategoryPublic = "public"
+)
+
+// Categories are the categories that can be granted
+var Categories = []string{CategoryRead, CategoryWrite, CategoryAdmin}
+
+var (
+	ErrAuthenticationRequired = errors.New("authentication required")
+	ErrInvalidCredentials     = errors.New("invalid username or password")
+)
+
+// Grant allows the commands of some categories on the keys starting with Prefix. An empty prefix covers all keys.
+type Grant struct {
+	Categories []string `json:"categories"`
+	Prefix     string   `json:"prefix"`
+}
+
+// User is a user of the system keyspace. Only a salted hash of the password is kept.
+type User struct {
+	Name       string  `json:"name"`
+	Salt       []byte  `json:"salt"`
+	Hash       []byte  `json:"hash"`
+	Iterations int     `json:"iterations"`
+	Grants     []Grant `json:"grants"`
+}
+
+// KeyRange is an inclusive range of keys a command accesses
+type KeyRange struct {
+	Start string
+	End   string
+}
+
+// AuthManager keeps the users and which user each connection authenticated as. Users live in the system
+// keyspace: they are logged to the WAL as PUT_USER and DELETE_USER rows and are never visible to data commands.
+type AuthManager struct {
+	enforce bool
+
+	mu       sync.RWMutex
+	users    map[string]*User
+	sessions map[*net.Conn]string
+	// verified holds a fast digest of the last password each user authenticated with, so repeated logins,
+	// e.g. of HTTP requests, skip the slow hash
+	verified map[string][32]byte
+}
+
+// NewAuthManager returns an AuthManager. Access control is only enforced if enforce is set and a user exists,
+// so a new database is open until its first user is added.
+func NewAuthManager(enforce bool) *AuthManager {
+	return &AuthManager{
+		enforce:  enforce,
+		users:    make(map[string]*User),
+		sessions: make(map[*net.Conn]string),
+		verified: make(map[string][32]byte),
+	}
+}
+
+// Enabled reports whether commands must be run by an authenticated user
+func (am *AuthManager) Enabled() bool {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	return am.enforce && len(am.users) > 0
+}
+
+// NewUser returns a user with a salted hash of the password. The user isn't stored until its PUT_USER row is applied.
+func NewUser(name, password string, grants []Grant) (*User, error) {
+	if name == "" || strings.ContainsAny(name, " \t\r\n") {
+		return nil, fmt.Errorf("invalid user name %q", name)
+	}
+	if password == "" {
+		return nil, errors.New("password can't be empty")
+	}
+
+	salt, err := newSalt()
+	if err != nil {
+		return nil, err
+	}
+	return &User{
+		Name:       name,
+		Salt:       salt,
+		Hash:       hashPassword(password, salt, passwordIterations),
+		Iterations: passwordIterations,
+		Grants:     grants,
+	}, nil
+}
+
+// User returns a copy of a user
+func (am *AuthManager) User(name string) (*User, bool) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	user, ok := am.users[name]
+	if !ok {
+		return nil, false
+	}
+	return user.clone(), true
+}
+
+// Users returns copies of all users sorted by name
+func (am *AuthManager) Users() []*User {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	users := make([]*User, 0, len(am.users))
+	for _, user := range am.users {
+		users = append(users, user.clone())
+	}
+	slices.SortFunc(users, func(a, b *User) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return users
+}
+
+func (u *User) clone() *User {
+	clone := *u
+	clone.Grants = make([]Grant, len(u.Grants))
+	for i, grant := range u.Grants {
+		clone.Grants[i] = Grant{Categories: slices.Clone(grant.Categories), Prefix: grant.Prefix}
+	}
+	return &clone
+}
+
+// Authenticate checks the password of a user
+func (am *AuthManager) Authenticate(name, password string) error {
+	am.mu.RLock()
+	user, ok := am.users[name]
+	verified, isVerified := am.verified[name]
+	am.mu.RUnlock()
+	if !ok {
+		// Hash anyway, so the response time doesn't tell which users exist
+		hashPassword(password, make([]byte, saltLength), passwordIterations)
+		return ErrInvalidCredentials
+	}
+
+	digest := sha256.Sum256(append(slices.Clone(user.Hash), password...))
+	if isVerified && subtle.ConstantTimeCompare(digest[:], verified[:]) == 1 {
+		return nil
+	}
+	if subtle.ConstantTimeCompare(hashPassword(password, user.Salt, user.Iterations), user.Hash) != 1 {
+		return ErrInvalidCredentials
+	}
+
+	am.mu.Lock()
+	am.verified[name] = digest
+	am.mu.Unlock()
+	return nil
+}
+
+// Login makes conn run its commands as the user
+func (am *AuthManager) Login(conn *net.Conn, name string) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.sessions[conn] = name
+}
+
+// Logout forgets the user of a connection, e.g. when it is closed
+func (am *AuthManager) Logout(conn *net.Conn) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	delete(am.sessions, conn)
+}
+
+// SessionUser returns the user a connection authenticated as
+func (am *AuthManager) SessionUser(conn *net.Conn) (string, bool) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	name, ok := am.sessions[conn]
+	return name, ok
+}
+
+// Authorize checks that the user of a connection may run a command of the category on the keys returned by
+// keys. It returns the user, empty if access control isn't enabled. keys is only called for users whose
+// grants of the category don't cover all keys. Commands without keys pass nil and need a grant on all keys.
+func (am *AuthManager) Authorize(conn *net.Conn, category string, keys func() []KeyRange) (string, error) {
+	if !am.Enabled() {
+		return "", nil
+	}
+
+	am.mu.RLock()
+	name, ok := am.sessions[conn]
+	user := am.users[name]
+	var prefixes []string
+	if user != nil {
+		for _, grant := range user.Grants {
+			if slices.Contains(grant.Categories, category) {
+				prefixes = append(prefixes, grant.Prefix)
+			}
+		}
+	}
+	am.mu.RUnlock()
+
+	if category == CategoryPublic {
+		return name, nil
+	}
+	if !ok || user == nil {
+		return "", ErrAuthenticationRequired
+	}
+	if category == CategorySession || slices.Contains(prefixes, "") {
+		return name, nil
+	}
+	if len(prefixes) == 0 {
+		return "", fmt.Errorf("permission denied - user %s has no %s access", name, category)
+	}
+	if keys == nil {
+		return "", fmt.Errorf("permission denied - user %s has no %s access to all keys", name, category)
+	}
+
+	for _, keyRange := range keys() {
+		// Contradicting conditions select no keys
+		if keyRange.Start > keyRange.End {
+			continue
+		}
+		if !coveredByPrefix(keyRange, prefixes) {
+			return "", fmt.Errorf("permission denied - user %s has no %s access to %s", name, category, describeRange(keyRange))
+		}
+	}
+	return name, nil
+}
+
+// coveredByPrefix checks that all keys of a range start with one of the prefixes. Keys between two keys with
+// the same prefix have that prefix as well, so checking the bounds is enough.
+func coveredByPrefix(keyRange KeyRange, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(keyRange.Start, prefix) && strings.HasPrefix(keyRange.End, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeRange(keyRange KeyRange) string {
+	switch {
+	case keyRange.Start == keyRange.End:
+		return fmt.Sprintf("key %q", keyRange.Start)
+	case keyRange.Start == "" && keyRange.End == parser.MaxKey:
+		return "all keys"
+	case keyRange.End == keyRange.Start+parser.MaxKey:
+		return fmt.Sprintf("keys with prefix %q", keyRange.Start)
+	case keyRange.End == parser.MaxKey:
+		return fmt.Sprintf("keys from %q", keyRange.Start)
+	}
+	return fmt.Sprintf("keys %q to %q", keyRange.Start, keyRange.End)
+}
+
+// ToTransactionRow encodes the user as a PUT_USER or DELETE_USER row. The key holds the user name and the
+// new value the user as JSON, so users are restored when the WAL is replayed.
+func (u *User) ToTransactionRow(transactionId uint32, operation string, gsn uint32) (*common.TransactionRow, error) {
+	encoded, err := json.Marshal(u)
+	if err != nil {
+		return nil, err
+	}
+	return common.NewTransactionRow(transactionId, operation, common.TRANSACTION_STATE_COMMIT, &common.K{Key: u.Name, Gsn: gsn}, nil, &common.V{Type: common.TypeJson, Value: encoded}), nil
+}
+
+// ApplyUserTxnRow adds, updates or removes the user of a PUT_USER or DELETE_USER row. Connections of a
+// removed user are logged out.
+func (am *AuthManager) ApplyUserTxnRow(row *common.TransactionRow) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	return am.applyUserTxnRow(row)
+}
+
+// UpdateUser changes a user while holding the lock of the AuthManager, so concurrent changes of users don't
+// overwrite each other. update receives a copy of the user, nil if it doesn't exist, and the number of users,
+// and returns the PUT_USER or DELETE_USER row it logged, which is then applied.
+func (am *AuthManager) UpdateUser(name string, update func(user *User, userCount int) (*common.TransactionRow, error)) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	var user *User
+	if existing, ok := am.users[name]; ok {
+		user = existing.clone()
+	}
+	row, err := update(user, len(am.users))
+	if err != nil {
+		return err
+	}
+	return am.applyUserTxnRow(row)
+}
+
+func (am *AuthManager) applyUserTxnRow(row *common.TransactionRow) error {
+	if row.Operation != common.DB_OP_PUT_USER && row.Operation != common.DB_OP_DELETE_USER {
+		return errors.New("not a user operation: " + row.Operation)
+	}
+	if row.Payload.NewValue == nil {
+		return errors.New("user operation without user")
+	}
+
+	var user User
+	if err := json.Unmarshal(row.Payload.NewValue.Value, &user); err != nil {
+		return err
+	}
+
+	delete(am.verified, user.Name)
+	if row.Operation == common.DB_OP_DELETE_USER {
+		delete(am.users, user.Name)
+		for conn, name := range am.sessions {
+			if name == user.Name {
+				delete(am.sessions, conn)
+			}
+		}
+		return nil
+	}
+	am.users[user.Name] = &user
+	return nil
+}
@@ -0,0 +1,150 @@
+package authmanager
+
+import (
+	"errors"
+	"meteor/internal/common"
+	"meteor/internal/parser"
+	"net"
+	"testing"
+)
+
+func TestCoveredByPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		keyRange KeyRange
+		prefixes []string
+		want     bool
+	}{
+		{"single key", KeyRange{"user:1", "user:1"}, []string{"user:"}, true},
+		{"key of another prefix", KeyRange{"order:1", "order:1"}, []string{"user:"}, false},
+		{"range within the prefix", KeyRange{"user:a", "user:z"}, []string{"user:"}, true},
+		{"whole prefix", KeyRange{"user:", "user:" + parser.MaxKey}, []string{"user:"}, true},
+		{"range starting before the prefix", KeyRange{"user", "user:z"}, []string{"user:"}, false},
+		{"range ending after the prefix", KeyRange{"user:a", "user;"}, []string{"user:"}, false},
+		{"range across two prefixes", KeyRange{"a:1", "b:1"}, []string{"a:", "b:"}, false},
+		{"second prefix", KeyRange{"b:1", "b:2"}, []string{"a:", "b:"}, true},
+		{"empty prefix", KeyRange{"", parser.MaxKey}, []string{""}, true},
+		{"no prefixes", KeyRange{"a", "a"}, nil, false},
+	}
+
+	for _, test := range tests {
+		if got := coveredByPrefix(test.keyRange, test.prefixes); got != test.want {
+			t.Errorf("%s: coveredByPrefix(%v, %q) = %v, want %v", test.name, test.keyRange, test.prefixes, got, test.want)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	am := NewAuthManager(true)
+	admin, reader, nobody := new(net.Conn), new(net.Conn), new(net.Conn)
+
+	// A database without users is open
+	if name, err := am.Authorize(nobody, CategoryWrite, nil); name != "" || err != nil {
+		t.Fatalf("Authorize without users = %q, %v, want no error", name, err)
+	}
+
+	am.users["admin"] = &User{Name: "admin", Grants: []Grant{{Categories: Categories, Prefix: ""}}}
+	am.users["reader"] = &User{Name: "reader", Grants: []Grant{
+		{Categories: []string{CategoryRead}, Prefix: "user:"},
+		{Categories: []string{CategoryRead, CategoryWrite}, Prefix: "tmp:"},
+	}}
+	am.Login(admin, "admin")
+	am.Login(reader, "reader")
+
+	keys := func(ranges ...KeyRange) func() []KeyRange {
+		return func() []KeyRange { return ranges }
+	}
+	tests := []struct {
+		name     string
+		conn     *net.Conn
+		category string
+		keys     func() []KeyRange
+		wantErr  bool
+	}{
+		{"public before authenticating", nobody, CategoryPublic, nil, false},
+		{"read before authenticating", nobody, CategoryRead, keys(KeyRange{"user:1", "user:1"}), true},
+		{"session command", reader, CategorySession, nil, false},
+		{"admin on all keys", admin, CategoryAdmin, nil, false},
+		{"granted key", reader, CategoryRead, keys(KeyRange{"user:1", "user:1"}), false},
+		{"key of another prefix", reader, CategoryRead, keys(KeyRange{"order:1", "order:1"}), true},
+		{"category not granted on the prefix", reader, CategoryWrite, keys(KeyRange{"user:1", "user:1"}), true},
+		{"category granted on another prefix", reader, CategoryWrite, keys(KeyRange{"tmp:1", "tmp:1"}), false},
+		{"keys of two granted prefixes", reader, CategoryRead, keys(KeyRange{"user:1", "user:1"}, KeyRange{"tmp:1", "tmp:1"}), false},
+		{"one key not granted", reader, CategoryRead, keys(KeyRange{"user:1", "user:1"}, KeyRange{"x", "x"}), true},
+		{"range beyond the prefix", reader, CategoryRead, keys(KeyRange{"user:1", "v"}), true},
+		{"contradicting conditions", reader, CategoryRead, keys(KeyRange{"z", "a"}), false},
+		{"command without keys", reader, CategoryRead, nil, true},
+		{"category not granted at all", reader, CategoryAdmin, nil, true},
+	}
+
+	for _, test := range tests {
+		_, err := am.Authorize(test.conn, test.category, test.keys)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: Authorize error = %v, want error %v", test.name, err, test.wantErr)
+		}
+	}
+
+	if _, err := am.Authorize(nobody, CategoryRead, nil); err != ErrAuthenticationRequired {
+		t.Errorf("Authorize before authenticating = %v, want %v", err, ErrAuthenticationRequired)
+	}
+
+	// Access control is only enforced if configured
+	open := NewAuthManager(false)
+	open.users["admin"] = am.users["admin"]
+	if _, err := open.Authorize(nobody, CategoryAdmin, nil); err != nil {
+		t.Errorf("Authorize without enforcing = %v, want no error", err)
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	am := NewAuthManager(true)
+	put := func(name string, grants []Grant) error {
+		return am.UpdateUser(name, func(user *User, userCount int) (*common.TransactionRow, error) {
+			if user == nil {
+				user = &User{Name: name}
+			}
+			user.Grants = append(user.Grants, grants...)
+			return user.ToTransactionRow(1, common.DB_OP_PUT_USER, uint32(userCount+1))
+		})
+	}
+
+	if err := put("alice", []Grant{{Categories: []string{CategoryRead}, Prefix: "a:"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := put("alice", []Grant{{Categories: []string{CategoryRead}, Prefix: "b:"}}); err != nil {
+		t.Fatal(err)
+	}
+	user, ok := am.User("alice")
+	if !ok || len(user.Grants) != 2 {
+		t.Fatalf("User after two updates = %+v, %v, want 2 grants", user, ok)
+	}
+
+	// A failed update changes nothing
+	err := am.UpdateUser("alice", func(user *User, userCount int) (*common.TransactionRow, error) {
+		user.Grants = nil
+		return nil, errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("UpdateUser returned no error for a failed update")
+	}
+	if user, _ := am.User("alice"); len(user.Grants) != 2 {
+		t.Errorf("User after a failed update has %d grants, want 2", len(user.Grants))
+	}
+
+	// Replaying the delete row removes the user and logs out its connections
+	conn := new(net.Conn)
+	am.Login(conn, "alice")
+	row, err := user.ToTransactionRow(2, common.DB_OP_DELETE_USER, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := am.ApplyUserTxnRow(row); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := am.User("alice"); ok {
+		t.Error("deleted user still exists")
+	}
+	if _, ok := am.SessionUser(conn); ok {
+		t.Error("connection of the deleted user is still logged in")
+	}
+}
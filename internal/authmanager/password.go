@@ -0,0 +1,43 @@
+package authmanager
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+const (
+	// passwordIterations is the PBKDF2 work factor of new password hashes. Users keep the iterations they were
+	// hashed with, so it can be raised without invalidating existing passwords.
+	passwordIterations = 100_000
+	saltLength         = 16
+)
+
+// hashPassword derives a key from the password with PBKDF2-HMAC-SHA256 (RFC 8018)
+func hashPassword(password string, salt []byte, iterations int) []byte {
+	prf := hmac.New(sha256.New, []byte(password))
+	prf.Write(salt)
+	prf.Write(binary.BigEndian.AppendUint32(nil, 1))
+	u := prf.Sum(nil)
+
+	key := make([]byte, len(u))
+	copy(key, u)
+	for i := 1; i < iterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
@@ -0,0 +1,64 @@
+package authmanager
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Test vectors of PBKDF2-HMAC-SHA256 in the style of RFC 6070, which lists them for SHA-1
+func TestHashPassword(t *testing.T) {
+	tests := []struct {
+		password   string
+		salt       string
+		iterations int
+		want       string
+	}{
+		{"password", "salt", 1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, "348c89dbcbd32b2f32d814b8116e84cf2b17347ebc1800181c4e2a1fb8dd53e1"},
+		{"pass\x00word", "sa\x00lt", 4096, "89b69d0516f829893c696226650a86878c029ac13ee276509d5ae58b6466a724"},
+	}
+
+	for _, test := range tests {
+		got := hex.EncodeToString(hashPassword(test.password, []byte(test.salt), test.iterations))
+		if got != test.want {
+			t.Errorf("hashPassword(%q, %q, %d) = %s, want %s", test.password, test.salt, test.iterations, got, test.want)
+		}
+	}
+}
+
+func TestNewUserAuthenticate(t *testing.T) {
+	user, err := NewUser("alice", "s3cret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(user.Hash, hashPassword("s3cret", make([]byte, saltLength), user.Iterations)) {
+		t.Error("password was hashed without a salt")
+	}
+
+	am := NewAuthManager(true)
+	am.users[user.Name] = user
+	for range 2 {
+		// The second login is checked against the digest of the first
+		if err := am.Authenticate("alice", "s3cret"); err != nil {
+			t.Errorf("Authenticate with the right password: %v", err)
+		}
+	}
+	if err := am.Authenticate("alice", "wrong"); err != ErrInvalidCredentials {
+		t.Errorf("Authenticate with a wrong password = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if err := am.Authenticate("bob", "s3cret"); err != ErrInvalidCredentials {
+		t.Errorf("Authenticate of a missing user = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	for _, name := range []string{"", "a b", "a\nb"} {
+		if _, err := NewUser(name, "pw", nil); err == nil {
+			t.Errorf("NewUser(%q) succeeded, want an error", name)
+		}
+	}
+	if _, err := NewUser("alice", "", nil); err == nil {
+		t.Error("NewUser with an empty password succeeded, want an error")
+	}
+}
@@ -0,0 +1,128 @@
+// Package clientapi holds the types, errors and result decoding of the typed commands the Go client and the
+// embedded database share. Both packages export them under their own names.
+package clientapi
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Isolation is the isolation level of a transaction
+type Isolation string
+
+const (
+	ReadCommitted     Isolation = "READ_COMMITTED"
+	RepeatableRead    Isolation = "REPEATABLE_READ"
+	SnapshotIsolation Isolation = "SNAPSHOT_ISOLATION"
+	Serializable      Isolation = "SERIALIZABLE"
+)
+
+// KeyValue is a key and its value, as returned by Scan and RGet
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// Page is a page of results. Cursor is set when a limit cut the results short and continues after the page.
+type Page struct {
+	Results []KeyValue
+	Cursor  string
+}
+
+// ReadOptions page the results of Scan and RGet
+type ReadOptions struct {
+	// Limit is the maximum number of results, all results if 0
+	Limit int
+	// Cursor continues after the page that returned it
+	Cursor string
+	// Descending returns the results in descending key order
+	Descending bool
+}
+
+// ExecFunc runs a command, either on its own or in a transaction, and returns its text result
+type ExecFunc func(name string, args ...string) (string, error)
+
+// Get returns the value of a key, or ErrNotFound
+func Get(exec ExecFunc, key string) (string, error) {
+	// MGET tells missing keys apart from values, unlike GET which returns -1 for both
+	result, err := exec("MGET", key)
+	if err != nil {
+		return "", err
+	}
+
+	var values []json.RawMessage
+	if err := json.Unmarshal([]byte(result), &values); err != nil {
+		return "", err
+	}
+	if len(values) != 1 || string(values[0]) == "null" {
+		return "", ErrNotFound
+	}
+	return decodeValue(values[0])
+}
+
+// Count returns the number of keys matching a condition
+func Count(exec ExecFunc, condition string) (int64, error) {
+	result, err := exec("COUNT", condition)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(result, 10, 64)
+}
+
+// ReadPage runs SCAN or RGET with the page clauses of opts and decodes the page
+func ReadPage(exec ExecFunc, name string, args []string, opts *ReadOptions) (*Page, error) {
+	if opts == nil {
+		opts = &ReadOptions{}
+	}
+
+	// Ordering the results always returns them as a page, even without a limit
+	order := "ASC"
+	if opts.Descending {
+		order = "DESC"
+	}
+	args = append(args, "ORDER", "BY", "key", order)
+	if opts.Limit > 0 {
+		args = append(args, "LIMIT", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		args = append(args, "CURSOR", opts.Cursor)
+	}
+
+	result, err := exec(name, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var encoded struct {
+		Results []struct {
+			Key   string          `json:"key"`
+			Value json.RawMessage `json:"value"`
+		} `json:"results"`
+		Cursor string `json:"cursor"`
+	}
+	if err := json.Unmarshal([]byte(result), &encoded); err != nil {
+		return nil, err
+	}
+
+	page := &Page{Results: make([]KeyValue, 0, len(encoded.Results)), Cursor: encoded.Cursor}
+	for _, row := range encoded.Results {
+		value, err := decodeValue(row.Value)
+		if err != nil {
+			return nil, err
+		}
+		page.Results = append(page.Results, KeyValue{Key: row.Key, Value: value})
+	}
+	return page, nil
+}
+
+// decodeValue returns strings unquoted and all other values in their JSON form
+func decodeValue(raw json.RawMessage) (string, error) {
+	if len(raw) > 0 && raw[0] == '"' {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return "", err
+		}
+		return value, nil
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,102 @@
+package clientapi
+
+import (
+	"errors"
+	"meteor/internal/common"
+	"slices"
+	"testing"
+)
+
+// fakeExec answers commands with fixed results and records the commands it ran
+type fakeExec struct {
+	results map[string]string
+	ran     [][]string
+}
+
+func (f *fakeExec) exec(name string, args ...string) (string, error) {
+	f.ran = append(f.ran, append([]string{name}, args...))
+	result, ok := f.results[name]
+	if !ok {
+		return "", &Error{Code: common.ErrorCodeUnknownCommand, Message: "unknown operation"}
+	}
+	return result, nil
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		result  string
+		want    string
+		wantErr error
+	}{
+		{`["alice"]`, "alice", nil},
+		{`["line\nbreak"]`, "line\nbreak", nil},
+		{`[42]`, "42", nil},
+		{`[{"a":1}]`, `{"a":1}`, nil},
+		{`[null]`, "", ErrNotFound},
+		{`[]`, "", ErrNotFound},
+	}
+
+	for _, test := range tests {
+		fake := &fakeExec{results: map[string]string{"MGET": test.result}}
+		got, err := Get(fake.exec, "key")
+		if got != test.want || !errors.Is(err, test.wantErr) {
+			t.Errorf("Get of %s = %q, %v, want %q, %v", test.result, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestReadPage(t *testing.T) {
+	fake := &fakeExec{results: map[string]string{
+		"RGET": `{"results":[{"key":"a","value":"1"},{"key":"b","value":2.5},{"key":"c","value":null}],"cursor":"next"}`,
+	}}
+
+	page, err := ReadPage(fake.exec, "RGET", []string{"a", "z"}, &ReadOptions{Limit: 3, Cursor: "prev", Descending: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []KeyValue{{"a", "1"}, {"b", "2.5"}, {"c", "null"}}
+	if !slices.Equal(page.Results, want) || page.Cursor != "next" {
+		t.Errorf("page = %+v, want %+v with cursor next", page, want)
+	}
+	wantArgs := []string{"RGET", "a", "z", "ORDER", "BY", "key", "DESC", "LIMIT", "3", "CURSOR", "prev"}
+	if !slices.Equal(fake.ran[0], wantArgs) {
+		t.Errorf("command = %q, want %q", fake.ran[0], wantArgs)
+	}
+
+	// Without options the results are still ordered, so they come back as a page
+	if _, err := ReadPage(fake.exec, "RGET", []string{"a", "z"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if wantArgs := []string{"RGET", "a", "z", "ORDER", "BY", "key", "ASC"}; !slices.Equal(fake.ran[1], wantArgs) {
+		t.Errorf("command = %q, want %q", fake.ran[1], wantArgs)
+	}
+}
+
+func TestCount(t *testing.T) {
+	fake := &fakeExec{results: map[string]string{"COUNT": "12"}}
+	if n, err := Count(fake.exec, "*"); n != 12 || err != nil {
+		t.Errorf("Count = %d, %v, want 12", n, err)
+	}
+
+	_, err := Count((&fakeExec{}).exec, "*")
+	if !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Count of a failing command = %v, want %v", err, ErrUnknownCommand)
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	err := &Error{Code: common.ErrorCodeConflict, Message: "write-write conflict"}
+	if !errors.Is(err, ErrConflict) {
+		t.Error("error with the conflict code doesn't match ErrConflict")
+	}
+	if errors.Is(err, ErrDeadlock) {
+		t.Error("error with the conflict code matches ErrDeadlock")
+	}
+	// Errors with a message match only themselves
+	if errors.Is(ErrConflict, err) {
+		t.Error("ErrConflict matches an error with a message")
+	}
+	if err.Error() != "meteor: write-write conflict" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
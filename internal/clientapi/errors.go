@@ -0,0 +1,44 @@
+package clientapi
+
+import (
+	"errors"
+	"meteor/internal/common"
+)
+
+// Error is an error of a command. It matches the Err* values of its code with errors.Is, e.g.
+// errors.Is(err, ErrConflict).
+type Error struct {
+	Code    common.ErrorCode
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return "meteor: " + e.Code.String()
+	}
+	return "meteor: " + e.Message
+}
+
+// Is matches the Err* values, which have a code but no message
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Message == "" && t.Code == e.Code
+}
+
+var (
+	ErrUnknownCommand   = &Error{Code: common.ErrorCodeUnknownCommand}
+	ErrInvalidArgument  = &Error{Code: common.ErrorCodeInvalidArgument}
+	ErrTransaction      = &Error{Code: common.ErrorCodeTransaction}
+	ErrConflict         = &Error{Code: common.ErrorCodeConflict}
+	ErrDeadlock         = &Error{Code: common.ErrorCodeDeadlock}
+	ErrLockTimeout      = &Error{Code: common.ErrorCodeLockTimeout}
+	ErrConditionNotMet  = &Error{Code: common.ErrorCodeConditionNotMet}
+	ErrProtocol         = &Error{Code: common.ErrorCodeProtocol}
+	ErrAuthentication   = &Error{Code: common.ErrorCodeAuthentication}
+	ErrPermissionDenied = &Error{Code: common.ErrorCodePermission}
+
+	// ErrNotFound is returned by Get for keys that don't exist
+	ErrNotFound = errors.New("meteor: key not found")
+	// ErrTxDone is returned by the methods of a Tx after Commit or Rollback
+	ErrTxDone = errors.New("meteor: transaction has already been committed or rolled back")
+)
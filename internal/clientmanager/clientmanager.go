@@ -0,0 +1,151 @@
+package clientmanager
+
+import (
+	"cmp"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrNoSuchClient = errors.New("no such client")
+
+// Client is a connection of a client speaking one of the protocols
+type Client struct {
+	Id          uint64
+	Protocol    string
+	RemoteAddr  string
+	ConnectedAt time.Time
+	// Name is set by the client with CLIENT SETNAME, empty by default
+	Name string
+	// LastCommand is the command the client sent last and LastCommandAt when, empty before its first command
+	LastCommand   string
+	LastCommandAt time.Time
+}
+
+// client is a registered connection. done is closed once the connection is unregistered, i.e. once its
+// handler has returned and cleaned up after it.
+type client struct {
+	Client
+	conn *net.Conn
+	done chan struct{}
+}
+
+// ClientManager keeps the open client connections. Clients are keyed by the connection reference their
+// commands run with, the same reference transactions and sessions belong to.
+type ClientManager struct {
+	mu      sync.RWMutex
+	lastId  uint64
+	clients map[*net.Conn]*client
+}
+
+func NewClientManager() *ClientManager {
+	return &ClientManager{clients: make(map[*net.Conn]*client)}
+}
+
+// Register adds the connection of a client and assigns it the next client id
+func (cm *ClientManager) Register(conn *net.Conn, protocol string) Client {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.lastId++
+	c := &client{
+		Client: Client{
+			Id:          cm.lastId,
+			Protocol:    protocol,
+			RemoteAddr:  (*conn).RemoteAddr().String(),
+			ConnectedAt: time.Now(),
+		},
+		conn: conn,
+		done: make(chan struct{}),
+	}
+	cm.clients[conn] = c
+	return c.Client
+}
+
+// Unregister removes the connection of a client once it is closed
+func (cm *ClientManager) Unregister(conn *net.Conn) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if c, ok := cm.clients[conn]; ok {
+		close(c.done)
+		delete(cm.clients, conn)
+	}
+}
+
+// SetName names the client of a connection, an empty name removes the name
+func (cm *ClientManager) SetName(conn *net.Conn, name string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if c, ok := cm.clients[conn]; ok {
+		c.Name = name
+	}
+}
+
+// Touch records the command a connection is running
+func (cm *ClientManager) Touch(conn *net.Conn, command string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if c, ok := cm.clients[conn]; ok {
+		c.LastCommand = command
+		c.LastCommandAt = time.Now()
+	}
+}
+
+// Client returns a copy of the client of a connection, false if the connection isn't registered
+func (cm *ClientManager) Client(conn *net.Conn) (Client, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	c, ok := cm.clients[conn]
+	if !ok {
+		return Client{}, false
+	}
+	return c.Client, true
+}
+
+// Clients returns copies of the connected clients sorted by id
+func (cm *ClientManager) Clients() []Client {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	clients := make([]Client, 0, len(cm.clients))
+	for _, c := range cm.clients {
+		clients = append(clients, c.Client)
+	}
+	slices.SortFunc(clients, func(a, b Client) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+	return clients
+}
+
+// Connection returns the connection of the client with the given id
+func (cm *ClientManager) Connection(id uint64) (*net.Conn, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	for conn, c := range cm.clients {
+		if c.Id == id {
+			return conn, true
+		}
+	}
+	return nil, false
+}
+
+// Kill closes the connection of a client. The returned channel is closed once the handler of the connection
+// has returned and unregistered it, which waits for the command it is running.
+func (cm *ClientManager) Kill(conn *net.Conn) (<-chan struct{}, error) {
+	cm.mu.RLock()
+	c, ok := cm.clients[conn]
+	cm.mu.RUnlock()
+	if !ok {
+		return nil, ErrNoSuchClient
+	}
+	return c.done, (*conn).Close()
+}
+
+// Count returns the number of connected clients
+func (cm *ClientManager) Count() int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return len(cm.clients)
+}
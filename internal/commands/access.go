@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"meteor/internal/authmanager"
+	"meteor/internal/dbmanager"
+	"meteor/internal/parser"
+	"strings"
+)
+
+// The KeyAccess functions of the commands. They return no ranges for missing or malformed arguments, which
+// ensureInputs rejects before anything is read or written.
+
+// firstKey is the key access of single key commands
+func firstKey(dm *dbmanager.DBManager, args []string) []authmanager.KeyRange {
+	if len(args) == 0 {
+		return nil
+	}
+	return []authmanager.KeyRange{{Start: args[0], End: args[0]}}
+}
+
+// keyList is the key access of commands taking keys followed by an optional TXN transactionId, e.g. MGET
+func keyList(dm *dbmanager.DBManager, args []string) []authmanager.KeyRange {
+	keys, _ := trimTransactionId(args)
+	return singleKeyRanges(keys)
+}
+
+// keyPairs is the key access of MSET, whose arguments alternate keys and values
+func keyPairs(dm *dbmanager.DBManager, args []string) []authmanager.KeyRange {
+	keys := make([]string, 0, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		keys = append(keys, args[i])
+	}
+	return singleKeyRanges(keys)
+}
+
+func singleKeyRanges(keys []string) []authmanager.KeyRange {
+	ranges := make([]authmanager.KeyRange, 0, len(keys))
+	for _, key := range keys {
+		ranges = append(ranges, authmanager.KeyRange{Start: key, End: key})
+	}
+	return ranges
+}
+
+// keyRange is the key access of commands starting with startKey and endKey, e.g. RGET
+func keyRange(dm *dbmanager.DBManager, args []string) []authmanager.KeyRange {
+	if len(args) < 2 {
+		return nil
+	}
+	return []authmanager.KeyRange{{Start: args[0], End: args[1]}}
+}
+
+// keyPrefix is the key access of DELPREFIX
+func keyPrefix(dm *dbmanager.DBManager, args []string) []authmanager.KeyRange {
+	if len(args) == 0 {
+		return nil
+	}
+	return []authmanager.KeyRange{{Start: args[0], End: args[0] + parser.MaxKey}}
+}
+
+// conditionKeys returns the key access of commands with a condition at position i, e.g. SCAN. The keys are
+// the bounds the condition puts on $key, all keys if it doesn't bound them.
+func conditionKeys(i int) KeyAccess {
+	return func(dm *dbmanager.DBManager, args []string) []authmanager.KeyRange {
+		if len(args) <= i {
+			return nil
+		}
+		plan, err := parser.PlanScan(args[i], nil)
+		if err != nil {
+			return nil
+		}
+		return []authmanager.KeyRange{{Start: plan.StartKey, End: plan.EndKey}}
+	}
+}
+
+// aggregationKeys is the key access of AGG, the bounds the WHERE condition puts on $key
+func aggregationKeys(dm *dbmanager.DBManager, args []string) []authmanager.KeyRange {
+	queryArgs, _ := trimTransactionId(args)
+	query, err := parser.ParseAggregation(strings.Join(queryArgs, " "))
+	if err != nil {
+		return nil
+	}
+	return []authmanager.KeyRange{{Start: query.StartKey, End: query.EndKey}}
+}
+
+// indexKeys is the key access of CREATE INDEX and DROP INDEX, the prefix of the keys indexed
+func indexKeys(dm *dbmanager.DBManager, args []string) []authmanager.KeyRange {
+	if len(args) >= 5 && strings.EqualFold(args[0], "INDEX") {
+		return keyPrefix(dm, args[4:])
+	}
+	if len(args) == 2 && strings.EqualFold(args[0], "INDEX") {
+		for _, definition := range dm.StoreManager.IndexManager.Definitions() {
+			if definition.Name == args[1] {
+				return keyPrefix(dm, []string{definition.Prefix})
+			}
+		}
+	}
+	return nil
+}
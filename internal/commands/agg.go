@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"meteor/internal/parser"
+	"strings"
+)
+
+func init() {
+	Register("AGG", authmanager.CategoryRead, aggregationKeys, []ArgSpec{
+		{Name: "query", Type: "string", Required: true, Description: "Aggregates with optional condition and grouping (e.g., 'SUM($value) WHERE $key LIKE order_%' or 'COUNT(*), AVG($value) GROUP BY PREFIX($key, :)')"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "TXN followed by the transaction id for the aggregation"},
+	}, ensureAgg, execAgg)
+}
+
+type AggArgs struct {
+	query                       string
+	aggregationQuery            *parser.AggregationQuery
+	isPartOfExistingTransaction bool
+	transactionId               uint32
+}
+
+func ensureAgg(dm *dbmanager.DBManager, cmd *common.Command) (*AggArgs, error) {
+	if len(cmd.Args) < 1 {
+		return nil, errors.New("command must have at least one argument - query")
+	}
+
+	// The query may be passed quoted as one argument or unquoted as several
+	queryArgs, transactionId, isPartOfExistingTransaction, err := splitKeysAndTransactionId(dm, cmd.Args)
+	if err != nil {
+		return nil, err
+	}
+	query := strings.Join(queryArgs, " ")
+
+	aggregationQuery, err := parser.ParseAggregation(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aggregation: %v", err)
+	}
+
+	return &AggArgs{
+		query:                       query,
+		aggregationQuery:            aggregationQuery,
+		isPartOfExistingTransaction: isPartOfExistingTransaction,
+		transactionId:               transactionId,
+	}, nil
+}
+
+func execAgg(dm *dbmanager.DBManager, aggArgs *AggArgs, ctx *CommandContext) ([]byte, error) {
+	transactionId := aggArgs.transactionId
+	isolationLevel, err := ctx.isolationLevel(dm, transactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Acquire predicate lock for the query to prevent phantom reads
+	err = ctx.waitForLock(func() error {
+		return dm.TransactionManager.AcquirePredicateLock(transactionId, "AGG("+aggArgs.query+")")
+	})
+	if err != nil {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+		return nil, err
+	}
+
+	results, err := dm.TransactionManager.ReadFilteredValues(transactionId, ctx.examine(aggArgs.aggregationQuery.Filter), dm.StoreManager.BufferStore, ctx.clientConnection)
+	if err != nil {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+		return nil, err
+	}
+
+	// Process read values for transaction store and read locks
+	err = addReadValuesToTxnStoreByAcquiringLocks(dm, transactionId, results, isolationLevel, ctx)
+	if err != nil {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+		return nil, err
+	}
+
+	aggregator := aggArgs.aggregationQuery.NewAggregator()
+	for key, value := range results {
+		if value == nil {
+			continue
+		}
+		aggregator.Add(key, value)
+	}
+
+	// Grouped aggregations return a row per group, others a single row
+	result := aggregator.Result()
+	ctx.trace.rowsReturned = 1
+	if groups, ok := result.(map[string]map[string]any); ok {
+		ctx.trace.rowsReturned = len(groups)
+	}
+
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+		return nil, err
+	}
+
+	return jsonBytes, nil
+}
@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"errors"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+)
+
+func init() {
+	Register("AUTH", authmanager.CategoryPublic, nil, []ArgSpec{
+		{Name: "user", Type: "string", Required: true, Description: "The name of the user"},
+		{Name: "password", Type: "string", Required: true, Description: "The password of the user"},
+	}, ensureAuth, execAuth)
+}
+
+type AuthArgs struct {
+	user     string
+	password string
+}
+
+func ensureAuth(dm *dbmanager.DBManager, cmd *common.Command) (*AuthArgs, error) {
+	if len(cmd.Args) != 2 {
+		return nil, errors.New("command must have 2 arguments - user, password")
+	}
+	return &AuthArgs{user: cmd.Args[0], password: cmd.Args[1]}, nil
+}
+
+// execAuth runs the following commands of the connection as the user. A failed attempt keeps the user the
+// connection authenticated as before.
+func execAuth(dm *dbmanager.DBManager, authArgs *AuthArgs, ctx *CommandContext) ([]byte, error) {
+	if err := dm.AuthManager.Authenticate(authArgs.user, authArgs.password); err != nil {
+		return nil, err
+	}
+	dm.AuthManager.Login(ctx.clientConnection, authArgs.user)
+	return []byte("OK"), nil
+}
@@ -2,13 +2,14 @@ package commands
 
 import (
 	"errors"
+	"meteor/internal/authmanager"
 	"meteor/internal/common"
 	"meteor/internal/dbmanager"
 	"strconv"
 )
 
 func init() {
-	Register("BEGIN", []ArgSpec{
+	Register("BEGIN", authmanager.CategorySession, nil, []ArgSpec{
 		{ Name: "transactionIsolation", Type: "string", Required: false, Description: "The transaction isolation level" },
 	}, ensureBegin, execBegin)
 }
@@ -61,6 +62,7 @@ func execBegin(dm *dbmanager.DBManager, beginArgs *BeginArgs, ctx *CommandContex
 	if err != nil {
 		return nil, err
 	}
+	ctx.traceTransaction(dm, transactionId)
 
 	gsn := dm.GsnManager.GetNewGsn()
 	
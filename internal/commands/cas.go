@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"errors"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+)
+
+func init() {
+	Register("CAS", authmanager.CategoryWrite, firstKey, []ArgSpec{
+		{Name: "key", Type: "string", Required: true, Description: "The key to set"},
+		{Name: "expectedValue", Type: "string", Required: true, Description: "The value the key must currently hold"},
+		{Name: "newValue", Type: "string", Required: true, Description: "The value to set if the current value matches"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to put the key and value to"},
+	}, ensureCas, execPut)
+}
+
+// ensureCas builds a conditional PUT so compare-and-set shares the write path (locking, WAL and commit) with PUT
+func ensureCas(dm *dbmanager.DBManager, cmd *common.Command) (*PutArgs, error) {
+	argLen := len(cmd.Args)
+
+	if argLen < 3 {
+		return nil, errors.New("command must have at least 3 arguments - key, expectedValue, newValue")
+	}
+
+	if argLen > 4 {
+		return nil, errors.New("command must have at most 4 arguments - key, expectedValue, newValue, transactionId")
+	}
+
+	transactionId, isPartOfExistingTransaction, err := parseOptionalTransactionId(dm, cmd.Args[3:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &PutArgs{
+		key:                         cmd.Args[0],
+		value:                       cmd.Args[2],
+		valueType:                   common.TypeString,
+		condition:                   putConditionValue,
+		expectedValue:               cmd.Args[1],
+		isPartOfExistingTransaction: isPartOfExistingTransaction,
+		transactionId:               transactionId,
+	}, nil
+}
@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meteor/internal/authmanager"
+	"meteor/internal/clientmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("CLIENT", authmanager.CategorySession, nil, []ArgSpec{
+		{Name: "action", Type: "string", Required: true, Description: "ID, SETNAME <name>, GETNAME, LIST or KILL ID <id> | ADDR <addr>"},
+		{Name: "value", Type: "string", Required: false, Description: "The name of SETNAME, ID or ADDR of KILL"},
+		{Name: "target", Type: "string", Required: false, Description: "The client id or address KILL closes"},
+	}, ensureClient, execClient)
+}
+
+// clientKillTimeout is how long CLIENT KILL waits for the handler of the connection to roll back its transactions
+const clientKillTimeout = 5 * time.Second
+
+type ClientArgs struct {
+	action string
+	name   string
+	// killId or killAddr select the client KILL closes
+	killId   uint64
+	killAddr string
+}
+
+// clientInfo is a client as listed by CLIENT LIST
+type clientInfo struct {
+	Id           uint64   `json:"id"`
+	Name         string   `json:"name"`
+	Protocol     string   `json:"protocol"`
+	Addr         string   `json:"addr"`
+	User         string   `json:"user,omitempty"`
+	ConnectedAt  string   `json:"connected_at"`
+	AgeSeconds   int64    `json:"age_seconds"`
+	IdleSeconds  int64    `json:"idle_seconds"`
+	LastCommand  string   `json:"last_command"`
+	Transactions []uint32 `json:"transactions"`
+}
+
+const clientUsage = "usage: CLIENT ID | CLIENT SETNAME <name> | CLIENT GETNAME | CLIENT LIST | CLIENT KILL ID <id> | CLIENT KILL ADDR <addr>"
+
+func ensureClient(dm *dbmanager.DBManager, cmd *common.Command) (*ClientArgs, error) {
+	if len(cmd.Args) == 0 {
+		return nil, errors.New(clientUsage)
+	}
+
+	clientArgs := &ClientArgs{action: strings.ToUpper(cmd.Args[0])}
+	switch {
+	case (clientArgs.action == "ID" || clientArgs.action == "GETNAME" || clientArgs.action == "LIST") && len(cmd.Args) == 1:
+		return clientArgs, nil
+	case clientArgs.action == "SETNAME" && len(cmd.Args) == 2:
+		// Names are listed in space separated lines over RESP, like by Redis
+		if strings.ContainsAny(cmd.Args[1], " \n") {
+			return nil, errors.New("client names can't contain spaces or newlines")
+		}
+		clientArgs.name = cmd.Args[1]
+		return clientArgs, nil
+	case clientArgs.action == "KILL" && len(cmd.Args) == 3 && strings.EqualFold(cmd.Args[1], "ID"):
+		id, err := strconv.ParseUint(cmd.Args[2], 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid client id")
+		}
+		clientArgs.killId = id
+		return clientArgs, nil
+	case clientArgs.action == "KILL" && len(cmd.Args) == 3 && strings.EqualFold(cmd.Args[1], "ADDR"):
+		clientArgs.killAddr = cmd.Args[2]
+		return clientArgs, nil
+	}
+	return nil, errors.New(clientUsage)
+}
+
+// execClient names the client of the connection and lists and kills clients. LIST and KILL need admin on all
+// keys, since they expose and end the connections of other users.
+func execClient(dm *dbmanager.DBManager, clientArgs *ClientArgs, ctx *CommandContext) ([]byte, error) {
+	switch clientArgs.action {
+	case "ID":
+		client, ok := dm.ClientManager.Client(ctx.clientConnection)
+		if !ok {
+			return nil, clientmanager.ErrNoSuchClient
+		}
+		return []byte(strconv.FormatUint(client.Id, 10)), nil
+
+	case "SETNAME":
+		dm.ClientManager.SetName(ctx.clientConnection, clientArgs.name)
+		return []byte("OK"), nil
+
+	case "GETNAME":
+		client, _ := dm.ClientManager.Client(ctx.clientConnection)
+		return []byte(client.Name), nil
+	}
+
+	if _, err := dm.AuthManager.Authorize(ctx.clientConnection, authmanager.CategoryAdmin, nil); err != nil {
+		return nil, err
+	}
+
+	if clientArgs.action == "LIST" {
+		now := time.Now()
+		clients := dm.ClientManager.Clients()
+		infos := make([]clientInfo, 0, len(clients))
+		for _, client := range clients {
+			conn, ok := dm.ClientManager.Connection(client.Id)
+			if !ok {
+				// Closed while listing
+				continue
+			}
+			user, _ := dm.AuthManager.SessionUser(conn)
+			lastActive := client.ConnectedAt
+			if !client.LastCommandAt.IsZero() {
+				lastActive = client.LastCommandAt
+			}
+			infos = append(infos, clientInfo{
+				Id:           client.Id,
+				Name:         client.Name,
+				Protocol:     client.Protocol,
+				Addr:         client.RemoteAddr,
+				User:         user,
+				ConnectedAt:  client.ConnectedAt.UTC().Format(time.RFC3339),
+				AgeSeconds:   int64(now.Sub(client.ConnectedAt).Seconds()),
+				IdleSeconds:  int64(now.Sub(lastActive).Seconds()),
+				LastCommand:  client.LastCommand,
+				Transactions: dm.TransactionManager.ConnectionTransactions(conn),
+			})
+		}
+		return json.Marshal(infos)
+	}
+
+	conn, err := findClient(dm, clientArgs)
+	if err != nil {
+		return nil, err
+	}
+	if conn == ctx.clientConnection {
+		return nil, errors.New("a client can't kill its own connection")
+	}
+
+	done, err := dm.ClientManager.Kill(conn)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return nil, err
+	}
+	// The handler of the connection rolls back its transactions once the command it is running returns
+	select {
+	case <-done:
+		return []byte("OK"), nil
+	case <-time.After(clientKillTimeout):
+		return nil, errors.New("connection closed, its transactions are rolled back once its running command returns")
+	}
+}
+
+// findClient returns the connection of the client KILL selects
+func findClient(dm *dbmanager.DBManager, clientArgs *ClientArgs) (*net.Conn, error) {
+	if clientArgs.killAddr == "" {
+		conn, ok := dm.ClientManager.Connection(clientArgs.killId)
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", clientmanager.ErrNoSuchClient, clientArgs.killId)
+		}
+		return conn, nil
+	}
+
+	for _, client := range dm.ClientManager.Clients() {
+		if client.RemoteAddr == clientArgs.killAddr {
+			if conn, ok := dm.ClientManager.Connection(client.Id); ok {
+				return conn, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", clientmanager.ErrNoSuchClient, clientArgs.killAddr)
+}
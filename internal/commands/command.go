@@ -4,11 +4,19 @@ import (
 	"log"
 	"log/slog"
 	"net"
+	"sort"
 	"strings"
 	"time"
 
+	"meteor/internal/authmanager"
 	"meteor/internal/common"
 	"meteor/internal/dbmanager"
+	"meteor/internal/metrics"
+)
+
+var (
+	commandDuration = metrics.Default.NewHistogram("meteor_command_duration_seconds", "Duration of executed commands in seconds.", metrics.DurationBuckets, "command")
+	commandErrors   = metrics.Default.NewCounter("meteor_command_errors_total", "Commands that failed, by error code.", "command", "code")
 )
 
 // ArgSpec describes exactly one positional argument
@@ -21,22 +29,52 @@ type ArgSpec struct {
 
 // CommandSpec is what each file builds and calls Register on
 type CommandSpec struct {
-    Name    string
-    Args    []ArgSpec
-    Handler func(dm *dbmanager.DBManager, cmd *common.Command) ([]byte, error)
+    Name     string
+    Category string
+    Args     []ArgSpec
+    Handler  func(dm *dbmanager.DBManager, cmd *common.Command) ([]byte, error)
 }
 
 type CommandContext struct {
 	clientConnection *net.Conn
+	// user is the authenticated user running the command, empty if access control isn't enabled
+	user string
+	// trace records what the command did for the slow log
+	trace *commandTrace
 }
 
+// KeyAccess returns the ranges of keys a command accesses, so grants on key prefixes are checked before the
+// inputs are validated. Arguments it can't make sense of are left to ensureInputs to reject.
+type KeyAccess func(dm *dbmanager.DBManager, args []string) []authmanager.KeyRange
+
 
 var registry = make(map[string]*CommandSpec)
 
-// Register wires up your CommandSpec into the global registry
+// secretCommands take passwords, so their arguments aren't logged
+var secretCommands = map[string]bool{"AUTH": true, "USER": true}
+
+func loggedArgs(name string, args []string) any {
+	if secretCommands[strings.ToUpper(name)] {
+		return "[redacted]"
+	}
+	return args
+}
+
+// redactedArgs returns the arguments of a command to record, without the passwords of secret commands
+func redactedArgs(name string, args []string) []string {
+	if secretCommands[strings.ToUpper(name)] {
+		return []string{"[redacted]"}
+	}
+	return args
+}
+
+// Register wires up your CommandSpec into the global registry. category is the authmanager category users
+// need a grant of, keys the keys the grant must cover, nil for commands that don't access keys.
 func Register[I any](
-    name    string,
-    args    []ArgSpec,
+    name     string,
+    category string,
+    keys     KeyAccess,
+    args     []ArgSpec,
     ensureInputs func(*dbmanager.DBManager, *common.Command) (I, error),
     execute  func(*dbmanager.DBManager, I, *CommandContext) ([]byte, error),
 ) {
@@ -56,20 +94,45 @@ func Register[I any](
     }
 
     handler := func(dm *dbmanager.DBManager, cmd *common.Command) ([]byte, error) {
-        slog.Info("validating", "command", name, "args", cmd.Args)
+        dm.ClientManager.Touch(cmd.Connection, name)
+
+        var commandKeys func() []authmanager.KeyRange
+        if keys != nil {
+            commandKeys = func() []authmanager.KeyRange { return keys(dm, cmd.Args) }
+        }
+        user, err := dm.AuthManager.Authorize(cmd.Connection, category, commandKeys)
+        if err != nil {
+            slog.Warn("not authorized", "command", name, "error", err)
+            commandErrors.Inc(name, common.ClassifyError(err).String())
+            publishCommand(dm, name, cmd, time.Now(), 0, 0, err)
+            return nil, err
+        }
+
+        slog.Info("validating", "command", name, "args", loggedArgs(name, cmd.Args))
         in, err := ensureInputs(dm, cmd)
         if err != nil {
             slog.Error("validation failed", "command", name, "error", err)
+            commandErrors.Inc(name, common.ErrorCodeInvalidArgument.String())
+            err = &common.InvalidArgumentError{Err: err}
+            publishCommand(dm, name, cmd, time.Now(), 0, 0, err)
             return nil, err
         }
 
-        slog.Info("executing", "command", name, "args", cmd.Args)
+        slog.Info("executing", "command", name, "args", loggedArgs(name, cmd.Args))
+        trace := &commandTrace{}
         t0 := time.Now()
-        res, err := execute(dm, in, &CommandContext{clientConnection: cmd.Connection})
+        res, err := execute(dm, in, &CommandContext{clientConnection: cmd.Connection, user: user, trace: trace})
         dt := time.Since(t0)
+        commandDuration.Observe(dt.Seconds(), name)
+        // MONITOR runs until the client disconnects, so it isn't slow
+        if dm.SlowLog != nil && dm.SlowLog.IsSlow(dt) && name != "MONITOR" {
+            recordSlowCommand(dm, name, cmd, t0, dt, trace, err)
+        }
+        publishCommand(dm, name, cmd, t0, dt, trace.transactionId, err)
 
         if err != nil {
             slog.Error("error", "command", name, "duration", dt, "error", err)
+            commandErrors.Inc(name, common.ClassifyError(err).String())
         } else {
             slog.Info("done", "command", name, "duration", dt)
         }
@@ -79,9 +142,10 @@ func Register[I any](
 	uppercasedName := strings.ToUpper(name)
 
     registry[uppercasedName] = &CommandSpec{
-        Name:    name,
-        Args:    args,
-        Handler: handler,
+        Name:     name,
+        Category: category,
+        Args:     args,
+        Handler:  handler,
     }
 }
 
@@ -91,3 +155,15 @@ func Get(name string) (*CommandSpec, bool) {
     c, ok := registry[uppercasedName]
     return c, ok
 }
+
+// List returns the registered CommandSpecs sorted by name
+func List() []*CommandSpec {
+	specs := make([]*CommandSpec, 0, len(registry))
+	for _, spec := range registry {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+	return specs
+}
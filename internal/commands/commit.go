@@ -2,13 +2,14 @@ package commands
 
 import (
 	"errors"
+	"meteor/internal/authmanager"
 	"meteor/internal/common"
 	"meteor/internal/dbmanager"
 	"strconv"
 )
 
 func init() {
-	Register("COMMIT", []ArgSpec{
+	Register("COMMIT", authmanager.CategorySession, nil, []ArgSpec{
 		{Name: "transactionId", Type: "uint32", Required: true, Description: "The transaction id to commit"},
 	}, ensureCommit, execCommit)
 }
@@ -33,6 +34,7 @@ func ensureCommit(dm *dbmanager.DBManager, cmd *common.Command) (*CommitArgs, er
 
 func execCommit(dm *dbmanager.DBManager, commitArgs *CommitArgs, ctx *CommandContext) ([]byte, error) {
 	transactionId := commitArgs.transactionId
+	ctx.traceTransaction(dm, transactionId)
 
 	gsn := dm.GsnManager.GetNewGsn()
 	key := &common.K{Key: common.TypeKeyNull, Gsn: gsn}
@@ -76,10 +78,10 @@ func execCommit(dm *dbmanager.DBManager, commitArgs *CommitArgs, ctx *CommandCon
 		// Compare buffer store latest GSN with transaction store GSN
 		// If buffer store has newer version, detect conflict
 		// TODO: We can reuse the bufferValue from above since it returns the value with latest GSN. Refer mapdatatable.go for implementation.
+		// A key which doesn't exist in the buffer store yet can't have a conflicting version
 		bufferLatestGsn, bufferErr := dm.StoreManager.BufferStore.GetLatestGsn(keyStr)
 		if bufferErr != nil {
-			dm.TransactionManager.ClearTransactionStore(transactionId)
-			return nil, bufferErr
+			bufferLatestGsn = 0
 		}
 		// TODO: Ideally this check should be moved to ValidateWrite method. Currently similar check is present in ValidateWrite method but only for SNAPSHOT_ISOLATION. But ValidateWrite is used in other places so need to be careful.
 		if bufferLatestGsn > latestGsn {
@@ -118,9 +120,18 @@ func execCommit(dm *dbmanager.DBManager, commitArgs *CommitArgs, ctx *CommandCon
 		return nil, err
 	}
 
+	// Apply range deletes of the transaction to buffer store. Entries written after a range delete have a higher GSN so they stay visible.
+	for _, rangeTombstone := range transactionStore.RangeTombstones() {
+		err = dm.StoreManager.PutRangeTombstone(rangeTombstone)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+	}
+
 	// Apply all validated entries to buffer store
 	for _, entry := range validatedEntries {
-		err = dm.StoreManager.BufferStore.Put(entry.key, entry.value)
+		err = dm.StoreManager.Put(entry.key, entry.value)
 		if err != nil {
 			// Clean up and return error
 			dm.TransactionManager.ClearTransactionStore(transactionId)
@@ -3,6 +3,7 @@ package commands
 import (
 	"errors"
 	"fmt"
+	"meteor/internal/authmanager"
 	"meteor/internal/common"
 	"meteor/internal/dbmanager"
 	"meteor/internal/parser"
@@ -10,7 +11,7 @@ import (
 )
 
 func init() {
-	Register("COUNT", []ArgSpec{
+	Register("COUNT", authmanager.CategoryRead, conditionKeys(0), []ArgSpec{
 		{Name: "condition", Type: "string", Required: true, Description: "Condition for counting (e.g., '$key LIKE user_%' or '$value > 100' or '$key = user1 AND $value > 50' or '*' for all records)"},
 		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id for the count operation"},
 	}, ensureCount, execCount)
@@ -18,6 +19,7 @@ func init() {
 
 type CountArgs struct {
 	condition                   string
+	plan                        *parser.ScanPlan
 	isPartOfExistingTransaction bool
 	transactionId               uint32
 }
@@ -37,6 +39,13 @@ func ensureCount(dm *dbmanager.DBManager, cmd *common.Command) (*CountArgs, erro
 		transactionId:               0,
 	}
 
+	// Conditions that bound the key are read by prefix or range instead of scanning every key
+	plan, err := parser.PlanScan(countArgs.condition, dm.StoreManager.IndexManager.Definitions())
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition: %v", err)
+	}
+	countArgs.plan = plan
+
 	// Handle optional transactionId argument
 	if argLen == 2 {
 		transactionId64Bits, err := strconv.ParseUint(cmd.Args[1], 10, 32)
@@ -59,7 +68,7 @@ func ensureCount(dm *dbmanager.DBManager, cmd *common.Command) (*CountArgs, erro
 
 func execCount(dm *dbmanager.DBManager, countArgs *CountArgs, ctx *CommandContext) ([]byte, error) {
 	transactionId := countArgs.transactionId
-	isolationLevel, err := dm.TransactionManager.GetIsolationLevel(transactionId)
+	isolationLevel, err := ctx.isolationLevel(dm, transactionId)
 	if err != nil {
 		return nil, err
 	}
@@ -70,53 +79,36 @@ func execCount(dm *dbmanager.DBManager, countArgs *CountArgs, ctx *CommandContex
 		predicate = "COUNT(*)"
 	}
 
-	// Acquire predicate lock for the condition to prevent phantom reads
-	err = dm.TransactionManager.AcquirePredicateLock(transactionId, predicate)
+	// Acquire a range lock for the counted keys, or a predicate lock for the condition, to prevent phantom reads
+	err = acquirePlanLock(dm, transactionId, ctx, countArgs.plan, predicate)
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
 
-	// Create appropriate filter function
-	var filterFunc func(string, *common.V) bool
-	if countArgs.condition == "*" {
-		// Full count - count all non-tombstone records
-		filterFunc = func(key string, value *common.V) bool {
-			return value != nil && value.Type != common.TypeTombstone
-		}
-	} else {
-		// Parse the condition using the same parser as SCAN
-		conditionParser := parser.NewConditionParser(countArgs.condition)
-		var parseErr error
-		filterFunc, parseErr = conditionParser.ParseExpression()
-		if parseErr != nil {
-			dm.TransactionManager.ClearTransactionStore(transactionId)
-			return nil, fmt.Errorf("invalid condition: %v", parseErr)
-		}
-	}
-
 	// TODO: Refactor this to get only count and not all rows to save memory
-	results, err := dm.TransactionManager.ReadFilteredValues(transactionId, filterFunc, dm.StoreManager.BufferStore, ctx.clientConnection)
+	results, err := readPlannedValues(dm, transactionId, countArgs.plan, ctx)
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
 
 	// Process read values for transaction store and read locks
-	err = addReadValuesToTxnStoreByAcquiringLocks(dm, transactionId, results, isolationLevel, ctx.clientConnection)
+	err = addReadValuesToTxnStoreByAcquiringLocks(dm, transactionId, results, isolationLevel, ctx)
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
 
-	// Count only non-tombstone records
+	// Deleted records are only counted if the condition asks for them, e.g. IS TOMBSTONE
 	count := 0
 	for _, value := range results {
-		if value != nil && value.Type != common.TypeTombstone {
+		if value != nil {
 			count++
 		}
 	}
 
+	ctx.trace.rowsReturned = count
 	return []byte(strconv.Itoa(count)), nil
 }
 
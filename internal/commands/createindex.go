@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"meteor/internal/parser"
+	"strings"
+)
+
+func init() {
+	Register("CREATE", authmanager.CategoryAdmin, indexKeys, []ArgSpec{
+		{Name: "index", Type: "string", Required: true, Description: "INDEX <name>, the name of the index"},
+		{Name: "prefix", Type: "string", Required: true, Description: "ON PREFIX <prefix>, the keys to index ('' for all keys)"},
+		{Name: "field", Type: "string", Required: true, Description: "(<field>), the indexed field, $value or $value.<json path>"},
+	}, ensureCreateIndex, execCreateIndex)
+
+	Register("DROP", authmanager.CategoryAdmin, indexKeys, []ArgSpec{
+		{Name: "index", Type: "string", Required: true, Description: "INDEX <name>, the name of the index to drop"},
+	}, ensureDropIndex, execDropIndex)
+}
+
+type IndexArgs struct {
+	definition *common.IndexDefinition
+}
+
+func ensureCreateIndex(dm *dbmanager.DBManager, cmd *common.Command) (*IndexArgs, error) {
+	args := cmd.Args
+	if len(args) < 6 || !strings.EqualFold(args[0], "INDEX") || !strings.EqualFold(args[2], "ON") || !strings.EqualFold(args[3], "PREFIX") {
+		return nil, errors.New("usage: CREATE INDEX <name> ON PREFIX <prefix> (<field>)")
+	}
+
+	// The field may be split into several arguments, e.g. ( $value.email )
+	field := strings.Join(args[5:], "")
+	if !strings.HasPrefix(field, "(") || !strings.HasSuffix(field, ")") {
+		return nil, errors.New("usage: CREATE INDEX <name> ON PREFIX <prefix> (<field>)")
+	}
+	field, err := parser.ParseIndexField(strings.TrimSuffix(strings.TrimPrefix(field, "("), ")"))
+	if err != nil {
+		return nil, err
+	}
+
+	definition, err := common.NewIndexDefinition(args[1], args[4], field)
+	if err != nil {
+		return nil, err
+	}
+	return &IndexArgs{definition: definition}, nil
+}
+
+// execCreateIndex builds the index from the committed values and logs its definition to the WAL, so it is
+// rebuilt on recovery. Indexes are created outside of transactions.
+func execCreateIndex(dm *dbmanager.DBManager, indexArgs *IndexArgs, ctx *CommandContext) ([]byte, error) {
+	for _, definition := range dm.StoreManager.IndexManager.Definitions() {
+		if definition.Name == indexArgs.definition.Name {
+			return nil, fmt.Errorf("index already exists: %s", definition.Name)
+		}
+	}
+
+	gsn := dm.GsnManager.GetNewGsn()
+	transactionRow, err := indexArgs.definition.ToTransactionRow(dm.TransactionManager.GetNewTransactionId(), common.DB_OP_CREATE_INDEX, gsn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dm.AddTransactionToWal(transactionRow)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dm.StoreManager.ApplyIndexTxnRow(transactionRow)
+	if err != nil {
+		return nil, err
+	}
+	return []byte("OK"), nil
+}
+
+func ensureDropIndex(dm *dbmanager.DBManager, cmd *common.Command) (*IndexArgs, error) {
+	if len(cmd.Args) != 2 || !strings.EqualFold(cmd.Args[0], "INDEX") {
+		return nil, errors.New("usage: DROP INDEX <name>")
+	}
+
+	for _, definition := range dm.StoreManager.IndexManager.Definitions() {
+		if definition.Name == cmd.Args[1] {
+			return &IndexArgs{definition: definition}, nil
+		}
+	}
+	return nil, fmt.Errorf("index not found: %s", cmd.Args[1])
+}
+
+func execDropIndex(dm *dbmanager.DBManager, indexArgs *IndexArgs, ctx *CommandContext) ([]byte, error) {
+	gsn := dm.GsnManager.GetNewGsn()
+	transactionRow, err := indexArgs.definition.ToTransactionRow(dm.TransactionManager.GetNewTransactionId(), common.DB_OP_DROP_INDEX, gsn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dm.AddTransactionToWal(transactionRow)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dm.StoreManager.ApplyIndexTxnRow(transactionRow)
+	if err != nil {
+		return nil, err
+	}
+	return []byte("OK"), nil
+}
@@ -2,13 +2,14 @@ package commands
 
 import (
 	"errors"
+	"meteor/internal/authmanager"
 	"meteor/internal/common"
 	"meteor/internal/dbmanager"
 	"strconv"
 )
 
 func init() {
-	Register("DELETE", []ArgSpec{
+	Register("DELETE", authmanager.CategoryWrite, firstKey, []ArgSpec{
 		{ Name: "key", Type: "string", Required: true, Description: "The key to delete" },
 		{ Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to delete" },
 	}, ensureDelete, execDelete)
@@ -61,78 +62,15 @@ func ensureDelete(dm *dbmanager.DBManager, cmd *common.Command) (*DeleteArgs, er
 }
 
 func execDelete(dm *dbmanager.DBManager, deleteArgs *DeleteArgs, ctx *CommandContext) ([]byte, error) {
-	key := deleteArgs.key
-	isPartOfExistingTransaction := deleteArgs.isPartOfExistingTransaction
-	transactionId := deleteArgs.transactionId
-
-	isolationLevel, err := dm.TransactionManager.GetIsolationLevel(transactionId)
-	if err != nil {
-		return nil, err
-	}
-
-	// Acquire write lock. But this lock is not released immediately for non-transactional operations since lock should be released only after commit or rollback.
-	err = dm.TransactionManager.AcquireWriteLock(transactionId, key, isolationLevel)
+	_, err := writeValue(dm, deleteArgs.transactionId, deleteArgs.isPartOfExistingTransaction, common.DB_OP_DELETE, deleteArgs.key, ctx, func(oldValue *common.V) (*common.V, error) {
+		return &common.V{Type: common.TypeTombstone, Value: nil}, nil
+	})
 	if err != nil {
-		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
-	
-	gsn := dm.GsnManager.GetNewGsn()
-
-	keyObj := &common.K{Key: key, Gsn: gsn}
-
-	// Get old value using the correct read order (transaction store first, then buffer store)
-	oldValue, err := dm.TransactionManager.ReadValue(transactionId, key, dm.StoreManager.BufferStore, ctx.clientConnection)
-	if err != nil {
-		dm.TransactionManager.ClearTransactionStore(transactionId)
-		return nil, err
-	}
-
-	tombstone := &common.V{Type: common.TypeTombstone, Value: nil}
-
-	// Validate write based on isolation level
-	err = dm.TransactionManager.ValidateWrite(transactionId, key, dm.StoreManager.BufferStore, ctx.clientConnection)
-	if err != nil {
-		dm.TransactionManager.ClearTransactionStore(transactionId)
-		return nil, err
-	}
-
-	// if not part of existing transaction, commit immediately as single operation
-	transactionState := common.TRANSACTION_STATE_COMMIT
 
-	// if part of existing transaction, queue the operation
-	if isPartOfExistingTransaction {
-		transactionState = common.TRANSACTION_STATE_QUEUED
+	if deleteArgs.isPartOfExistingTransaction {
+		return []byte("QUEUED"), nil
 	}
-
-	transactionRow := common.NewTransactionRow(transactionId, common.DB_OP_DELETE, transactionState, keyObj, oldValue, tombstone)
-
-	// the operation is stored in transaction store (queued)
-	err = dm.TransactionManager.AddTransaction(transactionRow, ctx.clientConnection)
-	if err != nil {
-		dm.TransactionManager.ClearTransactionStore(transactionId)
-		return nil, err
-	}
-
-	err = dm.AddTransactionToWal(transactionRow)
-	if err != nil {
-		dm.TransactionManager.ClearTransactionStore(transactionId)
-		return nil, err
-	}
-
-	// if not part of existing transaction, put the transaction row to the buffer store as single operation
-	if !isPartOfExistingTransaction {
-		err = dm.StoreManager.PutTxnRowToBufferStore(transactionRow)
-		if err != nil {
-			dm.TransactionManager.ClearTransactionStore(transactionId)
-			return nil, err
-		}
-		
-		// Release locks immediately for non-transactional operations
-		dm.TransactionManager.ClearTransactionStore(transactionId)
-		
-		return []byte("OK"), nil
-	}
-
-	return []byte("QUEUED"), nil
+	return []byte("OK"), nil
 }
@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"errors"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+)
+
+func init() {
+	Register("DELRANGE", authmanager.CategoryWrite, keyRange, []ArgSpec{
+		{Name: "startKey", Type: "string", Required: true, Description: "The starting key of the range to delete (inclusive)"},
+		{Name: "endKey", Type: "string", Required: true, Description: "The ending key of the range to delete (inclusive)"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to delete the range in"},
+	}, ensureDelRange, execDelRange)
+
+	Register("DELPREFIX", authmanager.CategoryWrite, keyPrefix, []ArgSpec{
+		{Name: "prefix", Type: "string", Required: true, Description: "The prefix of the keys to delete"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to delete the prefix in"},
+	}, ensureDelPrefix, execDelRange)
+}
+
+type DelRangeArgs struct {
+	startKey                    string
+	endKey                      string
+	prefix                      string
+	isPrefix                    bool
+	isPartOfExistingTransaction bool
+	transactionId               uint32
+}
+
+func ensureDelRange(dm *dbmanager.DBManager, cmd *common.Command) (*DelRangeArgs, error) {
+	argLen := len(cmd.Args)
+	if argLen < 2 {
+		return nil, errors.New("command must have at least two arguments - startKey, endKey")
+	}
+	if argLen > 3 {
+		return nil, errors.New("command must have at most 3 arguments - startKey, endKey, transactionId")
+	}
+
+	delRangeArgs := &DelRangeArgs{
+		startKey: cmd.Args[0],
+		endKey:   cmd.Args[1],
+	}
+
+	// Validate that startKey <= endKey lexicographically
+	if delRangeArgs.startKey > delRangeArgs.endKey {
+		return nil, errors.New("startKey must be lexicographically less than or equal to endKey")
+	}
+
+	transactionId, isPartOfExistingTransaction, err := parseOptionalTransactionId(dm, cmd.Args[2:])
+	if err != nil {
+		return nil, err
+	}
+	delRangeArgs.transactionId = transactionId
+	delRangeArgs.isPartOfExistingTransaction = isPartOfExistingTransaction
+
+	return delRangeArgs, nil
+}
+
+func ensureDelPrefix(dm *dbmanager.DBManager, cmd *common.Command) (*DelRangeArgs, error) {
+	argLen := len(cmd.Args)
+	if argLen < 1 {
+		return nil, errors.New("command must have at least one argument - prefix")
+	}
+	if argLen > 2 {
+		return nil, errors.New("command must have at most 2 arguments - prefix, transactionId")
+	}
+
+	// An empty prefix would delete the whole database, which is almost certainly a mistake
+	if cmd.Args[0] == "" {
+		return nil, errors.New("prefix must not be empty")
+	}
+
+	transactionId, isPartOfExistingTransaction, err := parseOptionalTransactionId(dm, cmd.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &DelRangeArgs{
+		prefix:                      cmd.Args[0],
+		isPrefix:                    true,
+		isPartOfExistingTransaction: isPartOfExistingTransaction,
+		transactionId:               transactionId,
+	}, nil
+}
+
+// execDelRange writes a single range tombstone instead of one tombstone per key. Under SERIALIZABLE a range lock
+// is taken for the deleted range. A range delete outside a transaction takes it at every isolation level, like
+// single key writes take their write locks, so it waits for the transactions writing keys in the range and its
+// tombstone is newer than their writes.
+func execDelRange(dm *dbmanager.DBManager, delRangeArgs *DelRangeArgs, ctx *CommandContext) ([]byte, error) {
+	transactionId := delRangeArgs.transactionId
+
+	var rangeTombstone *common.RangeTombstone
+	if delRangeArgs.isPrefix {
+		rangeTombstone = common.NewPrefixTombstone(delRangeArgs.prefix, 0)
+	} else {
+		rangeTombstone = common.NewRangeTombstone(delRangeArgs.startKey, delRangeArgs.endKey, 0)
+	}
+
+	startKey, endKey := rangeTombstone.Bounds()
+	ctx.traceTransaction(dm, transactionId)
+	err := ctx.waitForLock(func() error {
+		if delRangeArgs.isPartOfExistingTransaction {
+			return dm.TransactionManager.AcquireRangeLock(transactionId, startKey, endKey)
+		}
+		return dm.TransactionManager.AcquireRangeWriteLock(transactionId, startKey, endKey)
+	})
+	if err != nil {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+		return nil, err
+	}
+
+	// The GSN is taken once the lock is held, after the writes the range delete waited for
+	rangeTombstone.Gsn = dm.GsnManager.GetNewGsn()
+
+	// if not part of existing transaction, commit immediately as single operation
+	transactionState := common.TRANSACTION_STATE_COMMIT
+
+	// if part of existing transaction, queue the operation
+	if delRangeArgs.isPartOfExistingTransaction {
+		transactionState = common.TRANSACTION_STATE_QUEUED
+	}
+
+	transactionRow := rangeTombstone.ToTransactionRow(transactionId, transactionState)
+
+	err = dm.TransactionManager.AddTransaction(transactionRow, ctx.clientConnection)
+	if err != nil {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+		return nil, err
+	}
+
+	err = dm.AddTransactionToWal(transactionRow)
+	if err != nil {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+		return nil, err
+	}
+
+	if delRangeArgs.isPartOfExistingTransaction {
+		return []byte("QUEUED"), nil
+	}
+
+	err = dm.StoreManager.PutTxnRowToBufferStore(transactionRow)
+	if err != nil {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+		return nil, err
+	}
+
+	// Release locks immediately for non-transactional operations
+	dm.TransactionManager.ClearTransactionStore(transactionId)
+
+	return []byte("OK"), nil
+}
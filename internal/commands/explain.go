@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"meteor/internal/parser"
+	"strings"
+)
+
+func init() {
+	Register("EXPLAIN", authmanager.CategoryRead, conditionKeys(1), []ArgSpec{
+		{Name: "command", Type: "string", Required: true, Description: "The command to explain, SCAN or COUNT"},
+		{Name: "condition", Type: "string", Required: true, Description: "The condition of the command (e.g., '$key LIKE user:% AND $value > 100')"},
+	}, ensureExplain, execExplain)
+}
+
+type ExplainArgs struct {
+	command string
+	plan    *parser.ScanPlan
+}
+
+// explainResult is the plan shown by EXPLAIN. Filter is the residual condition applied to the records read.
+type explainResult struct {
+	Command string  `json:"command"`
+	Access  string  `json:"access"`
+	Filter  *string `json:"filter"`
+	Lock    string  `json:"lock"`
+}
+
+func ensureExplain(dm *dbmanager.DBManager, cmd *common.Command) (*ExplainArgs, error) {
+	if len(cmd.Args) != 2 {
+		return nil, errors.New("command must have 2 arguments - command, condition")
+	}
+
+	command := strings.ToUpper(cmd.Args[0])
+	if command != "SCAN" && command != "COUNT" {
+		return nil, fmt.Errorf("EXPLAIN supports SCAN and COUNT, got %s", cmd.Args[0])
+	}
+
+	plan, err := parser.PlanScan(cmd.Args[1], dm.StoreManager.IndexManager.Definitions())
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition: %v", err)
+	}
+
+	return &ExplainArgs{command: command, plan: plan}, nil
+}
+
+// execExplain shows the plan without reading any records, so it doesn't take a transaction
+func execExplain(dm *dbmanager.DBManager, explainArgs *ExplainArgs, ctx *CommandContext) ([]byte, error) {
+	plan := explainArgs.plan
+	result := explainResult{
+		Command: explainArgs.command,
+		Access:  plan.AccessString(),
+		Lock:    planLockString(plan),
+	}
+	if plan.Access != parser.AccessNone && plan.Residual != nil {
+		filter := plan.Residual.String()
+		result.Filter = &filter
+	}
+
+	// Conditions are shown as written, without escaping < and >
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(result); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -2,20 +2,23 @@ package commands
 
 import (
 	"errors"
+	"meteor/internal/authmanager"
 	"meteor/internal/common"
 	"meteor/internal/dbmanager"
-	"strconv"
+	"strings"
 )
 
 func init() {
-	Register("GET", []ArgSpec{
+	Register("GET", authmanager.CategoryRead, firstKey, []ArgSpec{
 		{Name: "key", Type: "string", Required: true, Description: "The key to get"},
+		{Name: "path", Type: "string", Required: false, Description: "PATH <json path>, get part of a json value (e.g., PATH $.user.name)"},
 		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to get the key from"},
 	}, ensureGet, execGet)
 }
 
 type GetArgs struct {
 	key                         string
+	path                        common.JsonPath
 	isPartOfExistingTransaction bool
 	transactionId               uint32
 }
@@ -31,27 +34,29 @@ func ensureGet(dm *dbmanager.DBManager, cmd *common.Command) (*GetArgs, error) {
 		transactionId:               0,
 	}
 
-	if len(cmd.Args) == 1 {
-		getArgs.transactionId = dm.TransactionManager.GetNewTransactionId()
-	}
-
-	if len(cmd.Args) == 2 {
-		transactionId64Bits, err := strconv.ParseUint(cmd.Args[1], 10, 32)
+	rest := cmd.Args[1:]
+	if len(rest) > 0 && strings.EqualFold(rest[0], "PATH") {
+		if len(rest) < 2 {
+			return nil, errors.New("PATH must be followed by a json path")
+		}
+		path, err := common.ParseJsonPath(rest[1])
 		if err != nil {
-			return nil, errors.New("invalid transactionId")
+			return nil, err
 		}
-		getArgs.transactionId = uint32(transactionId64Bits)
+		getArgs.path = path
+		rest = rest[2:]
+	}
 
-		if dm.TransactionManager.IsNewTransactionId(getArgs.transactionId) {
-			return nil, errors.New("transactionId not allowed")
-		} else {
-			getArgs.isPartOfExistingTransaction = true
-		}
+	if len(rest) > 1 {
+		return nil, errors.New("command must have at most these arguments - key, PATH, transactionId")
 	}
 
-	if len(cmd.Args) > 2 {
-		return nil, errors.New("command must have at most 2 arguments - key, transactionId")
+	transactionId, isPartOfExistingTransaction, err := parseOptionalTransactionId(dm, rest)
+	if err != nil {
+		return nil, err
 	}
+	getArgs.transactionId = transactionId
+	getArgs.isPartOfExistingTransaction = isPartOfExistingTransaction
 
 	return getArgs, nil
 }
@@ -59,13 +64,15 @@ func ensureGet(dm *dbmanager.DBManager, cmd *common.Command) (*GetArgs, error) {
 func execGet(dm *dbmanager.DBManager, getArgs *GetArgs, ctx *CommandContext) ([]byte, error) {
 	key := getArgs.key
 	transactionId := getArgs.transactionId
-	isolationLevel, err := dm.TransactionManager.GetIsolationLevel(transactionId)
+	isolationLevel, err := ctx.isolationLevel(dm, transactionId)
 	if err != nil {
 		return nil, err
 	}
 
 	// Acquire read lock based on isolation level
-	err = dm.TransactionManager.AcquireReadLock(transactionId, key, isolationLevel)
+	err = ctx.waitForLock(func() error {
+		return dm.TransactionManager.AcquireReadLock(transactionId, key, isolationLevel)
+	})
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
@@ -82,6 +89,7 @@ func execGet(dm *dbmanager.DBManager, getArgs *GetArgs, ctx *CommandContext) ([]
 		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
+	ctx.trace.rowsExamined = 1
 
 	var valueToStore *common.V
 	var valueToReturn []byte
@@ -94,9 +102,24 @@ func execGet(dm *dbmanager.DBManager, getArgs *GetArgs, ctx *CommandContext) ([]
 	case v.Type == common.TypeTombstone:
 		valueToStore = &common.V{Type: common.TypeTombstone, Value: nil}
 		valueToReturn = []byte("-2")
+	case getArgs.path != nil:
+		if v.Type != common.TypeJson {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, errors.New("value is not a json document")
+		}
+		valueToStore = v
+		valueToReturn = []byte("-1")
+		ctx.trace.rowsReturned = 1
+		// A missing path is reported like a missing key
+		if part := v.JsonPathValue(getArgs.path); part != nil && part.Type == common.TypeNull {
+			valueToReturn = []byte("null")
+		} else if part != nil {
+			valueToReturn = []byte(part.Format())
+		}
 	default:
 		valueToStore = v
-		valueToReturn = v.Value
+		valueToReturn = []byte(v.Format())
+		ctx.trace.rowsReturned = 1
 	}
 
 	// Store read value in transaction store for REPEATABLE_READ, SNAPSHOT_ISOLATION and SERIALIZABLE
@@ -107,5 +130,10 @@ func execGet(dm *dbmanager.DBManager, getArgs *GetArgs, ctx *CommandContext) ([]
 		return nil, err
 	}
 
+	// Reads outside of a transaction leave no transaction behind
+	if !getArgs.isPartOfExistingTransaction {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+	}
+
 	return valueToReturn, nil
 }
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"slices"
+	"strings"
+)
+
+func init() {
+	Register("GRANT", authmanager.CategoryAdmin, nil, []ArgSpec{
+		{Name: "categories", Type: "string", Required: true, Description: "Comma separated categories to grant, read, write, admin or ALL"},
+		{Name: "prefix", Type: "string", Required: true, Description: "ON PREFIX <prefix>, the keys the categories are granted on ('' for all keys)"},
+		{Name: "user", Type: "string", Required: true, Description: "TO <name>, the user to grant the categories to"},
+	}, ensureGrant(true), execGrant)
+
+	Register("REVOKE", authmanager.CategoryAdmin, nil, []ArgSpec{
+		{Name: "categories", Type: "string", Required: true, Description: "Comma separated categories to revoke, read, write, admin or ALL"},
+		{Name: "prefix", Type: "string", Required: true, Description: "ON PREFIX <prefix>, the prefix the categories were granted on"},
+		{Name: "user", Type: "string", Required: true, Description: "FROM <name>, the user to revoke the categories from"},
+	}, ensureGrant(false), execGrant)
+}
+
+type GrantArgs struct {
+	isGrant    bool
+	categories []string
+	prefix     string
+	user       string
+}
+
+func ensureGrant(isGrant bool) func(*dbmanager.DBManager, *common.Command) (*GrantArgs, error) {
+	usage, preposition := "usage: GRANT <categories> ON PREFIX <prefix> TO <user>", "TO"
+	if !isGrant {
+		usage, preposition = "usage: REVOKE <categories> ON PREFIX <prefix> FROM <user>", "FROM"
+	}
+
+	return func(dm *dbmanager.DBManager, cmd *common.Command) (*GrantArgs, error) {
+		args := cmd.Args
+		if len(args) != 6 || !strings.EqualFold(args[1], "ON") || !strings.EqualFold(args[2], "PREFIX") || !strings.EqualFold(args[4], preposition) {
+			return nil, errors.New(usage)
+		}
+
+		categories, err := parseCategories(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &GrantArgs{isGrant: isGrant, categories: categories, prefix: args[3], user: args[5]}, nil
+	}
+}
+
+// parseCategories parses a comma separated list of categories, ALL stands for every category
+func parseCategories(input string) ([]string, error) {
+	var categories []string
+	for _, category := range strings.Split(input, ",") {
+		category = strings.ToLower(strings.TrimSpace(category))
+		switch {
+		case category == "all":
+			categories = append(categories, authmanager.Categories...)
+		case slices.Contains(authmanager.Categories, category):
+			categories = append(categories, category)
+		default:
+			return nil, fmt.Errorf("unknown category %q, expected one of %s or ALL", category, strings.Join(authmanager.Categories, ", "))
+		}
+	}
+	slices.Sort(categories)
+	return slices.Compact(categories), nil
+}
+
+// execGrant adds the categories to the user's grant on the prefix, or removes them from it. Grants are kept
+// sorted by prefix and dropped when their last category is revoked.
+func execGrant(dm *dbmanager.DBManager, grantArgs *GrantArgs, ctx *CommandContext) ([]byte, error) {
+	return updateUser(dm, grantArgs.user, func(user *authmanager.User, userCount int) (*authmanager.User, string, error) {
+		if user == nil {
+			return nil, "", fmt.Errorf("user not found: %s", grantArgs.user)
+		}
+
+		i := slices.IndexFunc(user.Grants, func(grant authmanager.Grant) bool {
+			return grant.Prefix == grantArgs.prefix
+		})
+
+		if grantArgs.isGrant {
+			if i == -1 {
+				user.Grants = append(user.Grants, authmanager.Grant{Prefix: grantArgs.prefix})
+				i = len(user.Grants) - 1
+			}
+			categories := append(user.Grants[i].Categories, grantArgs.categories...)
+			slices.Sort(categories)
+			user.Grants[i].Categories = slices.Compact(categories)
+			slices.SortFunc(user.Grants, func(a, b authmanager.Grant) int {
+				return strings.Compare(a.Prefix, b.Prefix)
+			})
+		} else {
+			if i == -1 {
+				return nil, "", fmt.Errorf("user %s has no grant on prefix %q", user.Name, grantArgs.prefix)
+			}
+			user.Grants[i].Categories = slices.DeleteFunc(user.Grants[i].Categories, func(category string) bool {
+				return slices.Contains(grantArgs.categories, category)
+			})
+			if len(user.Grants[i].Categories) == 0 {
+				user.Grants = slices.Delete(user.Grants, i, i+1)
+			}
+		}
+		return user, common.DB_OP_PUT_USER, nil
+	})
+}
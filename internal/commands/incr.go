@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"errors"
+	"math"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"strconv"
+)
+
+func init() {
+	Register("INCR", authmanager.CategoryWrite, firstKey, []ArgSpec{
+		{Name: "key", Type: "string", Required: true, Description: "The key to increment by 1"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to increment the key in"},
+	}, ensureIncrByOne(1), execIncr)
+
+	Register("DECR", authmanager.CategoryWrite, firstKey, []ArgSpec{
+		{Name: "key", Type: "string", Required: true, Description: "The key to decrement by 1"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to decrement the key in"},
+	}, ensureIncrByOne(-1), execIncr)
+
+	Register("INCRBY", authmanager.CategoryWrite, firstKey, []ArgSpec{
+		{Name: "key", Type: "string", Required: true, Description: "The key to increment"},
+		{Name: "delta", Type: "int64", Required: true, Description: "The amount to increment by"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to increment the key in"},
+	}, ensureIncrBy(false, 1), execIncr)
+
+	Register("DECRBY", authmanager.CategoryWrite, firstKey, []ArgSpec{
+		{Name: "key", Type: "string", Required: true, Description: "The key to decrement"},
+		{Name: "delta", Type: "int64", Required: true, Description: "The amount to decrement by"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to decrement the key in"},
+	}, ensureIncrBy(false, -1), execIncr)
+
+	Register("INCRBYFLOAT", authmanager.CategoryWrite, firstKey, []ArgSpec{
+		{Name: "key", Type: "string", Required: true, Description: "The key to increment"},
+		{Name: "delta", Type: "float64", Required: true, Description: "The amount to increment by"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to increment the key in"},
+	}, ensureIncrBy(true, 1), execIncr)
+}
+
+type IncrArgs struct {
+	key                         string
+	isFloat                     bool
+	delta                       int64
+	floatDelta                  float64
+	isPartOfExistingTransaction bool
+	transactionId               uint32
+}
+
+// ensureIncrByOne builds the validation for INCR and DECR which have an implicit delta
+func ensureIncrByOne(delta int64) func(*dbmanager.DBManager, *common.Command) (*IncrArgs, error) {
+	return func(dm *dbmanager.DBManager, cmd *common.Command) (*IncrArgs, error) {
+		argLen := len(cmd.Args)
+
+		if argLen < 1 {
+			return nil, errors.New("command must have at least one argument - key")
+		}
+
+		if argLen > 2 {
+			return nil, errors.New("command must have at most 2 arguments - key, transactionId")
+		}
+
+		transactionId, isPartOfExistingTransaction, err := parseOptionalTransactionId(dm, cmd.Args[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		return &IncrArgs{
+			key:                         cmd.Args[0],
+			delta:                       delta,
+			isPartOfExistingTransaction: isPartOfExistingTransaction,
+			transactionId:               transactionId,
+		}, nil
+	}
+}
+
+// ensureIncrBy builds the validation for commands with an explicit delta. sign is -1 for the DECR variants.
+func ensureIncrBy(isFloat bool, sign int64) func(*dbmanager.DBManager, *common.Command) (*IncrArgs, error) {
+	return func(dm *dbmanager.DBManager, cmd *common.Command) (*IncrArgs, error) {
+		argLen := len(cmd.Args)
+
+		if argLen < 2 {
+			return nil, errors.New("command must have at least 2 arguments - key, delta")
+		}
+
+		if argLen > 3 {
+			return nil, errors.New("command must have at most 3 arguments - key, delta, transactionId")
+		}
+
+		incrArgs := &IncrArgs{
+			key:     cmd.Args[0],
+			isFloat: isFloat,
+		}
+
+		if isFloat {
+			floatDelta, err := strconv.ParseFloat(cmd.Args[1], 64)
+			if err != nil || math.IsNaN(floatDelta) || math.IsInf(floatDelta, 0) {
+				return nil, errors.New("delta must be a valid float")
+			}
+			incrArgs.floatDelta = floatDelta
+		} else {
+			delta, err := strconv.ParseInt(cmd.Args[1], 10, 64)
+			if err != nil {
+				return nil, errors.New("delta must be a valid int64")
+			}
+			if sign < 0 {
+				if delta == math.MinInt64 {
+					return nil, errors.New("delta is out of range")
+				}
+				delta = -delta
+			}
+			incrArgs.delta = delta
+		}
+
+		transactionId, isPartOfExistingTransaction, err := parseOptionalTransactionId(dm, cmd.Args[2:])
+		if err != nil {
+			return nil, err
+		}
+		incrArgs.transactionId = transactionId
+		incrArgs.isPartOfExistingTransaction = isPartOfExistingTransaction
+
+		return incrArgs, nil
+	}
+}
+
+// execIncr performs the read-modify-write under the key's write lock and returns the new value.
+// Missing and deleted keys start from 0. Inside a transaction the write is queued, but the new value is
+// still returned since the write lock is held until commit.
+func execIncr(dm *dbmanager.DBManager, incrArgs *IncrArgs, ctx *CommandContext) ([]byte, error) {
+	newValue, err := writeValue(dm, incrArgs.transactionId, incrArgs.isPartOfExistingTransaction, common.DB_OP_PUT, incrArgs.key, ctx, func(oldValue *common.V) (*common.V, error) {
+		exists := oldValue != nil && oldValue.Type != common.TypeTombstone
+
+		if incrArgs.isFloat {
+			var current float64
+			if exists {
+				var err error
+				current, err = oldValue.Float64()
+				if err != nil {
+					return nil, err
+				}
+			}
+			result := current + incrArgs.floatDelta
+			if math.IsNaN(result) || math.IsInf(result, 0) {
+				return nil, errors.New("increment would produce NaN or Infinity")
+			}
+			return common.NewFloat64V(result), nil
+		}
+
+		var current int64
+		if exists {
+			var err error
+			current, err = oldValue.Int64()
+			if err != nil {
+				return nil, err
+			}
+		}
+		result := current + incrArgs.delta
+		if (incrArgs.delta > 0 && result < current) || (incrArgs.delta < 0 && result > current) {
+			return nil, errors.New("increment or decrement would overflow")
+		}
+		return common.NewInt64V(result), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(newValue.Format()), nil
+}
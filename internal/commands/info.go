@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/config"
+	"meteor/internal/datatable"
+	"meteor/internal/dbmanager"
+	"meteor/internal/store"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("INFO", authmanager.CategoryAdmin, nil, []ArgSpec{
+		{Name: "section", Type: "string", Required: false, Description: "The section to report, server, config, clients, transactions, locks, stores, wal or all (default)"},
+	}, ensureInfo, execInfo(false))
+
+	Register("STATS", authmanager.CategoryAdmin, nil, []ArgSpec{
+		{Name: "section", Type: "string", Required: false, Description: "The section to report, server, config, clients, transactions, locks, stores, wal or all (default)"},
+	}, ensureInfo, execInfo(true))
+}
+
+// infoSections are the sections of INFO and STATS in the order they are reported
+var infoSections = []string{"server", "config", "clients", "transactions", "locks", "stores", "wal"}
+
+type InfoArgs struct {
+	sections []string
+}
+
+type serverInfo struct {
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	StartedAt     string `json:"started_at"`
+	ProcessId     int    `json:"process_id"`
+	GoVersion     string `json:"go_version"`
+}
+
+type clientsInfo struct {
+	Connected int            `json:"connected"`
+	Protocols map[string]int `json:"protocols"`
+}
+
+type transactionsInfo struct {
+	Active       int               `json:"active"`
+	Transactions []transactionInfo `json:"transactions"`
+}
+
+type transactionInfo struct {
+	Id         uint32  `json:"id"`
+	Isolation  string  `json:"isolation"`
+	StartGsn   uint32  `json:"start_gsn,omitempty"`
+	AgeSeconds float64 `json:"age_seconds"`
+}
+
+type storesInfo struct {
+	Buffer    []datatable.TableStats `json:"buffer"`
+	Immutable []datatable.TableStats `json:"immutable"`
+	Total     datatable.TableStats   `json:"total"`
+}
+
+type walInfo struct {
+	Offset            int64  `json:"offset"`
+	NextGsn           uint32 `json:"next_gsn"`
+	NextTransactionId uint32 `json:"next_transaction_id"`
+}
+
+func ensureInfo(dm *dbmanager.DBManager, cmd *common.Command) (*InfoArgs, error) {
+	if len(cmd.Args) > 1 {
+		return nil, errors.New("command must have at most 1 argument - section")
+	}
+	if len(cmd.Args) == 0 || strings.EqualFold(cmd.Args[0], "all") {
+		return &InfoArgs{sections: infoSections}, nil
+	}
+
+	section := strings.ToLower(cmd.Args[0])
+	if !slices.Contains(infoSections, section) {
+		return nil, fmt.Errorf("unknown section %q, expected one of %s or all", cmd.Args[0], strings.Join(infoSections, ", "))
+	}
+	return &InfoArgs{sections: []string{section}}, nil
+}
+
+// execInfo reports the state of the running server. INFO writes it as "key:value" lines under a "# Section"
+// header per section, followed by an END line over the text protocol. STATS writes a JSON object with a member
+// per section.
+func execInfo(asJson bool) func(*dbmanager.DBManager, *InfoArgs, *CommandContext) ([]byte, error) {
+	return func(dm *dbmanager.DBManager, infoArgs *InfoArgs, ctx *CommandContext) ([]byte, error) {
+		if asJson {
+			report := make(map[string]any, len(infoArgs.sections))
+			for _, section := range infoArgs.sections {
+				report[section] = collectInfo(dm, section)
+			}
+			return json.Marshal(report)
+		}
+
+		var text strings.Builder
+		for i, section := range infoArgs.sections {
+			if i > 0 {
+				text.WriteString("\n")
+			}
+			fmt.Fprintf(&text, "# %s\n", strings.ToUpper(section[:1])+section[1:])
+			for _, line := range infoLines(collectInfo(dm, section)) {
+				text.WriteString(line)
+				text.WriteString("\n")
+			}
+		}
+		// The text protocol frames replies by newline, so its clients read the lines up to an END marker
+		if client, ok := dm.ClientManager.Client(ctx.clientConnection); ok && client.Protocol == "text" {
+			text.WriteString("END")
+			return []byte(text.String()), nil
+		}
+		return []byte(strings.TrimSuffix(text.String(), "\n")), nil
+	}
+}
+
+func collectInfo(dm *dbmanager.DBManager, section string) any {
+	now := time.Now()
+	switch section {
+	case "server":
+		return serverInfo{
+			UptimeSeconds: int64(now.Sub(dm.StartedAt).Seconds()),
+			StartedAt:     dm.StartedAt.UTC().Format(time.RFC3339),
+			ProcessId:     os.Getpid(),
+			GoVersion:     runtime.Version(),
+		}
+
+	case "config":
+		// Embedded databases aren't configured by a config file
+		if config.Config == nil {
+			return map[string]any{}
+		}
+		return config.Config.Settings()
+
+	case "clients":
+		clients := dm.ClientManager.Clients()
+		info := clientsInfo{Connected: len(clients), Protocols: make(map[string]int)}
+		for _, client := range clients {
+			info.Protocols[client.Protocol]++
+		}
+		return info
+
+	case "transactions":
+		transactions := dm.TransactionManager.Transactions()
+		info := transactionsInfo{Active: len(transactions), Transactions: make([]transactionInfo, 0, len(transactions))}
+		for _, transaction := range transactions {
+			info.Transactions = append(info.Transactions, transactionInfo{
+				Id:         transaction.Id,
+				Isolation:  transaction.IsolationLevel,
+				StartGsn:   transaction.StartGsn,
+				AgeSeconds: now.Sub(transaction.StartedAt).Seconds(),
+			})
+		}
+		return info
+
+	case "locks":
+		return dm.TransactionManager.GetLockStatistics()
+
+	case "stores":
+		info := storesInfo{Buffer: []datatable.TableStats{}, Immutable: []datatable.TableStats{}}
+		if bufferStore, ok := dm.StoreManager.BufferStore.(*store.BufferStore); ok {
+			info.Buffer = bufferStore.ShardStats()
+		}
+		for _, immutableStore := range dm.StoreManager.ImmutableStores {
+			info.Immutable = append(info.Immutable, storeStats(immutableStore))
+		}
+		for _, stats := range slices.Concat(info.Buffer, info.Immutable) {
+			info.Total.Keys += stats.Keys
+			info.Total.Versions += stats.Versions
+			info.Total.Bytes += stats.Bytes
+			info.Total.RangeTombstones += stats.RangeTombstones
+		}
+		return info
+
+	default:
+		return walInfo{
+			Offset:            dm.WalManager.Offset(),
+			NextGsn:           dm.GsnManager.NextGsn(),
+			NextTransactionId: dm.TransactionManager.NextTransactionId(),
+		}
+	}
+}
+
+// storeStats returns the stats of a store, only the number of keys of stores that can't count their versions
+func storeStats(s store.Store) datatable.TableStats {
+	if statsStore, ok := s.(interface{ Stats() datatable.TableStats }); ok {
+		return statsStore.Stats()
+	}
+	keys, _ := s.Size()
+	return datatable.TableStats{Keys: keys}
+}
+
+// infoLines formats a section as "key:value" lines
+func infoLines(info any) []string {
+	switch info := info.(type) {
+	case serverInfo:
+		return []string{
+			fmt.Sprintf("uptime_seconds:%d", info.UptimeSeconds),
+			"started_at:" + info.StartedAt,
+			fmt.Sprintf("process_id:%d", info.ProcessId),
+			"go_version:" + info.GoVersion,
+		}
+
+	case map[string]any:
+		lines := make([]string, 0, len(info))
+		for key, value := range info {
+			lines = append(lines, fmt.Sprintf("%s:%v", key, value))
+		}
+		slices.Sort(lines)
+		return lines
+
+	case clientsInfo:
+		lines := []string{fmt.Sprintf("connected_clients:%d", info.Connected)}
+		protocolLines := make([]string, 0, len(info.Protocols))
+		for protocol, count := range info.Protocols {
+			protocolLines = append(protocolLines, fmt.Sprintf("clients_%s:%d", protocol, count))
+		}
+		slices.Sort(protocolLines)
+		return append(lines, protocolLines...)
+
+	case transactionsInfo:
+		lines := []string{fmt.Sprintf("active_transactions:%d", info.Active)}
+		for _, transaction := range info.Transactions {
+			startGsn := "-"
+			if transaction.StartGsn != 0 {
+				startGsn = fmt.Sprint(transaction.StartGsn)
+			}
+			lines = append(lines, fmt.Sprintf("transaction_%d:isolation=%s,start_gsn=%s,age_seconds=%.3f", transaction.Id, transaction.Isolation, startGsn, transaction.AgeSeconds))
+		}
+		return lines
+
+	case storesInfo:
+		lines := make([]string, 0, len(info.Buffer)+len(info.Immutable)+1)
+		for i, stats := range info.Buffer {
+			lines = append(lines, fmt.Sprintf("buffer_shard_%d:%s", i, formatTableStats(stats)))
+		}
+		for i, stats := range info.Immutable {
+			lines = append(lines, fmt.Sprintf("immutable_%d:%s", i, formatTableStats(stats)))
+		}
+		return append(lines, "total:"+formatTableStats(info.Total))
+
+	case walInfo:
+		return []string{
+			fmt.Sprintf("wal_offset:%d", info.Offset),
+			fmt.Sprintf("next_gsn:%d", info.NextGsn),
+			fmt.Sprintf("next_transaction_id:%d", info.NextTransactionId),
+		}
+	}
+	return nil
+}
+
+func formatTableStats(stats datatable.TableStats) string {
+	return fmt.Sprintf("keys=%d,versions=%d,bytes=%d,range_tombstones=%d", stats.Keys, stats.Versions, stats.Bytes, stats.RangeTombstones)
+}
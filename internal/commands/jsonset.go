@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"errors"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+)
+
+func init() {
+	Register("JSONSET", authmanager.CategoryWrite, firstKey, []ArgSpec{
+		{Name: "key", Type: "string", Required: true, Description: "The key of the json document to update"},
+		{Name: "path", Type: "string", Required: true, Description: "The json path to set (e.g., $.user.age)"},
+		{Name: "value", Type: "string", Required: true, Description: "The new value, parsed as json if valid, otherwise stored as a string"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to update the document in"},
+	}, ensureJsonSet, execJsonSet)
+}
+
+type JsonSetArgs struct {
+	key                         string
+	path                        common.JsonPath
+	value                       *common.V
+	isPartOfExistingTransaction bool
+	transactionId               uint32
+}
+
+func ensureJsonSet(dm *dbmanager.DBManager, cmd *common.Command) (*JsonSetArgs, error) {
+	argLen := len(cmd.Args)
+
+	if argLen < 3 {
+		return nil, errors.New("command must have at least 3 arguments - key, path, value")
+	}
+
+	if argLen > 4 {
+		return nil, errors.New("command must have at most 4 arguments - key, path, value, transactionId")
+	}
+
+	path, err := common.ParseJsonPath(cmd.Args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// Values that aren't valid json, such as unquoted words, are set as strings
+	value, err := common.NewJsonV(cmd.Args[2])
+	if err != nil {
+		value = &common.V{Type: common.TypeString, Value: []byte(cmd.Args[2])}
+	}
+
+	transactionId, isPartOfExistingTransaction, err := parseOptionalTransactionId(dm, cmd.Args[3:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &JsonSetArgs{
+		key:                         cmd.Args[0],
+		path:                        path,
+		value:                       value,
+		isPartOfExistingTransaction: isPartOfExistingTransaction,
+		transactionId:               transactionId,
+	}, nil
+}
+
+// execJsonSet replaces the part of the document selected by the path under the key's write lock.
+// Missing and deleted keys start from an empty document. Like INCR, the new document is returned
+// inside a transaction too, since the write lock is held until commit.
+func execJsonSet(dm *dbmanager.DBManager, jsonSetArgs *JsonSetArgs, ctx *CommandContext) ([]byte, error) {
+	newValue, err := writeValue(dm, jsonSetArgs.transactionId, jsonSetArgs.isPartOfExistingTransaction, common.DB_OP_PUT, jsonSetArgs.key, ctx, func(oldValue *common.V) (*common.V, error) {
+		if oldValue != nil && oldValue.Type == common.TypeTombstone {
+			oldValue = nil
+		}
+		return common.SetJsonPath(oldValue, jsonSetArgs.path, jsonSetArgs.value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(newValue.Format()), nil
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"errors"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+)
+
+func init() {
+	Register("MDEL", authmanager.CategoryWrite, keyList, []ArgSpec{
+		{Name: "keys", Type: "[]string", Required: true, Description: "The keys to delete"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "TXN followed by the transaction id to delete the keys in"},
+	}, ensureMdel, execMdel)
+}
+
+type MdelArgs struct {
+	keys                        []string
+	isPartOfExistingTransaction bool
+	transactionId               uint32
+}
+
+func ensureMdel(dm *dbmanager.DBManager, cmd *common.Command) (*MdelArgs, error) {
+	if len(cmd.Args) < 1 {
+		return nil, errors.New("command must have at least one argument - keys")
+	}
+
+	keys, transactionId, isPartOfExistingTransaction, err := splitKeysAndTransactionId(dm, cmd.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MdelArgs{
+		keys:                        uniqueSortedKeys(keys),
+		isPartOfExistingTransaction: isPartOfExistingTransaction,
+		transactionId:               transactionId,
+	}, nil
+}
+
+// execMdel writes tombstones for all keys as one atomic transaction
+func execMdel(dm *dbmanager.DBManager, mdelArgs *MdelArgs, ctx *CommandContext) ([]byte, error) {
+	writes := make([]keyWrite, 0, len(mdelArgs.keys))
+	for _, key := range mdelArgs.keys {
+		writes = append(writes, keyWrite{
+			operation: common.DB_OP_DELETE,
+			key:       key,
+			computeValue: func(oldValue *common.V) (*common.V, error) {
+				return &common.V{Type: common.TypeTombstone, Value: nil}, nil
+			},
+		})
+	}
+
+	_, err := writeValues(dm, mdelArgs.transactionId, mdelArgs.isPartOfExistingTransaction, writes, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if mdelArgs.isPartOfExistingTransaction {
+		return []byte("QUEUED"), nil
+	}
+	return []byte("OK"), nil
+}
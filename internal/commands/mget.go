@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+)
+
+func init() {
+	Register("MGET", authmanager.CategoryRead, keyList, []ArgSpec{
+		{Name: "keys", Type: "[]string", Required: true, Description: "The keys to get"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "TXN followed by the transaction id to get the keys from"},
+	}, ensureMget, execMget)
+}
+
+type MgetArgs struct {
+	keys                        []string
+	isPartOfExistingTransaction bool
+	transactionId               uint32
+}
+
+func ensureMget(dm *dbmanager.DBManager, cmd *common.Command) (*MgetArgs, error) {
+	if len(cmd.Args) < 1 {
+		return nil, errors.New("command must have at least one argument - keys")
+	}
+
+	keys, transactionId, isPartOfExistingTransaction, err := splitKeysAndTransactionId(dm, cmd.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MgetArgs{
+		keys:                        keys,
+		isPartOfExistingTransaction: isPartOfExistingTransaction,
+		transactionId:               transactionId,
+	}, nil
+}
+
+// execMget reads all keys while holding read locks on all of them, so the result is a consistent snapshot even
+// under READ_COMMITTED. Returns a JSON array with the values in the requested order and null for missing keys.
+func execMget(dm *dbmanager.DBManager, mgetArgs *MgetArgs, ctx *CommandContext) ([]byte, error) {
+	transactionId := mgetArgs.transactionId
+	isolationLevel, err := ctx.isolationLevel(dm, transactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := uniqueSortedKeys(mgetArgs.keys)
+
+	// Acquire read locks in sorted key order to avoid deadlocks with multi-key writes
+	for _, key := range keys {
+		err = ctx.waitForLock(func() error {
+			return dm.TransactionManager.AcquireReadLock(transactionId, key, isolationLevel)
+		})
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+	}
+
+	values := make(map[string]*common.V, len(keys))
+	for _, key := range keys {
+		v, err := dm.TransactionManager.ReadValue(transactionId, key, dm.StoreManager.BufferStore, ctx.clientConnection)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+		values[key] = v
+		ctx.trace.rowsExamined++
+
+		err = addReadValueToTxnStore(dm, transactionId, key, v, isolationLevel, ctx.clientConnection)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+	}
+
+	if mgetArgs.isPartOfExistingTransaction {
+		// Release read locks for READ_COMMITTED, other isolation levels hold them until the transaction ends
+		for _, key := range keys {
+			_ = dm.TransactionManager.ReleaseReadLock(transactionId, key, isolationLevel)
+		}
+	} else {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+	}
+
+	jsonResults := make([]any, len(mgetArgs.keys))
+	for i, key := range mgetArgs.keys {
+		v := values[key]
+		if v == nil || v.Type == common.TypeTombstone {
+			jsonResults[i] = nil
+			continue
+		}
+		jsonResults[i] = v.Native()
+		ctx.trace.rowsReturned++
+	}
+
+	return json.Marshal(jsonResults)
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"meteor/internal/monitor"
+	"net"
+	"time"
+)
+
+func init() {
+	Register("MONITOR", authmanager.CategoryAdmin, nil, []ArgSpec{}, ensureMonitor, execMonitor)
+}
+
+const (
+	// monitorBufferSize is the number of events a monitor buffers before it drops events
+	monitorBufferSize = 1024
+	// monitorWriteTimeout closes monitors that don't read their events
+	monitorWriteTimeout = 10 * time.Second
+)
+
+type MonitorArgs struct{}
+
+func ensureMonitor(dm *dbmanager.DBManager, cmd *common.Command) (*MonitorArgs, error) {
+	if len(cmd.Args) != 0 {
+		return nil, errors.New("command takes no arguments")
+	}
+	return &MonitorArgs{}, nil
+}
+
+// publishCommand sends an executed command to the monitors. Commands that failed before executing have no
+// transaction id.
+func publishCommand(dm *dbmanager.DBManager, name string, cmd *common.Command, startedAt time.Time, duration time.Duration, transactionId uint32, err error) {
+	if !dm.Monitor.Active() {
+		return
+	}
+
+	event := monitor.Event{
+		Time:          startedAt,
+		Command:       name,
+		Args:          redactedArgs(name, cmd.Args),
+		TransactionId: transactionId,
+		Status:        "OK",
+		Duration:      duration,
+	}
+	if client, ok := dm.ClientManager.Client(cmd.Connection); ok {
+		event.ClientId = client.Id
+		event.Client = client.RemoteAddr
+	}
+	if err != nil {
+		event.Status = common.ClassifyError(err).String()
+	}
+	dm.Monitor.Publish(event)
+}
+
+// execMonitor turns the connection into a feed of the commands every client executes, one line per command,
+// until the client sends anything or disconnects. The connection is closed afterwards. Events a slow client
+// doesn't read in time are dropped and reported, and a client that doesn't read at all is disconnected.
+// It returns a nil result because the response is written to the connection.
+func execMonitor(dm *dbmanager.DBManager, monitorArgs *MonitorArgs, ctx *CommandContext) ([]byte, error) {
+	client, ok := dm.ClientManager.Client(ctx.clientConnection)
+	if !ok || (client.Protocol != "text" && client.Protocol != "resp") {
+		return nil, errors.New("MONITOR requires a text or RESP connection")
+	}
+	conn := *ctx.clientConnection
+	defer conn.Close()
+
+	// RESP clients read the events as simple strings
+	write := func(line string) error {
+		if client.Protocol == "resp" {
+			line = "+" + line + "\r"
+		}
+		if err := conn.SetWriteDeadline(time.Now().Add(monitorWriteTimeout)); err != nil {
+			return err
+		}
+		_, err := conn.Write([]byte(line + "\n"))
+		return err
+	}
+
+	subscription := dm.Monitor.Subscribe(monitorBufferSize)
+	defer dm.Monitor.Unsubscribe(subscription)
+
+	if err := write("OK"); err != nil {
+		return nil, err
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		_, _ = conn.Read(make([]byte, 1))
+		close(stopped)
+	}()
+
+	var reportedDrops uint64
+	for {
+		select {
+		case <-stopped:
+			return nil, nil
+		case event := <-subscription.Events():
+			if drops := subscription.Dropped(); drops > reportedDrops {
+				if err := write(fmt.Sprintf("%s dropped %d events", time.Now().UTC().Format("2006-01-02T15:04:05.000000Z"), drops-reportedDrops)); err != nil {
+					return nil, monitorWriteError(err)
+				}
+				reportedDrops = drops
+			}
+			if err := write(event.Format()); err != nil {
+				return nil, monitorWriteError(err)
+			}
+		}
+	}
+}
+
+// monitorWriteError reports a monitor that stopped reading, a closed connection ends the monitor without error
+func monitorWriteError(err error) error {
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return fmt.Errorf("monitor stopped: %w", err)
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"errors"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+)
+
+func init() {
+	Register("MSET", authmanager.CategoryWrite, keyPairs, []ArgSpec{
+		{Name: "keyValues", Type: "[]string", Required: true, Description: "Alternating keys and values to set"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to put the keys and values to"},
+	}, ensureMset, execMset)
+}
+
+type MsetArgs struct {
+	keys                        []string
+	values                      map[string]string
+	isPartOfExistingTransaction bool
+	transactionId               uint32
+}
+
+func ensureMset(dm *dbmanager.DBManager, cmd *common.Command) (*MsetArgs, error) {
+	argLen := len(cmd.Args)
+
+	if argLen < 2 {
+		return nil, errors.New("command must have at least 2 arguments - key, value")
+	}
+
+	// An odd number of arguments means the last one is the transactionId
+	pairs := cmd.Args[:argLen-argLen%2]
+	transactionId, isPartOfExistingTransaction, err := parseOptionalTransactionId(dm, cmd.Args[len(pairs):])
+	if err != nil {
+		return nil, err
+	}
+
+	msetArgs := &MsetArgs{
+		keys:                        make([]string, 0, len(pairs)/2),
+		values:                      make(map[string]string, len(pairs)/2),
+		isPartOfExistingTransaction: isPartOfExistingTransaction,
+		transactionId:               transactionId,
+	}
+
+	// If a key is repeated, the last value wins
+	for i := 0; i < len(pairs); i += 2 {
+		key := pairs[i]
+		if _, ok := msetArgs.values[key]; !ok {
+			msetArgs.keys = append(msetArgs.keys, key)
+		}
+		msetArgs.values[key] = pairs[i+1]
+	}
+
+	return msetArgs, nil
+}
+
+// execMset writes all pairs as one atomic transaction
+func execMset(dm *dbmanager.DBManager, msetArgs *MsetArgs, ctx *CommandContext) ([]byte, error) {
+	writes := make([]keyWrite, 0, len(msetArgs.keys))
+	for _, key := range msetArgs.keys {
+		value := msetArgs.values[key]
+		writes = append(writes, keyWrite{
+			operation: common.DB_OP_PUT,
+			key:       key,
+			computeValue: func(oldValue *common.V) (*common.V, error) {
+				return &common.V{Type: common.TypeString, Value: []byte(value)}, nil
+			},
+		})
+	}
+
+	_, err := writeValues(dm, msetArgs.transactionId, msetArgs.isPartOfExistingTransaction, writes, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if msetArgs.isPartOfExistingTransaction {
+		return []byte("QUEUED"), nil
+	}
+	return []byte("OK"), nil
+}
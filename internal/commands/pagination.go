@@ -0,0 +1,227 @@
+package commands
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"strconv"
+	"strings"
+)
+
+// pageOptions holds the optional ORDER BY, LIMIT, OFFSET, CURSOR and STREAM clauses of range reads
+type pageOptions struct {
+	isSet      bool
+	stream     bool
+	descending bool
+	limit      int // -1 if no limit
+	offset     int
+	cursor     *resultCursor
+}
+
+// resultCursor marks where the previous page ended. Pages outside a transaction are read from the snapshot at
+// Gsn so paging through a large result is consistent even while other clients write.
+type resultCursor struct {
+	LastKey    string `json:"k"`
+	Gsn        uint32 `json:"g"`
+	Descending bool   `json:"d,omitempty"`
+}
+
+type pageRow struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+type pageResult struct {
+	Results []pageRow `json:"results"`
+	Cursor  string    `json:"cursor,omitempty"`
+}
+
+func encodeCursor(cursor *resultCursor) string {
+	cursorBytes, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(cursorBytes)
+}
+
+func decodeCursor(token string) (*resultCursor, error) {
+	cursorBytes, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	cursor := &resultCursor{}
+	if err := json.Unmarshal(cursorBytes, cursor); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// IsStream reports whether a command is an RGET or SCAN with the STREAM option. Streamed results are written to
+// the connection row by row in the text format, so the other protocols reject them.
+func IsStream(cmd *common.Command) bool {
+	var args []string
+	switch {
+	case strings.EqualFold(cmd.Operation, "RGET") && len(cmd.Args) >= 2:
+		args = cmd.Args[2:]
+	case strings.EqualFold(cmd.Operation, "SCAN") && len(cmd.Args) >= 1:
+		args = cmd.Args[1:]
+	default:
+		return false
+	}
+	opts, _, err := parsePageOptions(args)
+	return err == nil && opts.stream
+}
+
+// parsePageOptions parses the page clauses at the start of args and returns the remaining arguments
+func parsePageOptions(args []string) (*pageOptions, []string, error) {
+	opts := &pageOptions{limit: -1}
+	orderSet := false
+
+	for len(args) > 0 {
+		switch strings.ToUpper(args[0]) {
+		case "ORDER":
+			if len(args) < 4 || strings.ToUpper(args[1]) != "BY" || strings.ToLower(args[2]) != "key" {
+				return nil, nil, errors.New("ORDER must be followed by BY key ASC|DESC")
+			}
+			switch strings.ToUpper(args[3]) {
+			case "ASC":
+				opts.descending = false
+			case "DESC":
+				opts.descending = true
+			default:
+				return nil, nil, errors.New("ORDER BY key must be followed by ASC or DESC")
+			}
+			orderSet = true
+			args = args[4:]
+		case "LIMIT":
+			if len(args) < 2 {
+				return nil, nil, errors.New("LIMIT must be followed by a number")
+			}
+			limit, err := strconv.Atoi(args[1])
+			if err != nil || limit <= 0 {
+				return nil, nil, errors.New("LIMIT must be a positive number")
+			}
+			opts.limit = limit
+			args = args[2:]
+		case "OFFSET":
+			if len(args) < 2 {
+				return nil, nil, errors.New("OFFSET must be followed by a number")
+			}
+			offset, err := strconv.Atoi(args[1])
+			if err != nil || offset < 0 {
+				return nil, nil, errors.New("OFFSET must be a non-negative number")
+			}
+			opts.offset = offset
+			args = args[2:]
+		case "CURSOR":
+			if len(args) < 2 {
+				return nil, nil, errors.New("CURSOR must be followed by a cursor")
+			}
+			cursor, err := decodeCursor(args[1])
+			if err != nil {
+				return nil, nil, err
+			}
+			opts.cursor = cursor
+			args = args[2:]
+		case "STREAM":
+			opts.stream = true
+			args = args[1:]
+		default:
+			return opts, args, nil
+		}
+		opts.isSet = true
+	}
+
+	// A cursor continues in the order it was created with
+	if opts.cursor != nil {
+		if orderSet && opts.cursor.Descending != opts.descending {
+			return nil, nil, errors.New("cursor was created with a different ORDER BY")
+		}
+		opts.descending = opts.cursor.Descending
+	}
+
+	return opts, args, nil
+}
+
+// pinSnapshot makes a paged read outside a transaction read from a fixed snapshot, the one of the cursor or a
+// new one for the first page. It returns the snapshot GSN to store in the next cursor.
+// Reads that are part of an existing transaction keep the transaction's own view.
+func pinSnapshot(dm *dbmanager.DBManager, transactionId uint32, isPartOfExistingTransaction bool, opts *pageOptions) (uint32, error) {
+	if isPartOfExistingTransaction {
+		startGsn, _ := dm.TransactionManager.GetTransactionStartGsn(transactionId)
+		return startGsn, nil
+	}
+
+	var gsn uint32
+	if opts.cursor != nil {
+		gsn = opts.cursor.Gsn
+	} else {
+		gsn = dm.GsnManager.GetNewGsn()
+	}
+
+	err := dm.TransactionManager.EnsureIsolationLevel(transactionId, common.TXN_ISOLATION_SNAPSHOT_ISOLATION)
+	if err != nil {
+		return 0, err
+	}
+	dm.TransactionManager.SetTransactionStartGsn(transactionId, gsn)
+	return gsn, nil
+}
+
+// walkPage passes the rows of the page the cursor, OFFSET and LIMIT select to emit, in the order of the iterator.
+// It stops at the first row after the page and returns a cursor if there is one.
+func walkPage(iterator common.KVIterator, opts *pageOptions, snapshotGsn uint32, emit func(key string, value *common.V) error) (string, error) {
+	skipped := 0
+	rows := 0
+	lastKey := ""
+	for iterator.Next() {
+		key, value := iterator.Key(), iterator.Value()
+		if value == nil {
+			continue
+		}
+		if opts.cursor != nil && (!opts.descending && key <= opts.cursor.LastKey || opts.descending && key >= opts.cursor.LastKey) {
+			continue
+		}
+		if skipped < opts.offset {
+			skipped++
+			continue
+		}
+
+		// There is one more row than the limit, so the client needs a cursor to continue
+		if opts.limit >= 0 && rows == opts.limit {
+			return encodeCursor(&resultCursor{LastKey: lastKey, Gsn: snapshotGsn, Descending: opts.descending}), nil
+		}
+
+		if err := emit(key, value); err != nil {
+			return "", err
+		}
+		rows++
+		lastKey = key
+	}
+	return "", nil
+}
+
+// pageRead reads a page from the iterator. Inside a transaction the returned values are recorded like any other
+// read, a single operation only read from its pinned snapshot and is cleared.
+func pageRead(dm *dbmanager.DBManager, transactionId uint32, isPartOfExistingTransaction bool, iterator common.KVIterator, isolationLevel string, opts *pageOptions, snapshotGsn uint32, ctx *CommandContext) ([]byte, error) {
+	page := pageResult{Results: []pageRow{}}
+	values := make(map[string]*common.V)
+	cursor, err := walkPage(iterator, opts, snapshotGsn, func(key string, value *common.V) error {
+		page.Results = append(page.Results, pageRow{Key: key, Value: value.Native()})
+		values[key] = value
+		return nil
+	})
+	if err == nil && isPartOfExistingTransaction {
+		err = addReadValuesToTxnStoreByAcquiringLocks(dm, transactionId, values, isolationLevel, ctx)
+	}
+	if err != nil {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+		return nil, err
+	}
+	page.Cursor = cursor
+
+	ctx.trace.rowsReturned = len(page.Results)
+	jsonBytes, err := json.Marshal(page)
+	if err != nil || !isPartOfExistingTransaction {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+	}
+	return jsonBytes, err
+}
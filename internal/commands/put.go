@@ -2,22 +2,42 @@ package commands
 
 import (
 	"errors"
+	"fmt"
+	"meteor/internal/authmanager"
 	"meteor/internal/common"
 	"meteor/internal/dbmanager"
 	"strconv"
+	"strings"
 )
 
 func init() {
-	Register("PUT", []ArgSpec{
+	Register("PUT", authmanager.CategoryWrite, firstKey, []ArgSpec{
 			{Name: "key", Type: "string", Required: true, Description: "The key to set"},
 			{Name: "value", Type: "string", Required: true, Description: "The value to set"},
+			{Name: "condition", Type: "string", Required: false, Description: "NX (only if absent), XX (only if present) or IF_VERSION <gsn> (only if the latest version matches)"},
+			{Name: "type", Type: "string", Required: false, Description: "TYPE <int8|int16|int32|int64|uint8|uint16|uint32|uint64|float32|float64|bool|bytes|string|json>, defaults to string. PUT key json '{...}' is a shorthand for TYPE json"},
 			{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id to put the key and value to"},
 		}, ensurePut, execPut)
 }
 
+// putCondition restricts a PUT to succeed only when the current state of the key matches
+type putCondition int
+
+const (
+	putConditionNone putCondition = iota
+	putConditionNotExists
+	putConditionExists
+	putConditionVersion
+	putConditionValue
+)
+
 type PutArgs struct {
 	key string
 	value string
+	valueType common.DataType
+	condition putCondition
+	expectedGsn uint32
+	expectedValue string
 	isPartOfExistingTransaction bool
 	transactionId uint32
 }
@@ -32,114 +52,144 @@ func ensurePut(dm *dbmanager.DBManager, cmd *common.Command) (*PutArgs, error) {
 	putArgs := &PutArgs{
 		key: cmd.Args[0],
 		value: cmd.Args[1],
+		valueType: common.TypeString,
 		isPartOfExistingTransaction: false,
 		transactionId: 0,
 	}
 
-	// if no transactionId, get a new one
-	if argLen == 2 {
-		putArgs.transactionId = dm.TransactionManager.GetNewTransactionId()
+	// Optional condition and type modifiers come before the optional transactionId
+	rest := cmd.Args[2:]
+	hasType := false
+
+	// PUT key json '{...}' is a shorthand for PUT key '{...}' TYPE json. Only objects and arrays are accepted
+	// here so a plain value "json" followed by a transaction id keeps working.
+	if argLen >= 3 && strings.EqualFold(cmd.Args[1], "json") && isJsonDocument(cmd.Args[2]) {
+		putArgs.value = cmd.Args[2]
+		putArgs.valueType = common.TypeJson
+		hasType = true
+		rest = cmd.Args[3:]
 	}
+	for len(rest) > 0 {
+		modifier := strings.ToUpper(rest[0])
+		if modifier == "TYPE" {
+			if hasType {
+				return nil, errors.New("TYPE can only be specified once")
+			}
+			if len(rest) < 2 {
+				return nil, errors.New("TYPE must be followed by a type name")
+			}
+			valueType, err := common.ParseDataType(rest[1])
+			if err != nil {
+				return nil, err
+			}
+			putArgs.valueType = valueType
+			hasType = true
+			rest = rest[2:]
+			continue
+		}
 
-	// if transactionId, check if it is part of an existing transaction
-	if argLen == 3 {
-		transactionId64Bits, err := strconv.ParseUint(cmd.Args[2], 10, 32)
-		if err != nil {
-			return nil, errors.New("invalid transactionId")
+		if modifier != "NX" && modifier != "XX" && modifier != "IF_VERSION" {
+			break
+		}
+		if putArgs.condition != putConditionNone {
+			return nil, errors.New("only one of NX, XX or IF_VERSION can be specified")
 		}
-		putArgs.transactionId = uint32(transactionId64Bits)
 
-		if dm.TransactionManager.IsNewTransactionId(putArgs.transactionId) {
-			return nil, errors.New("transactionId not allowed")
-		} else {
-			putArgs.isPartOfExistingTransaction = true
+		switch modifier {
+		case "NX":
+			putArgs.condition = putConditionNotExists
+			rest = rest[1:]
+		case "XX":
+			putArgs.condition = putConditionExists
+			rest = rest[1:]
+		case "IF_VERSION":
+			if len(rest) < 2 {
+				return nil, errors.New("IF_VERSION must be followed by a gsn")
+			}
+			gsn64Bits, err := strconv.ParseUint(rest[1], 10, 32)
+			if err != nil {
+				return nil, errors.New("invalid gsn for IF_VERSION")
+			}
+			putArgs.condition = putConditionVersion
+			putArgs.expectedGsn = uint32(gsn64Bits)
+			rest = rest[2:]
 		}
 	}
 
-	if argLen > 3 {
-		return nil, errors.New("command must have at most 3 arguments - key, value, transactionId")
+	if len(rest) > 1 {
+		return nil, errors.New("command must have at most 3 arguments - key, value, transactionId (excluding condition and type)")
 	}
 
-	return putArgs, nil
-}
-
-func execPut(dm *dbmanager.DBManager, putArgs *PutArgs, ctx *CommandContext) ([]byte, error) {
-	key := putArgs.key
-	value := []byte(putArgs.value)
-	isPartOfExistingTransaction := putArgs.isPartOfExistingTransaction
-	transactionId := putArgs.transactionId
-
-	isolationLevel, err := dm.TransactionManager.GetIsolationLevel(transactionId)
-	if err != nil {
-		return nil, err
-	}
-
-	// Acquire write lock. But this lock is not released immediately for non-transactional operations since lock should be released only after commit or rollback.
-	err = dm.TransactionManager.AcquireWriteLock(transactionId, key, isolationLevel)
-	if err != nil {
-		dm.TransactionManager.ClearTransactionStore(transactionId)
+	// Validate the value against its type before any lock is taken
+	if _, err := common.NewTypedV(putArgs.valueType, putArgs.value); err != nil {
 		return nil, err
 	}
 
-	var valueType common.DataType = common.TypeString
-
-	gsn := dm.GsnManager.GetNewGsn()
-
-	keyObj := &common.K{Key: key, Gsn: gsn}
-	valueObj := &common.V{Type: valueType, Value: value}
-
-	// Get old value using the correct read order (transaction store first, then buffer store)
-	var oldValue *common.V
-	// Use ReadValue to get the proper old value considering isolation level
-	oldValue, err = dm.TransactionManager.ReadValue(transactionId, key, dm.StoreManager.BufferStore, ctx.clientConnection)
+	transactionId, isPartOfExistingTransaction, err := parseOptionalTransactionId(dm, rest)
 	if err != nil {
-		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
+	putArgs.transactionId = transactionId
+	putArgs.isPartOfExistingTransaction = isPartOfExistingTransaction
 
-	// Validate write based on isolation level
-	err = dm.TransactionManager.ValidateWrite(transactionId, key, dm.StoreManager.BufferStore, ctx.clientConnection)
-	if err != nil {
-		dm.TransactionManager.ClearTransactionStore(transactionId)
-		return nil, err
-	}
-
-	// if not part of existing transaction, commit immediately as single operation
-	transactionState := common.TRANSACTION_STATE_COMMIT
-
-	// if part of existing transaction, queue the operation
-	if isPartOfExistingTransaction {
-		transactionState = common.TRANSACTION_STATE_QUEUED
-	}
-
-	transactionRow := common.NewTransactionRow(transactionId, common.DB_OP_PUT, transactionState, keyObj, oldValue, valueObj)
+	return putArgs, nil
+}
 
-	// the operation is stored in transaction store (queued)
-	err = dm.TransactionManager.AddTransaction(transactionRow, ctx.clientConnection)
+func execPut(dm *dbmanager.DBManager, putArgs *PutArgs, ctx *CommandContext) ([]byte, error) {
+	_, err := writeValue(dm, putArgs.transactionId, putArgs.isPartOfExistingTransaction, common.DB_OP_PUT, putArgs.key, ctx, func(oldValue *common.V) (*common.V, error) {
+		// Conditions are checked while holding the write lock so no other writer can change the key in between
+		if err := checkPutCondition(dm, putArgs); err != nil {
+			return nil, err
+		}
+		return common.NewTypedV(putArgs.valueType, putArgs.value)
+	})
 	if err != nil {
-		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
 
-	err = dm.AddTransactionToWal(transactionRow)
-	if err != nil {
-		dm.TransactionManager.ClearTransactionStore(transactionId)
-		return nil, err
+	if putArgs.isPartOfExistingTransaction {
+		return []byte("QUEUED"), nil
 	}
+	return []byte("OK"), nil
+}
 
-	// if not part of existing transaction, put the transaction row to the buffer store as single operation
-	if !isPartOfExistingTransaction {
-		err = dm.StoreManager.PutTxnRowToBufferStore(transactionRow)
+// checkPutCondition verifies the condition of a conditional PUT against the latest committed version of the key,
+// the version VERSION reports. NX, XX, IF_VERSION and CAS all use it, so they agree with each other regardless of
+// the isolation level, and neither the snapshot of the transaction nor its own uncommitted writes are considered.
+func checkPutCondition(dm *dbmanager.DBManager, putArgs *PutArgs) error {
+	currentValue := dm.StoreManager.BufferStore.Get(putArgs.key)
+	exists := currentValue != nil && currentValue.Type != common.TypeTombstone
+
+	switch putArgs.condition {
+	case putConditionNone:
+		return nil
+	case putConditionNotExists:
+		if exists {
+			return errors.New("condition not met - key already exists")
+		}
+	case putConditionExists:
+		if !exists {
+			return errors.New("condition not met - key does not exist")
+		}
+	case putConditionVersion:
+		// A key which was never written has version 0
+		latestGsn, err := dm.StoreManager.BufferStore.GetLatestGsn(putArgs.key)
 		if err != nil {
-			dm.TransactionManager.ClearTransactionStore(transactionId)
-			return nil, err
+			latestGsn = 0
+		}
+		if latestGsn != putArgs.expectedGsn {
+			return fmt.Errorf("condition not met - version mismatch, expected %d but latest is %d", putArgs.expectedGsn, latestGsn)
+		}
+	case putConditionValue:
+		if !exists || currentValue.Format() != putArgs.expectedValue {
+			return errors.New("condition not met - value mismatch")
 		}
-		
-		// Release locks immediately for non-transactional operations
-		dm.TransactionManager.ClearTransactionStore(transactionId)
-		
-		return []byte("OK"), nil
 	}
+	return nil
+}
 
-	return []byte("QUEUED"), nil
-}
\ No newline at end of file
+// isJsonDocument checks if the input looks like a JSON object or array
+func isJsonDocument(input string) bool {
+	trimmed := strings.TrimSpace(input)
+	return strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")
+}
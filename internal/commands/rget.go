@@ -3,15 +3,20 @@ package commands
 import (
 	"encoding/json"
 	"errors"
+	"meteor/internal/authmanager"
 	"meteor/internal/common"
 	"meteor/internal/dbmanager"
-	"strconv"
 )
 
 func init() {
-	Register("RGET", []ArgSpec{
+	Register("RGET", authmanager.CategoryRead, keyRange, []ArgSpec{
 		{Name: "startKey", Type: "string", Required: true, Description: "The starting key of the range"},
 		{Name: "endKey", Type: "string", Required: true, Description: "The ending key of the range"},
+		{Name: "order", Type: "string", Required: false, Description: "ORDER BY key ASC|DESC"},
+		{Name: "limit", Type: "string", Required: false, Description: "LIMIT <n>, the maximum number of results to return"},
+		{Name: "offset", Type: "string", Required: false, Description: "OFFSET <n>, the number of results to skip"},
+		{Name: "cursor", Type: "string", Required: false, Description: "CURSOR <cursor>, continue after the page that returned the cursor"},
+		{Name: "stream", Type: "string", Required: false, Description: "STREAM, send the results row by row followed by an END marker"},
 		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id for the range get operation"},
 	}, ensureRget, execRget)
 }
@@ -19,6 +24,7 @@ func init() {
 type RgetArgs struct {
 	startKey                    string
 	endKey                      string
+	pageOptions                 *pageOptions
 	isPartOfExistingTransaction bool
 	transactionId               uint32
 }
@@ -28,10 +34,6 @@ func ensureRget(dm *dbmanager.DBManager, cmd *common.Command) (*RgetArgs, error)
 	if argLen < 2 {
 		return nil, errors.New("command must have at least two arguments - startKey, endKey")
 	}
-	if argLen > 3 {
-		return nil, errors.New("command must have at most 3 arguments - startKey, endKey, transactionId")
-	}
-
 	rgetArgs := &RgetArgs{
 		startKey:                    cmd.Args[0],
 		endKey:                      cmd.Args[1],
@@ -44,21 +46,19 @@ func ensureRget(dm *dbmanager.DBManager, cmd *common.Command) (*RgetArgs, error)
 		return nil, errors.New("startKey must be lexicographically less than or equal to endKey")
 	}
 
-	// Handle optional transactionId argument
-	if argLen == 3 {
-		transactionId64Bits, err := strconv.ParseUint(cmd.Args[2], 10, 32)
-		if err != nil {
-			return nil, errors.New("invalid transactionId")
-		}
-		rgetArgs.transactionId = uint32(transactionId64Bits)
+	pageOptions, rest, err := parsePageOptions(cmd.Args[2:])
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 1 {
+		return nil, errors.New("command must have at most these arguments - startKey, endKey, ORDER BY, LIMIT, OFFSET, CURSOR, STREAM, transactionId")
+	}
+	rgetArgs.pageOptions = pageOptions
 
-		if dm.TransactionManager.IsNewTransactionId(rgetArgs.transactionId) {
-			return nil, errors.New("transactionId not allowed")
-		} else {
-			rgetArgs.isPartOfExistingTransaction = true
-		}
-	} else {
-		rgetArgs.transactionId = dm.TransactionManager.GetNewTransactionId()
+	// Handle optional transactionId argument
+	rgetArgs.transactionId, rgetArgs.isPartOfExistingTransaction, err = parseOptionalTransactionId(dm, rest)
+	if err != nil {
+		return nil, err
 	}
 
 	return rgetArgs, nil
@@ -66,26 +66,57 @@ func ensureRget(dm *dbmanager.DBManager, cmd *common.Command) (*RgetArgs, error)
 
 func execRget(dm *dbmanager.DBManager, rgetArgs *RgetArgs, ctx *CommandContext) ([]byte, error) {
 	transactionId := rgetArgs.transactionId
-	isolationLevel, err := dm.TransactionManager.GetIsolationLevel(transactionId)
+
+	var snapshotGsn uint32
+	if rgetArgs.pageOptions.isSet {
+		var err error
+		snapshotGsn, err = pinSnapshot(dm, transactionId, rgetArgs.isPartOfExistingTransaction, rgetArgs.pageOptions)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+	}
+
+	isolationLevel, err := ctx.isolationLevel(dm, transactionId)
 	if err != nil {
 		return nil, err
 	}
 
 	// Acquire range lock for [startKey, endKey] to prevent phantom reads
-	err = dm.TransactionManager.AcquireRangeLock(transactionId, rgetArgs.startKey, rgetArgs.endKey)
+	err = ctx.waitForLock(func() error {
+		return dm.TransactionManager.AcquireRangeLock(transactionId, rgetArgs.startKey, rgetArgs.endKey)
+	})
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
 
+	// Pages are read in key order from an iterator, so a page reads only up to the row after it
+	if rgetArgs.pageOptions.isSet {
+		iterator, err := dm.TransactionManager.IterateValues(transactionId, rgetArgs.pageOptions.iteratorOptions(rgetArgs.startKey, rgetArgs.endKey), dm.StoreManager.BufferStore, ctx.clientConnection)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+		// Skip deleted entries
+		iterator = common.NewFilterIterator(iterator, ctx.examine(func(key string, value *common.V) bool {
+			return value.Type != common.TypeTombstone
+		}))
+		if rgetArgs.pageOptions.stream {
+			return streamRead(dm, transactionId, rgetArgs.isPartOfExistingTransaction, iterator, isolationLevel, rgetArgs.pageOptions, snapshotGsn, ctx)
+		}
+		return pageRead(dm, transactionId, rgetArgs.isPartOfExistingTransaction, iterator, isolationLevel, rgetArgs.pageOptions, snapshotGsn, ctx)
+	}
+
 	results, err := dm.TransactionManager.ReadRangeValues(transactionId, rgetArgs.startKey, rgetArgs.endKey, dm.StoreManager.BufferStore, ctx.clientConnection)
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
+	ctx.trace.rowsExamined = len(results)
 
 	// Process read values for transaction store and read locks
-	err = addReadValuesToTxnStoreByAcquiringLocks(dm, transactionId, results, isolationLevel, ctx.clientConnection)
+	err = addReadValuesToTxnStoreByAcquiringLocks(dm, transactionId, results, isolationLevel, ctx)
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
@@ -97,8 +128,9 @@ func execRget(dm *dbmanager.DBManager, rgetArgs *RgetArgs, ctx *CommandContext)
 		if value.Type == common.TypeTombstone {
 			continue // Skip deleted entries
 		}
-		jsonResults[key] = string(value.Value)
+		jsonResults[key] = value.Native()
 	}
+	ctx.trace.rowsReturned = len(jsonResults)
 
 	jsonBytes, err := json.Marshal(jsonResults)
 	if err != nil {
@@ -106,5 +138,10 @@ func execRget(dm *dbmanager.DBManager, rgetArgs *RgetArgs, ctx *CommandContext)
 		return nil, err
 	}
 
+	// Reads outside of a transaction leave no transaction behind
+	if !rgetArgs.isPartOfExistingTransaction {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+	}
+
 	return jsonBytes, nil
 }
@@ -2,13 +2,16 @@ package commands
 
 import (
 	"errors"
+	"log/slog"
+	"meteor/internal/authmanager"
 	"meteor/internal/common"
 	"meteor/internal/dbmanager"
+	"net"
 	"strconv"
 )
 
 func init() {
-	Register("ROLLBACK", []ArgSpec{
+	Register("ROLLBACK", authmanager.CategorySession, nil, []ArgSpec{
 		{ Name: "transactionId", Type: "uint32", Required: true, Description: "The transaction id to rollback" },
 	}, ensureRollback, execRollback)
 }
@@ -33,10 +36,20 @@ func ensureRollback(dm *dbmanager.DBManager, cmd *common.Command) (*RollbackArgs
 
 func execRollback(dm *dbmanager.DBManager, rollbackArgs *RollbackArgs, ctx *CommandContext) ([]byte, error) {
 	transactionId := rollbackArgs.transactionId
+	ctx.traceTransaction(dm, transactionId)
 
+	err := rollbackTransaction(dm, transactionId, ctx.clientConnection)
+	if err != nil {
+		return nil, err
+	}
+	return []byte("OK"), nil
+}
+
+// rollbackTransaction logs the rollback of a transaction to the WAL, drops its writes and releases its locks
+func rollbackTransaction(dm *dbmanager.DBManager, transactionId uint32, conn *net.Conn) error {
 	transactionStore := dm.TransactionManager.GetTransactionStore(transactionId)
 	if transactionStore == nil {
-		return nil, errors.New("transaction not found")
+		return errors.New("transaction not found")
 	}
 
 	gsn := dm.GsnManager.GetNewGsn()
@@ -44,19 +57,31 @@ func execRollback(dm *dbmanager.DBManager, rollbackArgs *RollbackArgs, ctx *Comm
 
 	transactionRow := common.NewTransactionRow(transactionId, common.DB_OP_ROLLBACK, common.TRANSACTION_STATE_ROLLBACK, key, nil, nil)
 
-	err := dm.TransactionManager.AddTransaction(transactionRow, ctx.clientConnection)
+	err := dm.TransactionManager.AddTransaction(transactionRow, conn)
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
-		return nil, err
+		return err
 	}
 	
 	err = dm.AddTransactionToWal(transactionRow)
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
-		return nil, err
+		return err
 	}
 
 	dm.TransactionManager.ClearTransactionStore(transactionId)
+	return nil
+}
 
-	return []byte("OK"), nil
+// RollbackConnection rolls back the transactions a closed connection left open, so they don't hold locks
+// forever, and forgets the connection
+func RollbackConnection(dm *dbmanager.DBManager, conn *net.Conn) {
+	for _, transactionId := range dm.TransactionManager.ConnectionTransactions(conn) {
+		if err := rollbackTransaction(dm, transactionId, conn); err != nil {
+			slog.Error("Failed to roll back transaction of closed connection", "transactionId", transactionId, "error", err)
+			continue
+		}
+		slog.Info("Rolled back transaction of closed connection", "transactionId", transactionId)
+	}
+	dm.TransactionManager.ForgetConnection(conn)
 }
@@ -4,21 +4,28 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"meteor/internal/authmanager"
 	"meteor/internal/common"
 	"meteor/internal/dbmanager"
 	"meteor/internal/parser"
-	"strconv"
 )
 
 func init() {
-	Register("SCAN", []ArgSpec{
+	Register("SCAN", authmanager.CategoryRead, conditionKeys(0), []ArgSpec{
 		{Name: "condition", Type: "string", Required: true, Description: "Condition for filtering (e.g., '$key LIKE user_%' or '$value > 100' or '$key = user1 AND $value > 50' or '*' for all records)"},
+		{Name: "order", Type: "string", Required: false, Description: "ORDER BY key ASC|DESC"},
+		{Name: "limit", Type: "string", Required: false, Description: "LIMIT <n>, the maximum number of results to return"},
+		{Name: "offset", Type: "string", Required: false, Description: "OFFSET <n>, the number of results to skip"},
+		{Name: "cursor", Type: "string", Required: false, Description: "CURSOR <cursor>, continue after the page that returned the cursor"},
+		{Name: "stream", Type: "string", Required: false, Description: "STREAM, send the results row by row followed by an END marker"},
 		{Name: "transactionId", Type: "uint32", Required: false, Description: "The transaction id for the scan operation"},
 	}, ensureScan, execScan)
 }
 
 type ScanArgs struct {
 	condition                   string
+	plan                        *parser.ScanPlan
+	pageOptions                 *pageOptions
 	isPartOfExistingTransaction bool
 	transactionId               uint32
 }
@@ -28,31 +35,32 @@ func ensureScan(dm *dbmanager.DBManager, cmd *common.Command) (*ScanArgs, error)
 	if argLen < 1 {
 		return nil, errors.New("command must have at least one argument - condition")
 	}
-	if argLen > 2 {
-		return nil, errors.New("command must have at most 2 arguments - condition, transactionId")
-	}
-
 	scanArgs := &ScanArgs{
 		condition:                   cmd.Args[0],
 		isPartOfExistingTransaction: false,
 		transactionId:               0,
 	}
 
-	// Handle optional transactionId argument
-	if argLen == 2 {
-		transactionId64Bits, err := strconv.ParseUint(cmd.Args[1], 10, 32)
-		if err != nil {
-			return nil, errors.New("invalid transactionId")
-		}
-		scanArgs.transactionId = uint32(transactionId64Bits)
+	// Conditions that bound the key are read by prefix or range instead of scanning every key
+	plan, err := parser.PlanScan(scanArgs.condition, dm.StoreManager.IndexManager.Definitions())
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition: %v", err)
+	}
+	scanArgs.plan = plan
 
-		if dm.TransactionManager.IsNewTransactionId(scanArgs.transactionId) {
-			return nil, errors.New("transactionId not allowed")
-		} else {
-			scanArgs.isPartOfExistingTransaction = true
-		}
-	} else {
-		scanArgs.transactionId = dm.TransactionManager.GetNewTransactionId()
+	pageOptions, rest, err := parsePageOptions(cmd.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 1 {
+		return nil, errors.New("command must have at most these arguments - condition, ORDER BY, LIMIT, OFFSET, CURSOR, STREAM, transactionId")
+	}
+	scanArgs.pageOptions = pageOptions
+
+	// Handle optional transactionId argument
+	scanArgs.transactionId, scanArgs.isPartOfExistingTransaction, err = parseOptionalTransactionId(dm, rest)
+	if err != nil {
+		return nil, err
 	}
 
 	return scanArgs, nil
@@ -60,7 +68,18 @@ func ensureScan(dm *dbmanager.DBManager, cmd *common.Command) (*ScanArgs, error)
 
 func execScan(dm *dbmanager.DBManager, scanArgs *ScanArgs, ctx *CommandContext) ([]byte, error) {
 	transactionId := scanArgs.transactionId
-	isolationLevel, err := dm.TransactionManager.GetIsolationLevel(transactionId)
+
+	var snapshotGsn uint32
+	if scanArgs.pageOptions.isSet {
+		var err error
+		snapshotGsn, err = pinSnapshot(dm, transactionId, scanArgs.isPartOfExistingTransaction, scanArgs.pageOptions)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+	}
+
+	isolationLevel, err := ctx.isolationLevel(dm, transactionId)
 	if err != nil {
 		return nil, err
 	}
@@ -71,53 +90,49 @@ func execScan(dm *dbmanager.DBManager, scanArgs *ScanArgs, ctx *CommandContext)
 		predicate = "SCAN(*)"
 	}
 
-	// Acquire predicate lock for the condition to prevent phantom reads
-	err = dm.TransactionManager.AcquirePredicateLock(transactionId, predicate)
+	// Acquire a range lock for the scanned keys, or a predicate lock for the condition, to prevent phantom reads
+	err = acquirePlanLock(dm, transactionId, ctx, scanArgs.plan, predicate)
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
 
-	// Create appropriate filter function
-	var filterFunc func(string, *common.V) bool
-	if scanArgs.condition == "*" {
-		// Full scan - return all non-tombstone records
-		filterFunc = func(key string, value *common.V) bool {
-			return value != nil && value.Type != common.TypeTombstone
-		}
-	} else {
-		// Parse the condition into a filter function using the new parser
-		conditionParser := parser.NewConditionParser(scanArgs.condition)
-		var parseErr error
-		filterFunc, parseErr = conditionParser.ParseExpression()
-		if parseErr != nil {
+	// Pages are read in key order from an iterator, so a page reads only up to the row after it
+	if scanArgs.pageOptions.isSet {
+		iterator, err := planIterator(dm, transactionId, scanArgs.plan, scanArgs.pageOptions, ctx)
+		if err != nil {
 			dm.TransactionManager.ClearTransactionStore(transactionId)
-			return nil, fmt.Errorf("invalid condition: %v", parseErr)
+			return nil, err
+		}
+		if scanArgs.pageOptions.stream {
+			return streamRead(dm, transactionId, scanArgs.isPartOfExistingTransaction, iterator, isolationLevel, scanArgs.pageOptions, snapshotGsn, ctx)
 		}
+		return pageRead(dm, transactionId, scanArgs.isPartOfExistingTransaction, iterator, isolationLevel, scanArgs.pageOptions, snapshotGsn, ctx)
 	}
 
 	// Execute scan using transaction-aware read
-	results, err := dm.TransactionManager.ReadFilteredValues(transactionId, filterFunc, dm.StoreManager.BufferStore, ctx.clientConnection)
+	results, err := readPlannedValues(dm, transactionId, scanArgs.plan, ctx)
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
 
 	// Process read values for transaction store and read locks
-	err = addReadValuesToTxnStoreByAcquiringLocks(dm, transactionId, results, isolationLevel, ctx.clientConnection)
+	err = addReadValuesToTxnStoreByAcquiringLocks(dm, transactionId, results, isolationLevel, ctx)
 	if err != nil {
 		dm.TransactionManager.ClearTransactionStore(transactionId)
 		return nil, err
 	}
 
-	// Convert results to JSON, excluding tombstones
+	// Convert results to JSON. Deleted entries are only returned if the condition asks for them, e.g. IS TOMBSTONE
 	jsonResults := make(map[string]interface{})
 	for key, value := range results {
-		if value == nil || value.Type == common.TypeTombstone {
-			continue // Skip deleted entries
+		if value == nil {
+			continue
 		}
-		jsonResults[key] = string(value.Value)
+		jsonResults[key] = value.Native()
 	}
+	ctx.trace.rowsReturned = len(jsonResults)
 
 	jsonBytes, err := json.Marshal(jsonResults)
 	if err != nil {
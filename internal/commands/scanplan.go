@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"maps"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"meteor/internal/parser"
+)
+
+// readPlannedValues reads the candidate records of a scan plan from a secondary index, or by prefix or key range
+// where the condition bounds the key, and keeps the ones matching the residual condition
+func readPlannedValues(dm *dbmanager.DBManager, transactionId uint32, plan *parser.ScanPlan, ctx *CommandContext) (map[string]*common.V, error) {
+	var results map[string]*common.V
+	var err error
+
+	candidateKeys, isIndexed, err := lookupPlanIndex(dm, transactionId, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case isIndexed:
+		results, err = dm.TransactionManager.ReadKeyValues(transactionId, candidateKeys, plan.StartKey, plan.EndKey, dm.StoreManager.BufferStore, ctx.clientConnection)
+	case plan.Access == parser.AccessNone:
+		return make(map[string]*common.V), nil
+	case plan.Access == parser.AccessPrefix:
+		results, err = dm.TransactionManager.ReadPrefixValues(transactionId, plan.Prefix, dm.StoreManager.BufferStore, ctx.clientConnection)
+	case plan.Access == parser.AccessRange:
+		results, err = dm.TransactionManager.ReadRangeValues(transactionId, plan.StartKey, plan.EndKey, dm.StoreManager.BufferStore, ctx.clientConnection)
+	default:
+		return dm.TransactionManager.ReadFilteredValues(transactionId, ctx.examine(plan.Filter), dm.StoreManager.BufferStore, ctx.clientConnection)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ctx.trace.rowsExamined += len(results)
+
+	maps.DeleteFunc(results, func(key string, value *common.V) bool {
+		return !plan.Filter(key, value)
+	})
+	return results, nil
+}
+
+// lookupPlanIndex looks up the candidate keys of a plan in its index at the GSN the transaction reads at.
+// It returns false if the plan has no index, or if the index was dropped or created after the transaction's snapshot.
+func lookupPlanIndex(dm *dbmanager.DBManager, transactionId uint32, plan *parser.ScanPlan) ([]string, bool, error) {
+	if plan.Index == nil {
+		return nil, false, nil
+	}
+
+	readGsn, err := dm.TransactionManager.GetReadGsn(transactionId)
+	if err != nil {
+		return nil, false, err
+	}
+	keys, ok := dm.StoreManager.IndexManager.Lookup(plan.Index.Name, plan.IndexConditions, readGsn)
+	return keys, ok, nil
+}
+
+// acquirePlanLock prevents phantom reads for a scan plan. Conditions that bound the key lock only their key range,
+// other conditions lock the whole predicate.
+func acquirePlanLock(dm *dbmanager.DBManager, transactionId uint32, ctx *CommandContext, plan *parser.ScanPlan, predicate string) error {
+	return ctx.waitForLock(func() error {
+		switch plan.Access {
+		case parser.AccessNone:
+			return nil
+		case parser.AccessPrefix, parser.AccessRange:
+			return dm.TransactionManager.AcquireRangeLock(transactionId, plan.StartKey, plan.EndKey)
+		default:
+			return dm.TransactionManager.AcquirePredicateLock(transactionId, predicate)
+		}
+	})
+}
+
+// planLockString describes the lock acquirePlanLock takes for SERIALIZABLE transactions
+func planLockString(plan *parser.ScanPlan) string {
+	switch plan.Access {
+	case parser.AccessNone:
+		return "NONE"
+	case parser.AccessPrefix, parser.AccessRange:
+		return "KEY RANGE"
+	default:
+		return "PREDICATE"
+	}
+}
+
+// planIterator iterates the candidate records of a scan plan in the order of the page options. Records found
+// through an index are read up front, since the index isn't ordered by key.
+func planIterator(dm *dbmanager.DBManager, transactionId uint32, plan *parser.ScanPlan, opts *pageOptions, ctx *CommandContext) (common.KVIterator, error) {
+	if plan.Index != nil {
+		results, err := readPlannedValues(dm, transactionId, plan, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return common.NewMapIterator(results, opts.descending), nil
+	}
+
+	// Iterators treat an empty end key as unbounded
+	endKey := plan.EndKey
+	if endKey == parser.MaxKey {
+		endKey = ""
+	}
+
+	iterator, err := dm.TransactionManager.IterateValues(transactionId, opts.iteratorOptions(plan.StartKey, endKey), dm.StoreManager.BufferStore, ctx.clientConnection)
+	if err != nil {
+		return nil, err
+	}
+	return common.NewFilterIterator(iterator, ctx.examine(plan.Filter)), nil
+}
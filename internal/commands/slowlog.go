@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("SLOWLOG", authmanager.CategoryAdmin, nil, []ArgSpec{
+		{Name: "action", Type: "string", Required: true, Description: "GET [n] returns the latest n slow commands, newest first, LEN the number kept, RESET drops them"},
+		{Name: "n", Type: "int", Required: false, Description: "The number of entries GET returns, 10 by default"},
+	}, ensureSlowlog, execSlowlog)
+}
+
+// defaultSlowlogEntries is the number of entries SLOWLOG GET returns without a count
+const defaultSlowlogEntries = 10
+
+type SlowlogArgs struct {
+	action string
+	count  int
+}
+
+const slowlogUsage = "usage: SLOWLOG GET [n] | SLOWLOG LEN | SLOWLOG RESET"
+
+func ensureSlowlog(dm *dbmanager.DBManager, cmd *common.Command) (*SlowlogArgs, error) {
+	if len(cmd.Args) == 0 {
+		return nil, errors.New(slowlogUsage)
+	}
+
+	slowlogArgs := &SlowlogArgs{action: strings.ToUpper(cmd.Args[0]), count: defaultSlowlogEntries}
+	switch {
+	case (slowlogArgs.action == "LEN" || slowlogArgs.action == "RESET") && len(cmd.Args) == 1:
+		return slowlogArgs, nil
+	case slowlogArgs.action == "GET" && len(cmd.Args) <= 2:
+		if len(cmd.Args) == 2 {
+			count, err := strconv.Atoi(cmd.Args[1])
+			if err != nil || count < 0 {
+				return nil, errors.New("n must be a non-negative number")
+			}
+			slowlogArgs.count = count
+		}
+		return slowlogArgs, nil
+	}
+	return nil, errors.New(slowlogUsage)
+}
+
+// execSlowlog reads and resets the slow log. Without a slow log, i.e. a threshold of 0, it is always empty.
+func execSlowlog(dm *dbmanager.DBManager, slowlogArgs *SlowlogArgs, ctx *CommandContext) ([]byte, error) {
+	switch slowlogArgs.action {
+	case "GET":
+		if dm.SlowLog == nil {
+			return []byte("[]"), nil
+		}
+		return json.Marshal(dm.SlowLog.Get(slowlogArgs.count))
+
+	case "LEN":
+		if dm.SlowLog == nil {
+			return []byte("0"), nil
+		}
+		return []byte(strconv.Itoa(dm.SlowLog.Len())), nil
+
+	default:
+		if dm.SlowLog != nil {
+			dm.SlowLog.Reset()
+		}
+		return []byte("OK"), nil
+	}
+}
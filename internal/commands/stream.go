@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"net"
+)
+
+// resultStream writes a result set to the client one row at a time instead of building the response in memory.
+// Every row is a JSON object {"key":...,"value":...} on its own line. The stream ends with the line
+// "END <rows>" or "END <rows> <cursor>" if there are more results. If the command fails while streaming,
+// the usual "error: ..." line ends the stream instead.
+type resultStream struct {
+	writer *bufio.Writer
+	rows   int
+}
+
+func newResultStream(conn *net.Conn) (*resultStream, error) {
+	if conn == nil {
+		return nil, errors.New("streaming requires a client connection")
+	}
+	return &resultStream{writer: bufio.NewWriter(*conn)}, nil
+}
+
+func (s *resultStream) WriteRow(key string, value *common.V) error {
+	rowBytes, err := json.Marshal(pageRow{Key: key, Value: value.Native()})
+	if err != nil {
+		return err
+	}
+	rowBytes = append(rowBytes, '\n')
+	if _, err := s.writer.Write(rowBytes); err != nil {
+		return err
+	}
+	s.rows++
+
+	// Send the first row right away so clients can start processing, later rows go out whenever the buffer is full
+	if s.rows == 1 {
+		return s.writer.Flush()
+	}
+	return nil
+}
+
+func (s *resultStream) End(cursor string) error {
+	endMarker := fmt.Sprintf("END %d", s.rows)
+	if cursor != "" {
+		endMarker += " " + cursor
+	}
+	if _, err := s.writer.WriteString(endMarker + "\n"); err != nil {
+		return err
+	}
+	return s.writer.Flush()
+}
+
+// streamRead streams the pairs of the iterator to the client applying the cursor, OFFSET and LIMIT.
+// Inside a transaction every streamed value is recorded like any other read, a single operation is cleared at the end.
+// It returns a nil result because the response has already been written.
+func streamRead(dm *dbmanager.DBManager, transactionId uint32, isPartOfExistingTransaction bool, iterator common.KVIterator, isolationLevel string, opts *pageOptions, snapshotGsn uint32, ctx *CommandContext) ([]byte, error) {
+	err := writeStream(dm, transactionId, isPartOfExistingTransaction, iterator, isolationLevel, opts, snapshotGsn, ctx)
+	if err != nil || !isPartOfExistingTransaction {
+		dm.TransactionManager.ClearTransactionStore(transactionId)
+	}
+	return nil, err
+}
+
+func writeStream(dm *dbmanager.DBManager, transactionId uint32, isPartOfExistingTransaction bool, iterator common.KVIterator, isolationLevel string, opts *pageOptions, snapshotGsn uint32, ctx *CommandContext) error {
+	stream, err := newResultStream(ctx.clientConnection)
+	if err != nil {
+		return err
+	}
+	defer func() { ctx.trace.rowsReturned = stream.rows }()
+
+	cursor, err := walkPage(iterator, opts, snapshotGsn, func(key string, value *common.V) error {
+		if isPartOfExistingTransaction {
+			err := addReadValuesToTxnStoreByAcquiringLocks(dm, transactionId, map[string]*common.V{key: value}, isolationLevel, ctx)
+			if err != nil {
+				return err
+			}
+		}
+		return stream.WriteRow(key, value)
+	})
+	if err != nil {
+		return err
+	}
+	return stream.End(cursor)
+}
+
+// iteratorOptions narrows the iteration to the keys after the cursor
+func (opts *pageOptions) iteratorOptions(startKey, endKey string) common.IteratorOptions {
+	iteratorOptions := common.IteratorOptions{StartKey: startKey, EndKey: endKey, Descending: opts.descending}
+	if opts.cursor != nil {
+		if !opts.descending && opts.cursor.LastKey > iteratorOptions.StartKey {
+			iteratorOptions.StartKey = opts.cursor.LastKey
+		}
+		if opts.descending && (iteratorOptions.EndKey == "" || opts.cursor.LastKey < iteratorOptions.EndKey) {
+			iteratorOptions.EndKey = opts.cursor.LastKey
+		}
+	}
+	return iteratorOptions
+}
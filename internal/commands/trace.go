@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"meteor/internal/slowlog"
+	"time"
+)
+
+// commandTrace records what a command did while it executed, for the slow log
+type commandTrace struct {
+	transactionId  uint32
+	isolationLevel string
+	// rowsExamined counts the records read from the stores, rowsReturned the ones in the reply
+	rowsExamined int
+	rowsReturned int
+	// lockWait is the time spent acquiring locks
+	lockWait time.Duration
+}
+
+// isolationLevel returns the isolation level of a transaction, see TransactionManager.GetIsolationLevel, and
+// traces the command as part of the transaction
+func (ctx *CommandContext) isolationLevel(dm *dbmanager.DBManager, transactionId uint32) (string, error) {
+	isolationLevel, err := dm.TransactionManager.GetIsolationLevel(transactionId)
+	if err != nil {
+		return "", err
+	}
+	ctx.trace.transactionId = transactionId
+	ctx.trace.isolationLevel = isolationLevel
+	return isolationLevel, nil
+}
+
+// traceTransaction traces the command as part of a transaction without starting one, e.g. for COMMIT
+func (ctx *CommandContext) traceTransaction(dm *dbmanager.DBManager, transactionId uint32) {
+	ctx.trace.transactionId = transactionId
+	ctx.trace.isolationLevel, _ = dm.TransactionManager.LookupIsolationLevel(transactionId)
+}
+
+// waitForLock runs acquire and adds the time it took to the lock wait of the command
+func (ctx *CommandContext) waitForLock(acquire func() error) error {
+	t0 := time.Now()
+	err := acquire()
+	ctx.trace.lockWait += time.Since(t0)
+	return err
+}
+
+// examine counts every record the filter is applied to as examined
+func (ctx *CommandContext) examine(filter func(string, *common.V) bool) func(string, *common.V) bool {
+	return func(key string, value *common.V) bool {
+		ctx.trace.rowsExamined++
+		return filter(key, value)
+	}
+}
+
+// recordSlowCommand adds a command that took at least the threshold of the slow log to it
+func recordSlowCommand(dm *dbmanager.DBManager, name string, cmd *common.Command, startedAt time.Time, duration time.Duration, trace *commandTrace, err error) {
+	entry := slowlog.Entry{
+		Time:           startedAt,
+		Command:        name,
+		Args:           redactedArgs(name, cmd.Args),
+		DurationMicros: duration.Microseconds(),
+		LockWaitMicros: trace.lockWait.Microseconds(),
+		TransactionId:  trace.transactionId,
+		IsolationLevel: trace.isolationLevel,
+		RowsExamined:   trace.rowsExamined,
+		RowsReturned:   trace.rowsReturned,
+	}
+	if cmd.Connection != nil {
+		if client, ok := dm.ClientManager.Client(cmd.Connection); ok {
+			entry.Client = client.RemoteAddr
+		}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	dm.SlowLog.Add(entry)
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"slices"
+	"strings"
+)
+
+func init() {
+	Register("USER", authmanager.CategoryAdmin, nil, []ArgSpec{
+		{Name: "action", Type: "string", Required: true, Description: "ADD <name> <password>, DEL <name>, PASSWORD <name> <password> or LIST"},
+		{Name: "name", Type: "string", Required: false, Description: "The name of the user"},
+		{Name: "password", Type: "string", Required: false, Description: "The password of the user, only a salted hash is stored"},
+	}, ensureUser, execUser)
+}
+
+type UserArgs struct {
+	action string
+	name   string
+	// user holds the new password hash of ADD and PASSWORD
+	user *authmanager.User
+}
+
+// userInfo is a user as listed by USER LIST, without the password hash
+type userInfo struct {
+	Name   string              `json:"name"`
+	Grants []authmanager.Grant `json:"grants"`
+}
+
+const userUsage = "usage: USER ADD <name> <password> | USER DEL <name> | USER PASSWORD <name> <password> | USER LIST"
+
+func ensureUser(dm *dbmanager.DBManager, cmd *common.Command) (*UserArgs, error) {
+	if len(cmd.Args) == 0 {
+		return nil, errors.New(userUsage)
+	}
+
+	userArgs := &UserArgs{action: strings.ToUpper(cmd.Args[0])}
+	switch {
+	case userArgs.action == "LIST" && len(cmd.Args) == 1:
+		return userArgs, nil
+	case userArgs.action == "DEL" && len(cmd.Args) == 2:
+		userArgs.name = cmd.Args[1]
+		return userArgs, nil
+	case (userArgs.action == "ADD" || userArgs.action == "PASSWORD") && len(cmd.Args) == 3:
+		user, err := authmanager.NewUser(cmd.Args[1], cmd.Args[2], nil)
+		if err != nil {
+			return nil, err
+		}
+		userArgs.name = user.Name
+		userArgs.user = user
+		return userArgs, nil
+	}
+	return nil, errors.New(userUsage)
+}
+
+// execUser manages the users of the system keyspace. The first user added is granted every category on all
+// keys, since adding it turns on access control; later users start without grants.
+func execUser(dm *dbmanager.DBManager, userArgs *UserArgs, ctx *CommandContext) ([]byte, error) {
+	switch userArgs.action {
+	case "LIST":
+		users := dm.AuthManager.Users()
+		infos := make([]userInfo, 0, len(users))
+		for _, user := range users {
+			infos = append(infos, userInfo{Name: user.Name, Grants: user.Grants})
+		}
+		return json.Marshal(infos)
+
+	case "ADD":
+		return updateUser(dm, userArgs.name, func(user *authmanager.User, userCount int) (*authmanager.User, string, error) {
+			if user != nil {
+				return nil, "", fmt.Errorf("user already exists: %s", userArgs.name)
+			}
+			user = userArgs.user
+			if userCount == 0 {
+				user.Grants = []authmanager.Grant{{Categories: slices.Clone(authmanager.Categories), Prefix: ""}}
+			}
+			return user, common.DB_OP_PUT_USER, nil
+		})
+
+	case "PASSWORD":
+		return updateUser(dm, userArgs.name, func(user *authmanager.User, userCount int) (*authmanager.User, string, error) {
+			if user == nil {
+				return nil, "", fmt.Errorf("user not found: %s", userArgs.name)
+			}
+			user.Salt, user.Hash, user.Iterations = userArgs.user.Salt, userArgs.user.Hash, userArgs.user.Iterations
+			return user, common.DB_OP_PUT_USER, nil
+		})
+
+	default:
+		return updateUser(dm, userArgs.name, func(user *authmanager.User, userCount int) (*authmanager.User, string, error) {
+			if user == nil {
+				return nil, "", fmt.Errorf("user not found: %s", userArgs.name)
+			}
+			// Deleting the current user would lock out its connection halfway through
+			if user.Name == ctx.user {
+				return nil, "", errors.New("can't delete the authenticated user")
+			}
+			return user, common.DB_OP_DELETE_USER, nil
+		})
+	}
+}
+
+// updateUser changes a user under the lock of the AuthManager. change receives a copy of the user, nil if it
+// doesn't exist, and the number of users, and returns the user with the operation that stores it. The change is
+// logged to the WAL, so it is restored on recovery, and applied. Users are changed outside of transactions.
+func updateUser(dm *dbmanager.DBManager, name string, change func(user *authmanager.User, userCount int) (*authmanager.User, string, error)) ([]byte, error) {
+	err := dm.AuthManager.UpdateUser(name, func(user *authmanager.User, userCount int) (*common.TransactionRow, error) {
+		user, operation, err := change(user, userCount)
+		if err != nil {
+			return nil, err
+		}
+
+		gsn := dm.GsnManager.GetNewGsn()
+		transactionRow, err := user.ToTransactionRow(dm.TransactionManager.GetNewTransactionId(), operation, gsn)
+		if err != nil {
+			return nil, err
+		}
+		return transactionRow, dm.AddTransactionToWal(transactionRow)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte("OK"), nil
+}
@@ -1,11 +1,61 @@
 package commands
 
 import (
+	"errors"
 	"meteor/internal/common"
 	"meteor/internal/dbmanager"
 	"net"
+	"slices"
+	"strconv"
+	"strings"
 )
 
+// parseOptionalTransactionId parses the optional trailing transactionId argument (zero or one element).
+// If it is absent, a new transaction id is allocated so the command runs as a single operation.
+func parseOptionalTransactionId(dm *dbmanager.DBManager, args []string) (uint32, bool, error) {
+	if len(args) == 0 {
+		return dm.TransactionManager.GetNewTransactionId(), false, nil
+	}
+
+	transactionId64Bits, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return 0, false, errors.New("invalid transactionId")
+	}
+	transactionId := uint32(transactionId64Bits)
+
+	// if new transaction, client not allowed to specify transactionId
+	// it will be assigned by the server
+	if dm.TransactionManager.IsNewTransactionId(transactionId) {
+		return 0, false, errors.New("transactionId not allowed")
+	}
+
+	return transactionId, true, nil
+}
+
+// splitKeysAndTransactionId splits the arguments of variadic key commands into keys and the optional transactionId,
+// which is passed last after common.TransactionMarker, e.g. MGET a b TXN 12
+func splitKeysAndTransactionId(dm *dbmanager.DBManager, args []string) ([]string, uint32, bool, error) {
+	keys, transactionIdArgs := trimTransactionId(args)
+	transactionId, isPartOfExistingTransaction, err := parseOptionalTransactionId(dm, transactionIdArgs)
+	return keys, transactionId, isPartOfExistingTransaction, err
+}
+
+// trimTransactionId splits the trailing common.TransactionMarker and transactionId off the arguments of variadic
+// key commands. At least one key has to precede them, so MGET TXN 12 gets the keys TXN and 12.
+func trimTransactionId(args []string) ([]string, []string) {
+	if len(args) > 2 && strings.EqualFold(args[len(args)-2], common.TransactionMarker) {
+		return args[:len(args)-2], args[len(args)-1:]
+	}
+	return args, nil
+}
+
+// uniqueSortedKeys returns the distinct keys in sorted order, the order in which multi-key commands acquire locks
+func uniqueSortedKeys(keys []string) []string {
+	sortedKeys := slices.Clone(keys)
+	slices.Sort(sortedKeys)
+	return slices.Compact(sortedKeys)
+}
+
 // addReadValueToTxnStore adds the key value pair to transaction store so future reads return the same value
 func addReadValueToTxnStore(dm *dbmanager.DBManager, transactionId uint32, key string, value *common.V, isolationLevel string, conn *net.Conn) error {
 	// Store read value in transaction store for REPEATABLE_READ and SNAPSHOT_ISOLATION
@@ -33,19 +83,140 @@ func addReadValueToTxnStore(dm *dbmanager.DBManager, transactionId uint32, key s
 }
 
 // addReadValuesToTxnStoreByAcquiringLocks adds multiple read key value pairs to transaction store by acquiring read lock for each key based for repeatable read isolation
-func addReadValuesToTxnStoreByAcquiringLocks(dm *dbmanager.DBManager, transactionId uint32, results map[string]*common.V, isolationLevel string, conn *net.Conn) error {
+func addReadValuesToTxnStoreByAcquiringLocks(dm *dbmanager.DBManager, transactionId uint32, results map[string]*common.V, isolationLevel string, ctx *CommandContext) error {
 	for key, value := range results {
 		if isolationLevel == common.TXN_ISOLATION_REPEATABLE_READ {
-			err := dm.TransactionManager.AcquireReadLock(transactionId, key, isolationLevel)
+			err := ctx.waitForLock(func() error {
+				return dm.TransactionManager.AcquireReadLock(transactionId, key, isolationLevel)
+			})
 			if err != nil {
 				return err
 			}
 		}
 
-		err := addReadValueToTxnStore(dm, transactionId, key, value, isolationLevel, conn)
+		err := addReadValueToTxnStore(dm, transactionId, key, value, isolationLevel, ctx.clientConnection)
 		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// keyWrite describes the write of a single key. computeValue derives the new value from the current one.
+type keyWrite struct {
+	operation    string
+	key          string
+	computeValue func(oldValue *common.V) (*common.V, error)
+}
+
+// writeValue runs the write path for a single key, see writeValues
+func writeValue(dm *dbmanager.DBManager, transactionId uint32, isPartOfExistingTransaction bool, operation string, key string, ctx *CommandContext, computeValue func(oldValue *common.V) (*common.V, error)) (*common.V, error) {
+	newValues, err := writeValues(dm, transactionId, isPartOfExistingTransaction, []keyWrite{{operation: operation, key: key, computeValue: computeValue}}, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newValues[key], nil
+}
+
+// writeValues runs the write path shared by key writes: it acquires the write locks, derives the new values
+// from the current ones, validates the writes and records them in the transaction store and WAL.
+// Locks are acquired in sorted key order so concurrent multi-key writes can't deadlock each other.
+// Single operations are applied to the buffer store and release their locks immediately; a single operation
+// with multiple keys is logged as queued rows followed by one commit row so recovery applies all or none of them.
+// Writes that are part of an existing transaction stay queued until commit. The transaction is cleared on any error.
+func writeValues(dm *dbmanager.DBManager, transactionId uint32, isPartOfExistingTransaction bool, writes []keyWrite, ctx *CommandContext) (map[string]*common.V, error) {
+	isolationLevel, err := ctx.isolationLevel(dm, transactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	writes = slices.Clone(writes)
+	slices.SortStableFunc(writes, func(a, b keyWrite) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	// The write locks are held while the new values are computed, so read-modify-write is atomic
+	for _, write := range writes {
+		err = ctx.waitForLock(func() error {
+			return dm.TransactionManager.AcquireWriteLock(transactionId, write.key, isolationLevel)
+		})
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+	}
+
+	// Rows of a multi-key single operation are queued and committed together below
+	transactionState := common.TRANSACTION_STATE_COMMIT
+	if isPartOfExistingTransaction || len(writes) > 1 {
+		transactionState = common.TRANSACTION_STATE_QUEUED
+	}
+
+	newValues := make(map[string]*common.V, len(writes))
+	transactionRows := make([]*common.TransactionRow, 0, len(writes))
+
+	for _, write := range writes {
+		oldValue, err := dm.TransactionManager.ReadValue(transactionId, write.key, dm.StoreManager.BufferStore, ctx.clientConnection)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+
+		newValue, err := write.computeValue(oldValue)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+
+		err = dm.TransactionManager.ValidateWrite(transactionId, write.key, dm.StoreManager.BufferStore, ctx.clientConnection)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+
+		keyObj := &common.K{Key: write.key, Gsn: dm.GsnManager.GetNewGsn()}
+		transactionRow := common.NewTransactionRow(transactionId, write.operation, transactionState, keyObj, oldValue, newValue)
+
+		err = dm.TransactionManager.AddTransaction(transactionRow, ctx.clientConnection)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+
+		err = dm.AddTransactionToWal(transactionRow)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+
+		newValues[write.key] = newValue
+		transactionRows = append(transactionRows, transactionRow)
+	}
+
+	if isPartOfExistingTransaction {
+		return newValues, nil
+	}
+
+	if len(writes) > 1 {
+		commitKey := &common.K{Key: common.TypeKeyNull, Gsn: dm.GsnManager.GetNewGsn()}
+		commitRow := common.NewTransactionRow(transactionId, common.DB_OP_COMMIT, common.TRANSACTION_STATE_COMMIT, commitKey, nil, nil)
+		err = dm.AddTransactionToWal(commitRow)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+	}
+
+	for _, transactionRow := range transactionRows {
+		err = dm.StoreManager.PutTxnRowToBufferStore(transactionRow)
+		if err != nil {
+			dm.TransactionManager.ClearTransactionStore(transactionId)
+			return nil, err
+		}
+	}
+
+	// Release locks immediately for non-transactional operations
+	dm.TransactionManager.ClearTransactionStore(transactionId)
+
+	return newValues, nil
+}
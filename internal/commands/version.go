@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"errors"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"strconv"
+)
+
+func init() {
+	Register("VERSION", authmanager.CategoryRead, firstKey, []ArgSpec{
+		{Name: "key", Type: "string", Required: true, Description: "The key to get the latest version (gsn) of"},
+		{Name: "transactionId", Type: "uint32", Required: false, Description: "Accepted for compatibility, the version is always read from committed data"},
+	}, ensureVersion, execVersion)
+}
+
+type VersionArgs struct {
+	key string
+}
+
+func ensureVersion(dm *dbmanager.DBManager, cmd *common.Command) (*VersionArgs, error) {
+	argLen := len(cmd.Args)
+
+	if argLen < 1 {
+		return nil, errors.New("command must have at least one argument - key")
+	}
+
+	if argLen > 2 {
+		return nil, errors.New("command must have at most 2 arguments - key, transactionId")
+	}
+
+	return &VersionArgs{key: cmd.Args[0]}, nil
+}
+
+// execVersion returns the gsn of the latest committed version of the key, to be used with PUT ... IF_VERSION.
+// A key which was never written has version 0.
+func execVersion(dm *dbmanager.DBManager, versionArgs *VersionArgs, ctx *CommandContext) ([]byte, error) {
+	gsn, err := dm.StoreManager.BufferStore.GetLatestGsn(versionArgs.key)
+	if err != nil {
+		gsn = 0
+	}
+
+	return []byte(strconv.FormatUint(uint64(gsn), 10)), nil
+}
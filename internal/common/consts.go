@@ -14,6 +14,12 @@ const (
 const (
 	DB_OP_PUT = "PUT"
 	DB_OP_DELETE = "DELETE"
+	DB_OP_DELETE_RANGE = "DELETE_RANGE"
+	DB_OP_DELETE_PREFIX = "DELETE_PREFIX"
+	DB_OP_CREATE_INDEX = "CREATE_INDEX"
+	DB_OP_DROP_INDEX = "DROP_INDEX"
+	DB_OP_PUT_USER = "PUT_USER"
+	DB_OP_DELETE_USER = "DELETE_USER"
 	DB_OP_GET = "GET"
 	DB_OP_BEGIN = "BEGIN"
 	DB_OP_COMMIT = "COMMIT"
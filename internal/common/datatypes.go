@@ -1,5 +1,10 @@
 package common
 
+import (
+	"fmt"
+	"strings"
+)
+
 type DataType uint8
 
 const TypeKeyNull string = "KeyNull"
@@ -21,6 +26,7 @@ const (
 	TypeBytes
 	TypeString
 	TypeTombstone
+	TypeJson
 )
 
 func (dt DataType) String() string {
@@ -53,6 +59,8 @@ func (dt DataType) String() string {
 		return "Bytes"
 	case TypeString:
 		return "String"
+	case TypeJson:
+		return "Json"
 	default:
 		return "Unknown"
 	}
@@ -68,9 +76,65 @@ func (dt DataType) Size() int {
 		return 4
 	case TypeUint64, TypeInt64, TypeFloat64:
 		return 8
-	case TypeBytes, TypeString:
+	case TypeBytes, TypeString, TypeJson:
 		return 0
 	default:
 		return 0
 	}
 }
+
+// ParseDataType returns the DataType for a user facing type name such as int64 or string (case insensitive)
+func ParseDataType(name string) (DataType, error) {
+	switch strings.ToLower(name) {
+	case "uint8":
+		return TypeUint8, nil
+	case "uint16":
+		return TypeUint16, nil
+	case "uint32":
+		return TypeUint32, nil
+	case "uint64":
+		return TypeUint64, nil
+	case "int8":
+		return TypeInt8, nil
+	case "int16":
+		return TypeInt16, nil
+	case "int32":
+		return TypeInt32, nil
+	case "int64":
+		return TypeInt64, nil
+	case "bool":
+		return TypeBool, nil
+	case "float32":
+		return TypeFloat32, nil
+	case "float64":
+		return TypeFloat64, nil
+	case "bytes":
+		return TypeBytes, nil
+	case "string":
+		return TypeString, nil
+	case "json":
+		return TypeJson, nil
+	default:
+		return TypeNull, fmt.Errorf("unknown type %q. Valid types are: int8, int16, int32, int64, uint8, uint16, uint32, uint64, float32, float64, bool, bytes, string, json", name)
+	}
+}
+
+// IsInteger returns true for signed and unsigned integer types
+func (dt DataType) IsInteger() bool {
+	switch dt {
+	case TypeUint8, TypeUint16, TypeUint32, TypeUint64, TypeInt8, TypeInt16, TypeInt32, TypeInt64:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsFloat returns true for floating point types
+func (dt DataType) IsFloat() bool {
+	return dt == TypeFloat32 || dt == TypeFloat64
+}
+
+// IsNumeric returns true for integer and floating point types
+func (dt DataType) IsNumeric() bool {
+	return dt.IsInteger() || dt.IsFloat()
+}
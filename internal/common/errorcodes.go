@@ -0,0 +1,99 @@
+package common
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrorCode classifies command errors for protocols and clients that report errors in structured form
+type ErrorCode uint16
+
+const (
+	// ErrorCodeGeneric is any error without a more specific code
+	ErrorCodeGeneric ErrorCode = iota + 1
+	// ErrorCodeUnknownCommand is returned for operations that aren't registered
+	ErrorCodeUnknownCommand
+	// ErrorCodeInvalidArgument is returned when a command rejects its arguments before running
+	ErrorCodeInvalidArgument
+	// ErrorCodeTransaction is returned for unknown transactions or transactions of another connection
+	ErrorCodeTransaction
+	// ErrorCodeConflict is returned when another transaction committed a write to the same key first
+	ErrorCodeConflict
+	// ErrorCodeDeadlock is returned when acquiring a lock would deadlock
+	ErrorCodeDeadlock
+	// ErrorCodeLockTimeout is returned when a lock isn't acquired in time
+	ErrorCodeLockTimeout
+	// ErrorCodeConditionNotMet is returned by conditional writes whose condition doesn't hold
+	ErrorCodeConditionNotMet
+	// ErrorCodeProtocol is returned for malformed requests
+	ErrorCodeProtocol
+	// ErrorCodeAuthentication is returned for wrong credentials and commands run before authenticating
+	ErrorCodeAuthentication
+	// ErrorCodePermission is returned when the user isn't granted the command on its keys
+	ErrorCodePermission
+)
+
+var errorCodeNames = map[ErrorCode]string{
+	ErrorCodeGeneric:         "ERROR",
+	ErrorCodeUnknownCommand:  "UNKNOWN_COMMAND",
+	ErrorCodeInvalidArgument: "INVALID_ARGUMENT",
+	ErrorCodeTransaction:     "TRANSACTION",
+	ErrorCodeConflict:        "CONFLICT",
+	ErrorCodeDeadlock:        "DEADLOCK",
+	ErrorCodeLockTimeout:     "LOCK_TIMEOUT",
+	ErrorCodeConditionNotMet: "CONDITION_NOT_MET",
+	ErrorCodeProtocol:        "PROTOCOL",
+	ErrorCodeAuthentication:  "AUTHENTICATION",
+	ErrorCodePermission:      "PERMISSION_DENIED",
+}
+
+func (c ErrorCode) String() string {
+	if name, ok := errorCodeNames[c]; ok {
+		return name
+	}
+	return "ERROR"
+}
+
+// InvalidArgumentError wraps the errors of commands rejecting their arguments. The message is unchanged.
+type InvalidArgumentError struct {
+	Err error
+}
+
+func (e *InvalidArgumentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidArgumentError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError returns the code of a command error. Errors are plain messages, so the well known
+// messages of the transaction and lock managers are matched.
+func ClassifyError(err error) ErrorCode {
+	message := err.Error()
+	switch {
+	case strings.HasPrefix(message, "unknown operation"):
+		return ErrorCodeUnknownCommand
+	case strings.Contains(message, "write-write conflict"):
+		return ErrorCodeConflict
+	case strings.Contains(message, "deadlock detected"):
+		return ErrorCodeDeadlock
+	case strings.Contains(message, "lock acquisition timeout"):
+		return ErrorCodeLockTimeout
+	case message == "authentication required" || message == "invalid username or password":
+		return ErrorCodeAuthentication
+	case strings.HasPrefix(message, "permission denied"):
+		return ErrorCodePermission
+	case strings.HasPrefix(message, "condition not met"):
+		return ErrorCodeConditionNotMet
+	case message == "transaction not found" || message == "invalid transactionId" ||
+		message == "transactionId not allowed" || message == "transaction id not allowed for connection":
+		return ErrorCodeTransaction
+	}
+
+	var invalidArgumentError *InvalidArgumentError
+	if errors.As(err, &invalidArgumentError) {
+		return ErrorCodeInvalidArgument
+	}
+	return ErrorCodeGeneric
+}
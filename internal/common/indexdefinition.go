@@ -0,0 +1,91 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// IndexDefinition describes a secondary index over the values of the keys with a prefix.
+// Field is $value or a part of a JSON value such as $value.email.
+type IndexDefinition struct {
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+	Field  string `json:"field"`
+
+	path JsonPath
+}
+
+// IndexCondition is a condition on an indexed field used to look up candidate keys. Operator is one of
+// =, <, <=, > and >=. Equality matches any of Values, the comparisons use Values[0].
+type IndexCondition struct {
+	Operator string
+	Values   []string
+}
+
+// NewIndexDefinition validates an index definition. The field must refer to the value or a JSON path in it.
+func NewIndexDefinition(name, prefix, field string) (*IndexDefinition, error) {
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return nil, fmt.Errorf("invalid index name %q", name)
+	}
+
+	definition := &IndexDefinition{Name: name, Prefix: prefix, Field: field}
+	path, ok := strings.CutPrefix(field, "$value")
+	if !ok {
+		return nil, fmt.Errorf("invalid index field %s, expected $value or $value.<json path>", field)
+	}
+	if path != "" {
+		parsed, err := ParseJsonPath("$" + path)
+		if err != nil || (path[0] != '.' && path[0] != '[') {
+			return nil, fmt.Errorf("invalid index field %s, expected $value or $value.<json path>", field)
+		}
+		definition.path = parsed
+	}
+	return definition, nil
+}
+
+// Covers returns true if the key is indexed
+func (d *IndexDefinition) Covers(key string) bool {
+	return strings.HasPrefix(key, d.Prefix)
+}
+
+// FieldValue returns the indexed part of a value, or nil if the value is deleted or the field is missing or null
+func (d *IndexDefinition) FieldValue(value *V) *V {
+	if value == nil || value.Type == TypeTombstone {
+		return nil
+	}
+	if d.path != nil {
+		value = value.JsonPathValue(d.path)
+	}
+	if value == nil || value.Type == TypeNull {
+		return nil
+	}
+	return value
+}
+
+// ToTransactionRow encodes the creation or removal of the index as a transaction row. The key holds the index
+// name and the new value the definition as JSON, so indexes are recreated when the WAL is replayed.
+func (d *IndexDefinition) ToTransactionRow(transactionId uint32, operation string, gsn uint32) (*TransactionRow, error) {
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return NewTransactionRow(transactionId, operation, TRANSACTION_STATE_COMMIT, &K{Key: d.Name, Gsn: gsn}, nil, &V{Type: TypeJson, Value: encoded}), nil
+}
+
+// IndexDefinitionFromTransactionRow decodes a CREATE_INDEX or DROP_INDEX row
+func IndexDefinitionFromTransactionRow(row *TransactionRow) (*IndexDefinition, error) {
+	if row.Operation != DB_OP_CREATE_INDEX && row.Operation != DB_OP_DROP_INDEX {
+		return nil, errors.New("not an index operation: " + row.Operation)
+	}
+	if row.Payload.NewValue == nil {
+		return nil, errors.New("index operation without definition")
+	}
+
+	var encoded IndexDefinition
+	if err := json.Unmarshal(row.Payload.NewValue.Value, &encoded); err != nil {
+		return nil, err
+	}
+	return NewIndexDefinition(encoded.Name, encoded.Prefix, encoded.Field)
+}
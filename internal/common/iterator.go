@@ -0,0 +1,139 @@
+package common
+
+import "slices"
+
+// KVIterator walks key value pairs in key order. Values are read as the iterator advances,
+// so the memory used by an iteration doesn't depend on the size of the values.
+type KVIterator interface {
+	// Next advances the iterator and reports whether there is a pair to read
+	Next() bool
+	Key() string
+	Value() *V
+}
+
+// IteratorOptions bounds an iteration. Both keys are inclusive, an empty EndKey means no upper bound.
+// A MaxGsn of 0 reads the latest versions, otherwise the versions at or before MaxGsn.
+type IteratorOptions struct {
+	StartKey   string
+	EndKey     string
+	MaxGsn     uint32
+	Descending bool
+}
+
+// InBounds reports whether the key is within the iteration bounds
+func (o IteratorOptions) InBounds(key string) bool {
+	return key >= o.StartKey && (o.EndKey == "" || key <= o.EndKey)
+}
+
+type mergeIterator struct {
+	iterators  []KVIterator
+	hasCurrent []bool
+	started    bool
+	descending bool
+	key        string
+	value      *V
+}
+
+// NewMergeIterator merges iterators with the same order into one. If several iterators hold the same key,
+// the value of the first one wins, so iterators are passed in order of precedence.
+func NewMergeIterator(descending bool, iterators ...KVIterator) KVIterator {
+	return &mergeIterator{
+		iterators:  iterators,
+		hasCurrent: make([]bool, len(iterators)),
+		descending: descending,
+	}
+}
+
+func (it *mergeIterator) Next() bool {
+	// Advance every iterator positioned at the key returned last
+	for i, iterator := range it.iterators {
+		if !it.started || (it.hasCurrent[i] && iterator.Key() == it.key) {
+			it.hasCurrent[i] = iterator.Next()
+		}
+	}
+	it.started = true
+
+	found := false
+	for i, iterator := range it.iterators {
+		if !it.hasCurrent[i] {
+			continue
+		}
+		key := iterator.Key()
+		if !found || (!it.descending && key < it.key) || (it.descending && key > it.key) {
+			it.key = key
+			it.value = iterator.Value()
+			found = true
+		}
+	}
+	return found
+}
+
+func (it *mergeIterator) Key() string {
+	return it.key
+}
+
+func (it *mergeIterator) Value() *V {
+	return it.value
+}
+
+type filterIterator struct {
+	iterator   KVIterator
+	filterFunc func(string, *V) bool
+}
+
+// NewFilterIterator returns the pairs of the iterator that match the filter function
+func NewFilterIterator(iterator KVIterator, filterFunc func(string, *V) bool) KVIterator {
+	return &filterIterator{
+		iterator:   iterator,
+		filterFunc: filterFunc,
+	}
+}
+
+func (it *filterIterator) Next() bool {
+	for it.iterator.Next() {
+		if it.filterFunc(it.iterator.Key(), it.iterator.Value()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *filterIterator) Key() string {
+	return it.iterator.Key()
+}
+
+func (it *filterIterator) Value() *V {
+	return it.iterator.Value()
+}
+
+type mapIterator struct {
+	keys   []string
+	values map[string]*V
+	index  int
+}
+
+// NewMapIterator iterates the pairs of a map in key order, used for results that are read all at once
+func NewMapIterator(values map[string]*V, descending bool) KVIterator {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	if descending {
+		slices.Reverse(keys)
+	}
+	return &mapIterator{keys: keys, values: values, index: -1}
+}
+
+func (it *mapIterator) Next() bool {
+	it.index++
+	return it.index < len(it.keys)
+}
+
+func (it *mapIterator) Key() string {
+	return it.keys[it.index]
+}
+
+func (it *mapIterator) Value() *V {
+	return it.values[it.keys[it.index]]
+}
@@ -0,0 +1,262 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JsonPath is a parsed path into a JSON document such as $.user.tags[0].
+// Every segment is either an object member name (string) or an array index (int).
+type JsonPath []any
+
+// ParseJsonPath parses a path starting at the document root $. Members are selected with .name or ['name']
+// and array elements with [index]. The path $ selects the whole document.
+func ParseJsonPath(path string) (JsonPath, error) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return nil, fmt.Errorf("invalid json path %q, paths must start with $", path)
+	}
+
+	parsed := JsonPath{}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return nil, fmt.Errorf("invalid json path %q, empty member name", path)
+			}
+			parsed = append(parsed, name)
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid json path %q, missing ]", path)
+			}
+			selector := rest[1:end]
+			if len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0] {
+				parsed = append(parsed, selector[1:len(selector)-1])
+			} else {
+				index, err := strconv.Atoi(selector)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid json path %q, array index must be a non-negative integer", path)
+				}
+				parsed = append(parsed, index)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid json path %q, unexpected %q", path, rest[0])
+		}
+	}
+	return parsed, nil
+}
+
+func (p JsonPath) String() string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, segment := range p {
+		switch segment := segment.(type) {
+		case int:
+			sb.WriteString("[" + strconv.Itoa(segment) + "]")
+		case string:
+			sb.WriteString("." + segment)
+		}
+	}
+	return sb.String()
+}
+
+// NewJsonV validates a JSON document and stores it in compact form
+func NewJsonV(input string) (*V, error) {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, []byte(input)); err != nil {
+		return nil, fmt.Errorf("invalid %s value: %v", TypeJson.String(), err)
+	}
+	return &V{Type: TypeJson, Value: compacted.Bytes()}, nil
+}
+
+// decodeJson decodes a document keeping numbers as json.Number so large integers aren't rounded
+func decodeJson(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// JsonPathValue returns the part of a JSON value selected by the path as a typed value: JSON strings,
+// numbers, booleans and null become the matching scalar types, objects and arrays stay JSON.
+// It returns nil if the value isn't JSON or the path doesn't exist.
+func (v *V) JsonPathValue(path JsonPath) *V {
+	if v == nil || v.Type != TypeJson {
+		return nil
+	}
+	document, err := decodeJson(v.Value)
+	if err != nil {
+		return nil
+	}
+
+	node := document
+	for _, segment := range path {
+		switch segment := segment.(type) {
+		case string:
+			object, ok := node.(map[string]any)
+			if !ok {
+				return nil
+			}
+			if node, ok = object[segment]; !ok {
+				return nil
+			}
+		case int:
+			array, ok := node.([]any)
+			if !ok || segment >= len(array) {
+				return nil
+			}
+			node = array[segment]
+		}
+	}
+	return jsonToV(node)
+}
+
+func jsonToV(node any) *V {
+	switch node := node.(type) {
+	case nil:
+		return &V{Type: TypeNull}
+	case bool:
+		return &V{Type: TypeBool, Value: NewBinaryBuffer(1).WriteBool(node).GetBuffer()}
+	case json.Number:
+		if n, err := node.Int64(); err == nil {
+			return NewInt64V(n)
+		}
+		if f, err := node.Float64(); err == nil {
+			return NewFloat64V(f)
+		}
+		return &V{Type: TypeString, Value: []byte(node.String())}
+	case string:
+		return &V{Type: TypeString, Value: []byte(node)}
+	default:
+		encoded, err := json.Marshal(node)
+		if err != nil {
+			return nil
+		}
+		return &V{Type: TypeJson, Value: encoded}
+	}
+}
+
+// JsonElements returns the elements of a JSON array as typed values, or nil if the value isn't an array
+func (v *V) JsonElements() []*V {
+	if v == nil || v.Type != TypeJson {
+		return nil
+	}
+	document, err := decodeJson(v.Value)
+	if err != nil {
+		return nil
+	}
+	array, ok := document.([]any)
+	if !ok {
+		return nil
+	}
+	elements := make([]*V, len(array))
+	for i, element := range array {
+		elements[i] = jsonToV(element)
+	}
+	return elements
+}
+
+// HasJsonMember returns true if the value is a JSON object with the given member
+func (v *V) HasJsonMember(name string) bool {
+	if v == nil || v.Type != TypeJson {
+		return false
+	}
+	document, err := decodeJson(v.Value)
+	if err != nil {
+		return false
+	}
+	object, ok := document.(map[string]any)
+	if !ok {
+		return false
+	}
+	_, ok = object[name]
+	return ok
+}
+
+// SetJsonPath returns a copy of the JSON document with the part selected by the path replaced by newValue.
+// A nil document starts from an empty object. Missing object members are created, and an array index equal
+// to the length of the array appends to it.
+func SetJsonPath(document *V, path JsonPath, newValue *V) (*V, error) {
+	var root any
+	if document != nil {
+		if document.Type != TypeJson {
+			return nil, errors.New("value is not a json document")
+		}
+		var err error
+		if root, err = decodeJson(document.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	var replacement any
+	if newValue != nil {
+		replacement = newValue.Native()
+	}
+
+	updated, err := setJsonNode(root, path, replacement)
+	if err != nil {
+		return nil, fmt.Errorf("cannot set %s: %v", path.String(), err)
+	}
+
+	encoded, err := json.Marshal(updated)
+	if err != nil {
+		return nil, err
+	}
+	return &V{Type: TypeJson, Value: encoded}, nil
+}
+
+func setJsonNode(node any, path JsonPath, replacement any) (any, error) {
+	if len(path) == 0 {
+		return replacement, nil
+	}
+
+	switch segment := path[0].(type) {
+	case string:
+		if node == nil {
+			node = map[string]any{}
+		}
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("member %q of a value that isn't an object", segment)
+		}
+		child, err := setJsonNode(object[segment], path[1:], replacement)
+		if err != nil {
+			return nil, err
+		}
+		object[segment] = child
+		return object, nil
+	case int:
+		array, ok := node.([]any)
+		if !ok {
+			return nil, fmt.Errorf("index %d of a value that isn't an array", segment)
+		}
+		if segment > len(array) {
+			return nil, fmt.Errorf("index %d is out of range for an array of length %d", segment, len(array))
+		}
+		if segment == len(array) {
+			array = append(array, nil)
+		}
+		child, err := setJsonNode(array[segment], path[1:], replacement)
+		if err != nil {
+			return nil, err
+		}
+		array[segment] = child
+		return array, nil
+	}
+	return nil, errors.New("invalid path segment")
+}
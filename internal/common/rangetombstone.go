@@ -0,0 +1,65 @@
+package common
+
+import "strings"
+
+// RangeTombstone marks every key in a lexicographic range, or with a prefix, as deleted at a GSN.
+// Versions of covered keys written before the tombstone's GSN are hidden, later versions are not.
+type RangeTombstone struct {
+	StartKey string
+	EndKey   string
+	Prefix   string
+	IsPrefix bool
+	Gsn      uint32
+}
+
+// NewRangeTombstone creates a tombstone for the inclusive range [startKey, endKey]
+func NewRangeTombstone(startKey, endKey string, gsn uint32) *RangeTombstone {
+	return &RangeTombstone{StartKey: startKey, EndKey: endKey, Gsn: gsn}
+}
+
+// NewPrefixTombstone creates a tombstone for all keys starting with prefix
+func NewPrefixTombstone(prefix string, gsn uint32) *RangeTombstone {
+	return &RangeTombstone{Prefix: prefix, IsPrefix: true, Gsn: gsn}
+}
+
+// Covers returns true if the key is within the tombstone's range or has its prefix
+func (rt *RangeTombstone) Covers(key string) bool {
+	if rt.IsPrefix {
+		return strings.HasPrefix(key, rt.Prefix)
+	}
+	return key >= rt.StartKey && key <= rt.EndKey
+}
+
+// Bounds returns the inclusive lexicographic range covered by the tombstone, used for range locks
+func (rt *RangeTombstone) Bounds() (string, string) {
+	if rt.IsPrefix {
+		// No valid UTF-8 key contains the byte 0xff, so this is an upper bound for all keys with the prefix
+		return rt.Prefix, rt.Prefix + "\xff"
+	}
+	return rt.StartKey, rt.EndKey
+}
+
+// ToTransactionRow encodes the tombstone as a transaction row. The key holds the start key (or prefix)
+// and the new value holds the end key, so a range delete takes a single WAL row.
+func (rt *RangeTombstone) ToTransactionRow(transactionId uint32, state string) *TransactionRow {
+	if rt.IsPrefix {
+		return NewTransactionRow(transactionId, DB_OP_DELETE_PREFIX, state, &K{Key: rt.Prefix, Gsn: rt.Gsn}, nil, &V{Type: TypeTombstone, Value: nil})
+	}
+	return NewTransactionRow(transactionId, DB_OP_DELETE_RANGE, state, &K{Key: rt.StartKey, Gsn: rt.Gsn}, nil, &V{Type: TypeTombstone, Value: []byte(rt.EndKey)})
+}
+
+// RangeTombstoneFromTransactionRow decodes a DELETE_RANGE or DELETE_PREFIX row. Returns nil for other operations.
+func RangeTombstoneFromTransactionRow(row *TransactionRow) *RangeTombstone {
+	switch row.Operation {
+	case DB_OP_DELETE_RANGE:
+		endKey := ""
+		if row.Payload.NewValue != nil {
+			endKey = string(row.Payload.NewValue.Value)
+		}
+		return NewRangeTombstone(row.Payload.Key.Key, endKey, row.Payload.Key.Gsn)
+	case DB_OP_DELETE_PREFIX:
+		return NewPrefixTombstone(row.Payload.Key.Key, row.Payload.Key.Gsn)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,28 @@
+package common
+
+import (
+	"slices"
+	"strings"
+)
+
+// TransactionMarker precedes the transactionId of the commands taking a variable number of keys or query words,
+// MGET, MDEL and AGG, so their last argument is never mistaken for a transactionId or the other way round
+const TransactionMarker = "TXN"
+
+// NeedsTransactionMarker reports whether the transactionId of a command follows TransactionMarker
+func NeedsTransactionMarker(operation string) bool {
+	switch strings.ToUpper(operation) {
+	case "MGET", "MDEL", "AGG":
+		return true
+	}
+	return false
+}
+
+// AppendTransactionId appends the transactionId to the arguments of a command, after TransactionMarker for
+// the commands that need it. args is not modified.
+func AppendTransactionId(operation string, args []string, transactionId string) []string {
+	if NeedsTransactionMarker(operation) {
+		return append(slices.Clip(args), TransactionMarker, transactionId)
+	}
+	return append(slices.Clip(args), transactionId)
+}
@@ -0,0 +1,248 @@
+package common
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// NewInt64V creates a value holding a native int64
+func NewInt64V(n int64) *V {
+	return &V{Type: TypeInt64, Value: NewBinaryBuffer(8).WriteInt64(n).GetBuffer()}
+}
+
+// NewFloat64V creates a value holding a native float64
+func NewFloat64V(f float64) *V {
+	return &V{Type: TypeFloat64, Value: NewBinaryBuffer(8).WriteFloat64(f).GetBuffer()}
+}
+
+// NewTypedV validates the textual input against the data type and encodes it in the type's binary form.
+// Bytes are expected to be base64 encoded.
+func NewTypedV(dataType DataType, input string) (*V, error) {
+	invalid := fmt.Errorf("invalid %s value %q", dataType.String(), input)
+	bb := NewBinaryBuffer(dataType.Size())
+
+	switch dataType {
+	case TypeInt8, TypeInt16, TypeInt32, TypeInt64:
+		n, err := strconv.ParseInt(input, 10, dataType.Size()*8)
+		if err != nil {
+			return nil, invalid
+		}
+		switch dataType {
+		case TypeInt8:
+			bb.WriteInt8(int8(n))
+		case TypeInt16:
+			bb.WriteInt16(int16(n))
+		case TypeInt32:
+			bb.WriteInt32(int32(n))
+		default:
+			bb.WriteInt64(n)
+		}
+	case TypeUint8, TypeUint16, TypeUint32, TypeUint64:
+		n, err := strconv.ParseUint(input, 10, dataType.Size()*8)
+		if err != nil {
+			return nil, invalid
+		}
+		switch dataType {
+		case TypeUint8:
+			bb.WriteUint8(uint8(n))
+		case TypeUint16:
+			bb.WriteUint16(uint16(n))
+		case TypeUint32:
+			bb.WriteUint32(uint32(n))
+		default:
+			bb.WriteUint64(n)
+		}
+	case TypeFloat32, TypeFloat64:
+		f, err := strconv.ParseFloat(input, dataType.Size()*8)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, invalid
+		}
+		if dataType == TypeFloat32 {
+			bb.WriteFloat32(float32(f))
+		} else {
+			bb.WriteFloat64(f)
+		}
+	case TypeBool:
+		b, err := strconv.ParseBool(input)
+		if err != nil {
+			return nil, invalid
+		}
+		bb.WriteBool(b)
+	case TypeBytes:
+		decoded, err := base64.StdEncoding.DecodeString(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value, expected base64", dataType.String())
+		}
+		return &V{Type: dataType, Value: decoded}, nil
+	case TypeString:
+		return &V{Type: dataType, Value: []byte(input)}, nil
+	case TypeJson:
+		return NewJsonV(input)
+	default:
+		return nil, fmt.Errorf("values of type %s cannot be written", dataType.String())
+	}
+
+	return &V{Type: dataType, Value: bb.GetBuffer()}, nil
+}
+
+// hasValidSize checks that fixed size types hold exactly the number of bytes their type requires
+func (v *V) hasValidSize() bool {
+	size := v.Type.Size()
+	return size == 0 || len(v.Value) == size
+}
+
+// Int64 returns the value as an int64. Integer types are decoded natively and strings are parsed,
+// so values written by a plain PUT can still be used as counters.
+func (v *V) Int64() (int64, error) {
+	if !v.hasValidSize() {
+		return 0, errors.New("value has invalid size for type " + v.Type.String())
+	}
+
+	bb := NewBinaryBufferFrom(&v.Value, 0)
+
+	switch v.Type {
+	case TypeInt8:
+		var n int8
+		bb.ReadInt8(&n)
+		return int64(n), nil
+	case TypeInt16:
+		var n int16
+		bb.ReadInt16(&n)
+		return int64(n), nil
+	case TypeInt32:
+		var n int32
+		bb.ReadInt32(&n)
+		return int64(n), nil
+	case TypeInt64:
+		var n int64
+		bb.ReadInt64(&n)
+		return n, nil
+	case TypeUint8:
+		var n uint8
+		bb.ReadUint8(&n)
+		return int64(n), nil
+	case TypeUint16:
+		var n uint16
+		bb.ReadUint16(&n)
+		return int64(n), nil
+	case TypeUint32:
+		var n uint32
+		bb.ReadUint32(&n)
+		return int64(n), nil
+	case TypeUint64:
+		var n uint64
+		bb.ReadUint64(&n)
+		if n > math.MaxInt64 {
+			return 0, errors.New("value is out of range for int64")
+		}
+		return int64(n), nil
+	case TypeString:
+		n, err := strconv.ParseInt(string(v.Value), 10, 64)
+		if err != nil {
+			return 0, errors.New("value is not an integer")
+		}
+		return n, nil
+	default:
+		return 0, errors.New("value is not an integer")
+	}
+}
+
+// Float64 returns the value as a float64. Integer and float types are decoded natively and strings are parsed.
+func (v *V) Float64() (float64, error) {
+	if !v.hasValidSize() {
+		return 0, errors.New("value has invalid size for type " + v.Type.String())
+	}
+
+	bb := NewBinaryBufferFrom(&v.Value, 0)
+
+	switch v.Type {
+	case TypeFloat32:
+		var f float32
+		bb.ReadFloat32(&f)
+		return float64(f), nil
+	case TypeFloat64:
+		var f float64
+		bb.ReadFloat64(&f)
+		return f, nil
+	case TypeString:
+		f, err := strconv.ParseFloat(string(v.Value), 64)
+		if err != nil {
+			return 0, errors.New("value is not a number")
+		}
+		return f, nil
+	case TypeUint64:
+		var n uint64
+		bb.ReadUint64(&n)
+		return float64(n), nil
+	default:
+		n, err := v.Int64()
+		if err != nil {
+			return 0, errors.New("value is not a number")
+		}
+		return float64(n), nil
+	}
+}
+
+// Format returns the human readable representation of the value based on its type
+func (v *V) Format() string {
+	switch v.Type {
+	case TypeInt8, TypeInt16, TypeInt32, TypeInt64, TypeUint8, TypeUint16, TypeUint32:
+		if n, err := v.Int64(); err == nil {
+			return strconv.FormatInt(n, 10)
+		}
+	case TypeUint64:
+		if len(v.Value) == TypeUint64.Size() {
+			var n uint64
+			NewBinaryBufferFrom(&v.Value, 0).ReadUint64(&n)
+			return strconv.FormatUint(n, 10)
+		}
+	case TypeFloat32, TypeFloat64:
+		// Float32 values are formatted with their own precision, so 0.1 isn't widened to 0.10000000149011612
+		if f, err := v.Float64(); err == nil {
+			return strconv.FormatFloat(f, 'g', -1, v.Type.Size()*8)
+		}
+	case TypeBool:
+		if len(v.Value) == TypeBool.Size() {
+			return strconv.FormatBool(v.Value[0] != 0)
+		}
+	case TypeBytes:
+		return base64.StdEncoding.EncodeToString(v.Value)
+	case TypeNull, TypeTombstone:
+		return ""
+	}
+	return string(v.Value)
+}
+
+// Native returns the value as the closest Go type (int64, uint64, float32, float64, bool, string, json.RawMessage for
+// JSON documents or nil for null and deleted values), used when encoding results as JSON
+func (v *V) Native() any {
+	switch {
+	case v.Type == TypeNull || v.Type == TypeTombstone:
+		return nil
+	case v.Type == TypeUint64 && len(v.Value) == TypeUint64.Size():
+		var n uint64
+		NewBinaryBufferFrom(&v.Value, 0).ReadUint64(&n)
+		return n
+	case v.Type.IsInteger():
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+	case v.Type == TypeFloat32:
+		if f, err := v.Float64(); err == nil {
+			return float32(f)
+		}
+	case v.Type.IsFloat():
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	case v.Type == TypeBool && len(v.Value) == TypeBool.Size():
+		return v.Value[0] != 0
+	case v.Type == TypeJson && json.Valid(v.Value):
+		return json.RawMessage(v.Value)
+	}
+	return v.Format()
+}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
 	"log/slog"
+	"reflect"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -10,27 +13,67 @@ type MeteorDbConfig struct {
 	// Server Configuration
 	Host     string `mapstructure:"host" default:"0.0.0.0" description:"the sql host address"`
 	Port     string `mapstructure:"port" default:"7653" description:"the sql read port"`
+	RespPort string `mapstructure:"respPort" default:"" description:"the port for Redis clients speaking RESP, disabled if empty"`
+	HttpPort string `mapstructure:"httpPort" default:"" description:"the port of the HTTP/JSON API, disabled if empty"`
+	DataDir  string `mapstructure:"dataDir" default:"." description:"the directory of the WAL and the lock file"`
 	LogLevel string `mapstructure:"logLevel" default:"info" description:"Log Level"`
 	UseWal   bool   `mapstructure:"useWal" default:"true" description:"Whether to use write ahead log"`
+
+	// TLS Configuration, all listeners use TLS if a certificate is set
+	TlsCertFile     string `mapstructure:"tlsCertFile" default:"" description:"the PEM certificate of the server, TLS is disabled if empty"`
+	TlsKeyFile      string `mapstructure:"tlsKeyFile" default:"" description:"the PEM private key of the certificate"`
+	TlsMinVersion   string `mapstructure:"tlsMinVersion" default:"1.2" description:"the minimum TLS version, 1.0, 1.1, 1.2 or 1.3"`
+	TlsClientCaFile string `mapstructure:"tlsClientCaFile" default:"" description:"the PEM CA of client certificates, requires clients to present one if set"`
+
+	// Slow Log Configuration
+	SlowlogThreshold  time.Duration `mapstructure:"slowlogThreshold" default:"10ms" description:"the duration from which commands are recorded in the slow log, disabled if 0"`
+	SlowlogMaxLen     int           `mapstructure:"slowlogMaxLen" default:"128" description:"the number of slow log entries kept in memory"`
+	SlowlogRedactArgs bool          `mapstructure:"slowlogRedactArgs" default:"false" description:"whether to leave the arguments of commands out of the slow log"`
+	SlowlogFile       string        `mapstructure:"slowlogFile" default:"" description:"a file the slow log is appended to as JSON lines, none if empty"`
 }
 
 var Config *MeteorDbConfig
 const configPath = "./"
 
-func LoadConfig() {
-	viper.SetConfigName("config")
+// LoadConfig reads configFile, or config.json in the working directory if it is empty. Overrides, e.g. from
+// command line flags, take precedence over the file. Without configFile a missing config.json is not an error,
+// so instances can be configured by flags only.
+func LoadConfig(configFile string, overrides map[string]any) {
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+	} else {
+		viper.SetConfigName("config")
+		viper.AddConfigPath(configPath)
+	}
 	viper.SetConfigType("json")
-	viper.AddConfigPath(configPath)
 
 	// Set default values
 	viper.SetDefault("host", "0.0.0.0")
 	viper.SetDefault("port", "7653")
+	viper.SetDefault("respPort", "")
+	viper.SetDefault("httpPort", "")
+	viper.SetDefault("dataDir", ".")
 	viper.SetDefault("logLevel", "info")
 	viper.SetDefault("useWal", true)
+	viper.SetDefault("tlsCertFile", "")
+	viper.SetDefault("tlsKeyFile", "")
+	viper.SetDefault("tlsMinVersion", "1.2")
+	viper.SetDefault("tlsClientCaFile", "")
+	viper.SetDefault("slowlogThreshold", "10ms")
+	viper.SetDefault("slowlogMaxLen", 128)
+	viper.SetDefault("slowlogRedactArgs", false)
+	viper.SetDefault("slowlogFile", "")
 
 	if err := viper.ReadInConfig(); err != nil {
-		slog.Error("Failed to read config")
-		panic(err)
+		var notFound viper.ConfigFileNotFoundError
+		if configFile != "" || !errors.As(err, &notFound) {
+			slog.Error("Failed to read config")
+			panic(err)
+		}
+	}
+
+	for key, value := range overrides {
+		viper.Set(key, value)
 	}
 
 	if err := viper.Unmarshal(&Config); err != nil {
@@ -38,3 +81,13 @@ func LoadConfig() {
 		panic(err)
 	}
 }
+
+// Settings returns the config by the keys of config.json
+func (c *MeteorDbConfig) Settings() map[string]any {
+	settings := make(map[string]any)
+	value := reflect.ValueOf(c).Elem()
+	for i := range value.NumField() {
+		settings[value.Type().Field(i).Tag.Get("mapstructure")] = value.Field(i).Interface()
+	}
+	return settings
+}
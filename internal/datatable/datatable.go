@@ -2,6 +2,15 @@ package datatable
 
 import "meteor/internal/common"
 
+// TableStats describes the contents of a table
+type TableStats struct {
+	Keys     int `json:"keys"`
+	Versions int `json:"versions"`
+	// Bytes is the size of the keys and values of all versions
+	Bytes           int `json:"bytes"`
+	RangeTombstones int `json:"range_tombstones"`
+}
+
 type DataTable interface {
 	Get(key string) *common.V
 	Put(key *common.K, value *common.V) error
@@ -19,4 +28,13 @@ type DataTable interface {
 	ScanRange(startKey, endKey string) map[string]*common.V
 	ScanWithFilter(filterFunc func(string, *common.V) bool) map[string]*common.V
 	CountWithFilter(filterFunc func(string, *common.V) bool) int
+	// Iterator walks the keys in the bounds of opts in key order
+	Iterator(opts common.IteratorOptions) common.KVIterator
+
+	// PutRangeTombstone marks all keys covered by the tombstone as deleted at the tombstone's GSN
+	PutRangeTombstone(rangeTombstone *common.RangeTombstone) error
+	// RangeTombstones returns all range tombstones in the table
+	RangeTombstones() []*common.RangeTombstone
+	// Stats counts the keys, versions and bytes of the table
+	Stats() TableStats
 }
@@ -2,39 +2,40 @@ package datatable
 
 import (
 	"errors"
+	"math"
 	"meteor/internal/common"
+	"meteor/internal/skiplist"
+	"slices"
+	"strings"
 	"sync"
 )
 
 type MapDataTable struct {
 	m sync.RWMutex
 	table map[string]map[uint32]*common.V
+	// sortedKeys holds the keys of table in order, so iterators find the next key without sorting all keys
+	sortedKeys *skiplist.SkipList[string]
+	// TODO: Range tombstones are never removed. They can be dropped once compaction has applied them to all covered keys.
+	rangeTombstones []*common.RangeTombstone
 }
 
 func NewMapDataTable() *MapDataTable {
 	return &MapDataTable{
 		m: sync.RWMutex{},
 		table: make(map[string]map[uint32]*common.V),
+		sortedKeys: skiplist.New(strings.Compare),
+		rangeTombstones: make([]*common.RangeTombstone, 0),
 	}
 }
 
+// Get returns the latest version of a key. Keys covered by a newer range tombstone return a tombstone,
+// even if the key itself was never written, so range deletes in a transaction store hide the committed values.
 func (m *MapDataTable) Get(key string) *common.V {
 	m.m.RLock()
 	defer m.m.RUnlock()
 
-	gsnMap, ok := m.table[key]
-	if !ok {
-		return nil
-	}
-
-	var maxGsn uint32
-	for gsn := range gsnMap {
-		if gsn > maxGsn {
-			maxGsn = gsn
-		}
-	}
-
-	return gsnMap[maxGsn]
+	value, gsn := m.getVersionAtOrBeforeGsn(key, math.MaxUint32)
+	return m.applyRangeTombstones(key, value, gsn, math.MaxUint32)
 }
 
 func (m *MapDataTable) Put(key *common.K, value *common.V) error {
@@ -45,6 +46,7 @@ func (m *MapDataTable) Put(key *common.K, value *common.V) error {
 	if !ok {
 		gsnMap = make(map[uint32]*common.V)
 		m.table[key.Key] = gsnMap
+		m.sortedKeys.Insert(key.Key)
 	}
 	gsnMap[key.Gsn] = value
 	return nil
@@ -65,9 +67,45 @@ func (m *MapDataTable) Clear() error {
 	defer m.m.Unlock()
 
 	m.table = make(map[string]map[uint32]*common.V)
+	m.sortedKeys = skiplist.New(strings.Compare)
+	m.rangeTombstones = make([]*common.RangeTombstone, 0)
+	return nil
+}
+
+// PutRangeTombstone deletes all keys covered by the tombstone with a single entry instead of one tombstone per key
+func (m *MapDataTable) PutRangeTombstone(rangeTombstone *common.RangeTombstone) error {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	m.rangeTombstones = append(m.rangeTombstones, rangeTombstone)
 	return nil
 }
 
+func (m *MapDataTable) RangeTombstones() []*common.RangeTombstone {
+	m.m.RLock()
+	defer m.m.RUnlock()
+
+	return slices.Clone(m.rangeTombstones)
+}
+
+// Stats walks all versions of all keys, so it takes as long as a full scan
+func (m *MapDataTable) Stats() TableStats {
+	m.m.RLock()
+	defer m.m.RUnlock()
+
+	stats := TableStats{Keys: len(m.table), RangeTombstones: len(m.rangeTombstones)}
+	for key, versions := range m.table {
+		stats.Versions += len(versions)
+		for _, value := range versions {
+			stats.Bytes += len(key)
+			if value != nil {
+				stats.Bytes += len(value.Value)
+			}
+		}
+	}
+	return stats
+}
+
 func (m *MapDataTable) Keys() []string {
 	m.m.RLock()
 	defer m.m.RUnlock()
@@ -94,6 +132,13 @@ func (m *MapDataTable) GetLatestGsn(key string) (uint32, error) {
 			maxGsn = gsn
 		}
 	}
+
+	// A range delete of the key is a newer version of it
+	for _, rangeTombstone := range m.rangeTombstones {
+		if rangeTombstone.Gsn > maxGsn && rangeTombstone.Covers(key) {
+			maxGsn = rangeTombstone.Gsn
+		}
+	}
 	return maxGsn, nil
 }
 
@@ -102,9 +147,15 @@ func (m *MapDataTable) GetVersionAtOrBeforeGsn(key string, maxGsn uint32) *commo
 	m.m.RLock()
 	defer m.m.RUnlock()
 
+	value, gsn := m.getVersionAtOrBeforeGsn(key, maxGsn)
+	return m.applyRangeTombstones(key, value, gsn, maxGsn)
+}
+
+// getVersionAtOrBeforeGsn returns the version with the highest GSN that is <= maxGsn and its GSN (assumes lock is held)
+func (m *MapDataTable) getVersionAtOrBeforeGsn(key string, maxGsn uint32) (*common.V, uint32) {
 	gsnMap, ok := m.table[key]
 	if !ok {
-		return nil
+		return nil, 0
 	}
 
 	// Find the highest GSN that is <= maxGsn
@@ -121,10 +172,21 @@ func (m *MapDataTable) GetVersionAtOrBeforeGsn(key string, maxGsn uint32) *commo
 	}
 
 	if !found {
-		return nil
+		return nil, 0
 	}
 
-	return gsnMap[bestGsn]
+	return gsnMap[bestGsn], bestGsn
+}
+
+// applyRangeTombstones returns a tombstone if a range tombstone created after the version's GSN (and at or before maxGsn)
+// covers the key, otherwise the version itself (assumes lock is held)
+func (m *MapDataTable) applyRangeTombstones(key string, value *common.V, gsn uint32, maxGsn uint32) *common.V {
+	for _, rangeTombstone := range m.rangeTombstones {
+		if rangeTombstone.Gsn > gsn && rangeTombstone.Gsn <= maxGsn && rangeTombstone.Covers(key) {
+			return &common.V{Type: common.TypeTombstone, Value: nil}
+		}
+	}
+	return value
 }
 
 func (m *MapDataTable) ScanPrefix(prefix string) map[string]*common.V {
@@ -187,19 +249,80 @@ func (m *MapDataTable) CountWithFilter(filterFunc func(string, *common.V) bool)
 	return count
 }
 
-// getLatestValue is a helper method to get the latest value for a key (assumes lock is held)
+// getLatestValue is a helper method to get the latest value for a key, with range tombstones applied (assumes lock is held)
 func (m *MapDataTable) getLatestValue(key string) *common.V {
-	gsnMap, ok := m.table[key]
-	if !ok {
+	value, gsn := m.getVersionAtOrBeforeGsn(key, math.MaxUint32)
+	if value == nil {
 		return nil
 	}
-	
-	var maxGsn uint32
-	for gsn := range gsnMap {
-		if gsn > maxGsn {
-			maxGsn = gsn
+	return m.applyRangeTombstones(key, value, gsn, math.MaxUint32)
+}
+
+type mapDataTableIterator struct {
+	table   *MapDataTable
+	opts    common.IteratorOptions
+	maxGsn  uint32
+	started bool
+	key     string
+	value   *common.V
+}
+
+// Iterator returns the keys in the bounds in order. Every call to Next seeks the key after the previous one,
+// so an iteration holds no keys in memory and sees the keys written while it runs. Keys which didn't exist at
+// MaxGsn are skipped.
+func (m *MapDataTable) Iterator(opts common.IteratorOptions) common.KVIterator {
+	maxGsn := opts.MaxGsn
+	if maxGsn == 0 {
+		maxGsn = math.MaxUint32
+	}
+
+	return &mapDataTableIterator{
+		table:  m,
+		opts:   opts,
+		maxGsn: maxGsn,
+	}
+}
+
+func (it *mapDataTableIterator) Next() bool {
+	it.table.m.RLock()
+	defer it.table.m.RUnlock()
+
+	for {
+		key, ok := it.seek()
+		if !ok || !it.opts.InBounds(key) {
+			return false
+		}
+		it.started, it.key = true, key
+
+		value, gsn := it.table.getVersionAtOrBeforeGsn(key, it.maxGsn)
+		if it.value = it.table.applyRangeTombstones(key, value, gsn, it.maxGsn); it.value != nil {
+			return true
 		}
 	}
-	
-	return gsnMap[maxGsn]
+}
+
+// seek returns the key after the current one in the order of the iteration, or the first key in the bounds
+// (assumes lock is held)
+func (it *mapDataTableIterator) seek() (string, bool) {
+	sortedKeys := it.table.sortedKeys
+	switch {
+	case it.started && it.opts.Descending:
+		return sortedKeys.Lower(it.key)
+	case it.started:
+		return sortedKeys.Higher(it.key)
+	case it.opts.Descending && it.opts.EndKey == "":
+		return sortedKeys.Last()
+	case it.opts.Descending:
+		return sortedKeys.Floor(it.opts.EndKey)
+	default:
+		return sortedKeys.Ceiling(it.opts.StartKey)
+	}
+}
+
+func (it *mapDataTableIterator) Key() string {
+	return it.key
+}
+
+func (it *mapDataTableIterator) Value() *common.V {
+	return it.value
 }
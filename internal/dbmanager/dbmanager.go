@@ -1,33 +1,77 @@
 package dbmanager
 
 import (
-	"fmt"
 	"io"
 	"log/slog"
+	"meteor/internal/authmanager"
+	"meteor/internal/clientmanager"
 	"meteor/internal/common"
-	"meteor/internal/config"
 	"meteor/internal/gsnmanager"
+	"meteor/internal/metrics"
+	"meteor/internal/monitor"
 	"meteor/internal/parser"
+	"meteor/internal/slowlog"
 	"meteor/internal/storemanager"
 	"meteor/internal/transactionmanager"
 	"meteor/internal/walmanager"
+	"os"
 	"slices"
+	"time"
 )
 
+var recoveryDuration = metrics.Default.NewGauge("meteor_recovery_duration_seconds", "Duration of the WAL replay when the database was opened, in seconds.")
+
 type DBManager struct {
 	Parser parser.Parser
 	StoreManager *storemanager.StoreManager
 	GsnManager         *gsnmanager.GsnManager
 	TransactionManager *transactionmanager.TransactionManager
 	WalManager         *walmanager.WalManager
+	AuthManager        *authmanager.AuthManager
+	ClientManager      *clientmanager.ClientManager
+	// SlowLog records commands that took at least its threshold, nil if disabled
+	SlowLog            *slowlog.SlowLog
+	// Monitor publishes every executed command to the clients running MONITOR
+	Monitor            *monitor.Monitor
+	// StartedAt is when the database was opened
+	StartedAt          time.Time
+	useWal             bool
+	lockFile           *os.File
+}
+
+// Options configures where a DBManager keeps its data
+type Options struct {
+	// Dir is the data directory with the WAL file and the lock file. It is created if it doesn't exist.
+	Dir string
+	// UseWal logs writes to the WAL, without it data is lost when the process exits
+	UseWal bool
+	// EnforceAuth requires connections to authenticate once a user exists and checks their grants
+	EnforceAuth bool
+	// SlowLog configures the slow log, it is disabled if its threshold is 0
+	SlowLog slowlog.Options
 }
 
-func NewDBManager() (*DBManager, error) {
+// NewDBManager opens the data directory and recovers the store from its WAL. The directory is locked until
+// Close, so two instances can't use the same data.
+func NewDBManager(opts Options) (dm *DBManager, err error) {
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+	lockFile, err := lockDataDir(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			unlockDataDir(lockFile)
+		}
+	}()
+
 	storeManager, err := storemanager.NewStoreManager()
 	if err != nil {
 		return nil, err
 	}
-	walManager, err := walmanager.NewWalManager()
+	walManager, err := walmanager.NewWalManager(opts.Dir)
 	if err != nil {
 		return nil, err
 	}
@@ -42,18 +86,40 @@ func NewDBManager() (*DBManager, error) {
 		return nil, err
 	}
 
-	dm := &DBManager{
+	var slowLog *slowlog.SlowLog
+	if opts.SlowLog.Threshold != 0 {
+		slowLog, err = slowlog.New(opts.SlowLog)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dm = &DBManager{
 		Parser: parser.NewStringParser(),
 		StoreManager: storeManager,
 		GsnManager: gsnManager,
 		TransactionManager: transactionManager,
 		WalManager: walManager,
+		AuthManager: authmanager.NewAuthManager(opts.EnforceAuth),
+		ClientManager: clientmanager.NewClientManager(),
+		SlowLog: slowLog,
+		Monitor: monitor.New(),
+		StartedAt: time.Now(),
+		useWal: opts.UseWal,
+		lockFile: lockFile,
 	}
 
+	t0 := time.Now()
 	err = dm.recoverStoreFromWal()
 	if err != nil {
+		walManager.Close()
+		if slowLog != nil {
+			slowLog.Close()
+		}
 		return nil, err
 	}
+	recoveryDuration.Set(time.Since(t0).Seconds())
+	slog.Info("Recovered store from WAL", "duration", time.Since(t0))
 
 	return dm, nil
 }
@@ -66,7 +132,7 @@ func (dm *DBManager) recoverStoreFromWal() error {
 
 	// First pass: store all the transaction ids that were committed
 	err := readWalRows(dm.WalManager, func(transactionRow *common.TransactionRow) {
-		fmt.Printf("transactionRow: %v\n", transactionRow)
+		slog.Debug("recovering", "transactionRow", transactionRow)
 		if transactionRow.State == common.TRANSACTION_STATE_COMMIT {
 			activeTransactionIds = append(activeTransactionIds, transactionRow.TransactionId)
 		}
@@ -92,7 +158,22 @@ func (dm *DBManager) recoverStoreFromWal() error {
 			activeTransactionIds = slices.Delete(activeTransactionIds, transactionIdx, transactionIdx + 1)
 		}
 
-		if !slices.Contains([]string{common.DB_OP_PUT, common.DB_OP_DELETE}, transactionRow.Operation) {
+		// Indexes are recreated where they were created in the WAL, so later writes update them during the replay
+		if transactionRow.Operation == common.DB_OP_CREATE_INDEX || transactionRow.Operation == common.DB_OP_DROP_INDEX {
+			if err := dm.StoreManager.ApplyIndexTxnRow(transactionRow); err != nil {
+				slog.Warn("failed to recover index", "index", transactionRow.Payload.Key.Key, "error", err)
+			}
+			return
+		}
+
+		if transactionRow.Operation == common.DB_OP_PUT_USER || transactionRow.Operation == common.DB_OP_DELETE_USER {
+			if err := dm.AuthManager.ApplyUserTxnRow(transactionRow); err != nil {
+				slog.Warn("failed to recover user", "user", transactionRow.Payload.Key.Key, "error", err)
+			}
+			return
+		}
+
+		if !slices.Contains([]string{common.DB_OP_PUT, common.DB_OP_DELETE, common.DB_OP_DELETE_RANGE, common.DB_OP_DELETE_PREFIX}, transactionRow.Operation) {
 			return
 		}
 
@@ -120,9 +201,23 @@ func readWalRows(walManager *walmanager.WalManager, callback func(transactionRow
 }
 
 func (dm *DBManager) AddTransactionToWal(transactionRow *common.TransactionRow) error {
-	if !config.Config.UseWal {
+	if !dm.useWal {
 		return nil
 	}
-	fmt.Printf("%v\n", transactionRow)
+	slog.Debug("adding to wal", "transactionRow", transactionRow)
 	return dm.WalManager.AddRow(transactionRow)
 }
+
+// Close closes the WAL file and unlocks the data directory. The DBManager can't be used afterwards.
+func (dm *DBManager) Close() error {
+	walErr := dm.WalManager.Close()
+	if dm.SlowLog != nil {
+		if err := dm.SlowLog.Close(); err != nil {
+			slog.Error("Failed to close slow log", "error", err)
+		}
+	}
+	if err := unlockDataDir(dm.lockFile); err != nil {
+		return err
+	}
+	return walErr
+}
@@ -0,0 +1,36 @@
+package dbmanager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockFileName is the name of the lock file in the data directory
+const lockFileName = "meteor.lock"
+
+// lockDataDir takes the lock of a data directory, so no other DBManager, in this or another process, uses the
+// same WAL. The lock is held until the returned file is passed to unlockDataDir.
+func lockDataDir(dir string) (*os.File, error) {
+	path := filepath.Join(dir, lockFileName)
+	lockFile, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tryLockFile(lockFile); err != nil {
+		lockFile.Close()
+		return nil, fmt.Errorf("data directory %s is in use by another instance: %w", dir, err)
+	}
+
+	// The pid helps to find the instance holding the lock
+	lockFile.Truncate(0)
+	lockFile.WriteAt([]byte(fmt.Sprintf("%d\n", os.Getpid())), 0)
+	return lockFile, nil
+}
+
+// unlockDataDir releases the lock of a data directory. The lock file is kept, removing it could release the
+// lock another instance is just taking.
+func unlockDataDir(lockFile *os.File) error {
+	return lockFile.Close()
+}
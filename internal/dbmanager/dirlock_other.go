@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package dbmanager
+
+import "os"
+
+// tryLockFile doesn't lock on platforms without file locks
+func tryLockFile(f *os.File) error {
+	return nil
+}
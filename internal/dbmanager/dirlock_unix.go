@@ -0,0 +1,14 @@
+//go:build unix
+
+package dbmanager
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile takes an exclusive lock on a file without waiting. The lock is released when the file is closed
+// or the process exits.
+func tryLockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
@@ -40,3 +40,8 @@ func (gm *GsnManager) GetNewGsn() uint32 {
 	}
 	return gm.gsn.Add(1)
 }
+
+// NextGsn returns the GSN the next write gets, unless a new batch of GSNs is allocated first
+func (gm *GsnManager) NextGsn() uint32 {
+	return gm.gsn.Load() + 1
+}
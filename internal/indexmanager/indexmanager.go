@@ -0,0 +1,320 @@
+package indexmanager
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"meteor/internal/common"
+	"meteor/internal/skiplist"
+	"meteor/internal/store"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// IndexManager maintains the secondary indexes. Every committed write to an indexed key replaces the key's index
+// entry, so an index holds one entry per key. Snapshots read the entries written up to the snapshot, plus the keys
+// written after it, whose older entries are gone. The records are checked against the condition anyway.
+type IndexManager struct {
+	mu      sync.RWMutex
+	indexes map[string]*index
+}
+
+// index holds the latest entry of every key of one index
+type index struct {
+	definition *common.IndexDefinition
+	createdGsn uint32
+	// byText holds the entries ordered by the formatted field value
+	byText *skiplist.SkipList[*indexEntry]
+	// byNumber holds the entries whose field value is a number, or a string holding one, ordered numerically
+	byNumber *skiplist.SkipList[*indexEntry]
+	// byGsn holds the entries, including those of deleted keys, ordered by the GSN they were written at
+	byGsn *skiplist.SkipList[*indexEntry]
+	// current is the entry of every key
+	current map[string]*indexEntry
+}
+
+// indexEntry is the entry of a key written at gsn. Deleted keys and keys without the indexed field keep an entry
+// in byGsn only, so snapshots before their deletion still read them.
+type indexEntry struct {
+	key       string
+	text      string
+	number    float64
+	isNumber  bool
+	isDeleted bool
+	gsn       uint32
+}
+
+func NewIndexManager() *IndexManager {
+	return &IndexManager{indexes: make(map[string]*index)}
+}
+
+// Create registers an index and builds it from the latest committed values of the keys with its prefix.
+// The manager stays locked while building, so writes committed meanwhile are applied once the build is done.
+func (im *IndexManager) Create(definition *common.IndexDefinition, gsn uint32, bufferStore store.Store) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if _, exists := im.indexes[definition.Name]; exists {
+		return errors.New("index already exists: " + definition.Name)
+	}
+
+	idx := &index{
+		definition: definition,
+		createdGsn: gsn,
+		byText:     skiplist.New(compareText),
+		byNumber:   skiplist.New(compareNumber),
+		byGsn:      skiplist.New(compareGsn),
+		current:    make(map[string]*indexEntry),
+	}
+	iterator := bufferStore.Iterator(common.IteratorOptions{StartKey: definition.Prefix, EndKey: definition.Prefix + "\xff"})
+	for iterator.Next() {
+		key := iterator.Key()
+		if !definition.Covers(key) {
+			continue
+		}
+		latestGsn, err := bufferStore.GetLatestGsn(key)
+		if err != nil {
+			continue
+		}
+		idx.apply(key, iterator.Value(), latestGsn)
+	}
+
+	im.indexes[definition.Name] = idx
+	return nil
+}
+
+// Drop removes an index
+func (im *IndexManager) Drop(name string) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if _, exists := im.indexes[name]; !exists {
+		return errors.New("index not found: " + name)
+	}
+	delete(im.indexes, name)
+	return nil
+}
+
+// Definitions returns the definitions of all indexes ordered by name
+func (im *IndexManager) Definitions() []*common.IndexDefinition {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	definitions := make([]*common.IndexDefinition, 0, len(im.indexes))
+	for _, idx := range im.indexes {
+		definitions = append(definitions, idx.definition)
+	}
+	slices.SortFunc(definitions, func(a, b *common.IndexDefinition) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return definitions
+}
+
+// ApplyWrite updates the indexes covering the key with a committed value. Deleted values remove the key's entry.
+func (im *IndexManager) ApplyWrite(key *common.K, value *common.V) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	for _, idx := range im.indexes {
+		if idx.definition.Covers(key.Key) {
+			idx.apply(key.Key, value, key.Gsn)
+		}
+	}
+}
+
+// ApplyRangeTombstone removes the entries of the keys covered by a committed range delete that were written before it
+func (im *IndexManager) ApplyRangeTombstone(rangeTombstone *common.RangeTombstone) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	for _, idx := range im.indexes {
+		for key, entry := range idx.current {
+			if !entry.isDeleted && rangeTombstone.Covers(key) && entry.gsn < rangeTombstone.Gsn {
+				idx.apply(key, nil, rangeTombstone.Gsn)
+			}
+		}
+	}
+}
+
+// Lookup returns the keys whose indexed field may match all conditions, as of maxGsn. A maxGsn of 0 reads the
+// latest entries. The result is a superset of the matching keys, so the records still have to be checked against
+// the condition. The second result is false if the index doesn't exist or was created after maxGsn.
+func (im *IndexManager) Lookup(name string, conditions []common.IndexCondition, maxGsn uint32) ([]string, bool) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	idx, exists := im.indexes[name]
+	if !exists || (maxGsn != 0 && maxGsn < idx.createdGsn) {
+		return nil, false
+	}
+
+	var keys map[string]struct{}
+	for _, condition := range conditions {
+		matching := idx.lookup(condition, maxGsn)
+		if keys == nil {
+			keys = matching
+			continue
+		}
+		for key := range keys {
+			if _, ok := matching[key]; !ok {
+				delete(keys, key)
+			}
+		}
+	}
+
+	result := make([]string, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	slices.Sort(result)
+	return result, true
+}
+
+// apply replaces the entry of a key with the one written at gsn. Writes may arrive out of GSN order, e.g. when
+// a transaction that got its GSN earlier commits later. A write older than the entry is dropped, snapshots
+// before the entry read the key anyway.
+func (idx *index) apply(key string, value *common.V, gsn uint32) {
+	if current := idx.current[key]; current != nil {
+		if current.gsn >= gsn {
+			// Already applied, e.g. a write committed while the index was built, or older
+			return
+		}
+		idx.byText.Delete(current)
+		idx.byNumber.Delete(current)
+		idx.byGsn.Delete(current)
+	}
+
+	entry := newIndexEntry(key, idx.definition.FieldValue(value), gsn)
+	idx.current[key] = entry
+	idx.byGsn.Insert(entry)
+	if entry.isDeleted {
+		return
+	}
+	idx.byText.Insert(entry)
+	if entry.isNumber {
+		idx.byNumber.Insert(entry)
+	}
+}
+
+// lookup returns the keys whose entry may match the condition. Reads at a snapshot also return the keys
+// written after it, their entry at the snapshot isn't kept.
+func (idx *index) lookup(condition common.IndexCondition, maxGsn uint32) map[string]struct{} {
+	keys := make(map[string]struct{})
+	add := func(entry *indexEntry) {
+		keys[entry.key] = struct{}{}
+	}
+	if maxGsn != 0 {
+		idx.byGsn.Ascend(&indexEntry{gsn: maxGsn}, func(entry *indexEntry) bool {
+			if entry.gsn > maxGsn {
+				add(entry)
+			}
+			return true
+		})
+	}
+
+	if condition.Operator == "=" {
+		for _, value := range condition.Values {
+			idx.scanText(value, value, true, true, add)
+			if number, ok := parseNumber(value); ok {
+				idx.scanNumber(number, number, true, true, add)
+			}
+			// Bool values match any spelling strconv.ParseBool accepts
+			if b, err := strconv.ParseBool(value); err == nil {
+				idx.scanText(strconv.FormatBool(b), strconv.FormatBool(b), true, true, add)
+			}
+		}
+		return keys
+	}
+
+	value := condition.Values[0]
+	hasLower := condition.Operator == ">" || condition.Operator == ">="
+	hasUpper := condition.Operator == "<" || condition.Operator == "<="
+
+	// Numbers are compared numerically with numeric values and as strings with everything else. Bounds are
+	// inclusive, since numbers converted to float64 may compare equal.
+	if number, ok := parseNumber(value); ok {
+		idx.scanNumber(number, number, hasLower, hasUpper, add)
+		idx.scanText(value, value, hasLower, hasUpper, func(entry *indexEntry) {
+			if !entry.isNumber {
+				add(entry)
+			}
+		})
+		return keys
+	}
+
+	idx.scanText(value, value, hasLower, hasUpper, add)
+	return keys
+}
+
+// scanText calls fn for the entries whose text is within the inclusive bounds that are set
+func (idx *index) scanText(lower, upper string, hasLower, hasUpper bool, fn func(*indexEntry)) {
+	visit := func(entry *indexEntry) bool {
+		if hasUpper && entry.text > upper {
+			return false
+		}
+		fn(entry)
+		return true
+	}
+	if hasLower {
+		idx.byText.Ascend(&indexEntry{text: lower}, visit)
+	} else {
+		idx.byText.AscendAll(visit)
+	}
+}
+
+// scanNumber calls fn for the entries whose number is within the inclusive bounds that are set
+func (idx *index) scanNumber(lower, upper float64, hasLower, hasUpper bool, fn func(*indexEntry)) {
+	visit := func(entry *indexEntry) bool {
+		if hasUpper && entry.number > upper {
+			return false
+		}
+		fn(entry)
+		return true
+	}
+	if hasLower {
+		idx.byNumber.Ascend(&indexEntry{number: lower}, visit)
+	} else {
+		idx.byNumber.AscendAll(visit)
+	}
+}
+
+func newIndexEntry(key string, fieldValue *common.V, gsn uint32) *indexEntry {
+	entry := &indexEntry{key: key, gsn: gsn}
+	if fieldValue == nil {
+		entry.isDeleted = true
+		return entry
+	}
+
+	entry.text = fieldValue.Format()
+	switch {
+	case fieldValue.Type.IsNumeric():
+		if number, err := fieldValue.Float64(); err == nil && !math.IsNaN(number) {
+			entry.number, entry.isNumber = number, true
+		}
+	case fieldValue.Type == common.TypeString:
+		entry.number, entry.isNumber = parseNumber(entry.text)
+	}
+	return entry
+}
+
+// parseNumber parses the numbers conditions compare numerically. NaN never compares, so it isn't indexed as a number.
+func parseNumber(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) {
+		return 0, false
+	}
+	return number, true
+}
+
+func compareText(a, b *indexEntry) int {
+	return cmp.Or(cmp.Compare(a.text, b.text), cmp.Compare(a.key, b.key))
+}
+
+func compareNumber(a, b *indexEntry) int {
+	return cmp.Or(cmp.Compare(a.number, b.number), cmp.Compare(a.key, b.key))
+}
+
+func compareGsn(a, b *indexEntry) int {
+	return cmp.Or(cmp.Compare(a.gsn, b.gsn), cmp.Compare(a.key, b.key))
+}
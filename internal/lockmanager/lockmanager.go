@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -68,6 +69,11 @@ type LockManager struct {
 	waitingRequests map[string][]*LockRequest
 	// mutex for protecting internal data structures
 	mutex sync.RWMutex
+	// lockWaits, lockTimeouts and deadlocks count the requests that waited, timed out waiting and were refused
+	// because of a deadlock, since the lock manager was created
+	lockWaits    atomic.Uint64
+	lockTimeouts atomic.Uint64
+	deadlocks    atomic.Uint64
 }
 
 // NewLockManager creates a new lock manager
@@ -102,6 +108,7 @@ func (lm *LockManager) AcquireLock(transactionID uint32, key string, lockType Lo
 	// Check for deadlock before adding to waiting queue
 	if lm.wouldCauseDeadlock(transactionID, key) {
 		lm.mutex.Unlock()
+		lm.deadlocks.Add(1)
 		return errors.New("deadlock detected")
 	}
 
@@ -115,6 +122,7 @@ func (lm *LockManager) AcquireLock(transactionID uint32, key string, lockType Lo
 
 	lm.waitingRequests[key] = append(lm.waitingRequests[key], request)
 	lm.mutex.Unlock()
+	lm.lockWaits.Add(1)
 
 	// Wait for lock to be granted or timeout
 	select {
@@ -123,6 +131,7 @@ func (lm *LockManager) AcquireLock(transactionID uint32, key string, lockType Lo
 	case <-time.After(timeout):
 		// Remove from waiting queue and return timeout error
 		lm.removeWaitingRequest(transactionID, key)
+		lm.lockTimeouts.Add(1)
 		return fmt.Errorf("lock acquisition timeout for transaction %d on key %s", transactionID, key)
 	}
 }
@@ -259,8 +268,10 @@ func (lm *LockManager) releaseLock(transactionID uint32, key string, lockType Lo
 		lm.transactionLocks[transactionID] = newTxnLocks
 	}
 
-	// Check if any waiting requests can now be granted
+	// Check if any waiting requests can now be granted. Range locks wait for the locks on the keys in their
+	// range, so they are checked whenever a lock is released.
 	lm.processWaitingRequests(key)
+	lm.processWaitingRangeRequests()
 
 	return nil
 }
@@ -275,6 +286,26 @@ func (lm *LockManager) processWaitingRequests(key string) {
 	newQueue := make([]*LockRequest, 0)
 
 	for _, request := range waitingQueue {
+		if request.Type == RangeLock {
+			if !lm.canGrantRangeLock(request.StartKey, request.EndKey, request.TransactionID) {
+				newQueue = append(newQueue, request)
+				continue
+			}
+			lm.grantRangeLock(&Lock{
+				TransactionID: request.TransactionID,
+				Key:           key,
+				Type:          RangeLock,
+				AcquiredAt:    time.Now(),
+				StartKey:      request.StartKey,
+				EndKey:        request.EndKey,
+			})
+			select {
+			case request.AcquiredCh <- nil:
+			default:
+			}
+			continue
+		}
+
 		if lm.canGrantLock(key, request.Type, request.TransactionID) {
 			// Grant the lock
 			lock := &Lock{
@@ -304,6 +335,15 @@ func (lm *LockManager) processWaitingRequests(key string) {
 	}
 }
 
+// processWaitingRangeRequests processes the waiting range lock requests
+func (lm *LockManager) processWaitingRangeRequests() {
+	for key, waitingQueue := range lm.waitingRequests {
+		if len(waitingQueue) > 0 && waitingQueue[0].Type == RangeLock {
+			lm.processWaitingRequests(key)
+		}
+	}
+}
+
 // removeWaitingRequest removes a waiting request (used for timeout)
 func (lm *LockManager) removeWaitingRequest(transactionID uint32, key string) {
 	lm.mutex.Lock()
@@ -362,6 +402,9 @@ func (lm *LockManager) GetLockStatistics() map[string]any {
 		"total_keys_locked":   len(lm.lockTable),
 		"active_transactions": len(lm.transactionLocks),
 		"waiting_requests":    0,
+		"lock_waits":          lm.lockWaits.Load(),
+		"lock_timeouts":       lm.lockTimeouts.Load(),
+		"deadlocks":           lm.deadlocks.Load(),
 	}
 
 	totalWaiting := 0
@@ -396,6 +439,7 @@ func (lm *LockManager) AcquireRangeLock(transactionID uint32, startKey, endKey s
 	// Check for deadlock before adding to waiting queue
 	if lm.wouldCauseDeadlock(transactionID, fmt.Sprintf("range:%s:%s", startKey, endKey)) {
 		lm.mutex.Unlock()
+		lm.deadlocks.Add(1)
 		return errors.New("deadlock detected")
 	}
 	
@@ -411,6 +455,7 @@ func (lm *LockManager) AcquireRangeLock(transactionID uint32, startKey, endKey s
 	
 	lm.waitingRequests[request.Key] = append(lm.waitingRequests[request.Key], request)
 	lm.mutex.Unlock()
+	lm.lockWaits.Add(1)
 	
 	// Wait for lock to be granted or timeout
 	select {
@@ -418,6 +463,7 @@ func (lm *LockManager) AcquireRangeLock(transactionID uint32, startKey, endKey s
 		return err
 	case <-time.After(timeout):
 		lm.removeWaitingRequest(transactionID, request.Key)
+		lm.lockTimeouts.Add(1)
 		return fmt.Errorf("range lock acquisition timeout for transaction %d on range [%s, %s]", transactionID, startKey, endKey)
 	}
 }
@@ -444,6 +490,7 @@ func (lm *LockManager) AcquirePredicateLock(transactionID uint32, predicate stri
 	// Check for deadlock
 	if lm.wouldCauseDeadlock(transactionID, fmt.Sprintf("predicate:%s", predicate)) {
 		lm.mutex.Unlock()
+		lm.deadlocks.Add(1)
 		return errors.New("deadlock detected")
 	}
 	
@@ -458,6 +505,7 @@ func (lm *LockManager) AcquirePredicateLock(transactionID uint32, predicate stri
 	
 	lm.waitingRequests[request.Key] = append(lm.waitingRequests[request.Key], request)
 	lm.mutex.Unlock()
+	lm.lockWaits.Add(1)
 	
 	// Wait for lock to be granted or timeout
 	select {
@@ -465,6 +513,7 @@ func (lm *LockManager) AcquirePredicateLock(transactionID uint32, predicate stri
 		return err
 	case <-time.After(timeout):
 		lm.removeWaitingRequest(transactionID, request.Key)
+		lm.lockTimeouts.Add(1)
 		return fmt.Errorf("predicate lock acquisition timeout for transaction %d on predicate %s", transactionID, predicate)
 	}
 }
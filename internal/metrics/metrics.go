@@ -0,0 +1,344 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DurationBuckets are the upper bounds in seconds of duration histograms, from 50µs to 10s
+var DurationBuckets = []float64{0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default is the registry the packages of the database register their metrics in
+var Default = NewRegistry()
+
+// Registry holds metrics and writes them in the Prometheus text exposition format
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// metric is a family of series with the same name and labels
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[name]; ok {
+		log.Fatalf("metric %q already registered", name)
+	}
+	r.metrics[name] = m
+}
+
+// Write writes all metrics sorted by name
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	metrics := make([]metric, len(names))
+	for i, name := range names {
+		metrics[i] = r.metrics[name]
+	}
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// desc is the name, help and label names shared by the series of a metric
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.ReplaceAll(d.help, "\n", " "))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// writeSample writes one sample line. extra is a label added after the labels of the metric, e.g. le of histograms.
+func (d *desc) writeSample(w *bufio.Writer, suffix string, labelValues []string, extraName, extraValue string, value float64) {
+	w.WriteString(d.name)
+	w.WriteString(suffix)
+	if len(d.labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, label := range d.labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, label, labelValues[i])
+		}
+		if extraName != "" {
+			if len(d.labels) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatValue(value))
+	w.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeLabel(w *bufio.Writer, name, value string) {
+	w.WriteString(name)
+	w.WriteString(`="`)
+	labelEscaper.WriteString(w, value)
+	w.WriteByte('"')
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// seriesKey joins label values into a map key. The separator can't appear in valid UTF-8 label values.
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+func (d *desc) checkLabels(labelValues []string) {
+	if len(labelValues) != len(d.labels) {
+		panic(fmt.Sprintf("metric %s has labels %v, got %d values", d.name, d.labels, len(labelValues)))
+	}
+}
+
+// value is a counter or gauge, one series per combination of label values
+type value struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*valueSeries
+}
+
+type valueSeries struct {
+	labelValues []string
+	value       float64
+}
+
+func (v *value) add(delta float64, labelValues []string) {
+	v.checkLabels(labelValues)
+	key := seriesKey(labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &valueSeries{labelValues: slices.Clone(labelValues)}
+		v.series[key] = s
+	}
+	s.value += delta
+}
+
+func (v *value) set(newValue float64, labelValues []string) {
+	v.checkLabels(labelValues)
+	key := seriesKey(labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &valueSeries{labelValues: slices.Clone(labelValues)}
+		v.series[key] = s
+	}
+	s.value = newValue
+}
+
+func (v *value) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.writeHeader(w)
+	for _, key := range sortedKeys(v.series) {
+		s := v.series[key]
+		v.writeSample(w, "", s.labelValues, "", "", s.value)
+	}
+}
+
+func sortedKeys[S any](series map[string]S) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Counter is a value that only goes up, e.g. a number of errors
+type Counter struct {
+	value
+}
+
+// NewCounter registers a counter with the label names. Counters without labels start with a zero series.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{value{desc: desc{name: name, help: help, kind: "counter", labels: labels}, series: make(map[string]*valueSeries)}}
+	if len(labels) == 0 {
+		c.add(0, nil)
+	}
+	r.register(name, c)
+	return c
+}
+
+// Inc adds one to the series of the label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.add(1, labelValues)
+}
+
+// Add adds delta, which must not be negative, to the series of the label values
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("counter %s can't decrease", c.name))
+	}
+	c.add(delta, labelValues)
+}
+
+// Gauge is a value that goes up and down, e.g. a number of connections
+type Gauge struct {
+	value
+}
+
+// NewGauge registers a gauge with the label names. Gauges without labels start with a zero series.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{value{desc: desc{name: name, help: help, kind: "gauge", labels: labels}, series: make(map[string]*valueSeries)}}
+	if len(labels) == 0 {
+		g.add(0, nil)
+	}
+	r.register(name, g)
+	return g
+}
+
+func (g *Gauge) Set(newValue float64, labelValues ...string) {
+	g.set(newValue, labelValues)
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.add(1, labelValues)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.add(-1, labelValues)
+}
+
+// Histogram counts observations, e.g. durations, in buckets of upper bounds
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	// counts holds the observations of each bucket, not cumulative, and the ones above the last bucket
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the bucket upper bounds, which must be sorted, and the label names
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !slices.IsSorted(buckets) {
+		log.Fatalf("buckets of histogram %q aren't sorted", name)
+	}
+	h := &Histogram{
+		desc:    desc{name: name, help: help, kind: "histogram", labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	if len(labels) == 0 {
+		h.series[""] = &histogramSeries{counts: make([]uint64, len(buckets)+1)}
+	}
+	r.register(name, h)
+	return h
+}
+
+// Observe adds an observation to the series of the label values
+func (h *Histogram) Observe(observation float64, labelValues ...string) {
+	h.checkLabels(labelValues)
+	key := seriesKey(labelValues)
+	bucket, _ := slices.BinarySearch(h.buckets, observation)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{labelValues: slices.Clone(labelValues), counts: make([]uint64, len(h.buckets)+1)}
+		h.series[key] = s
+	}
+	s.counts[bucket]++
+	s.count++
+	s.sum += observation
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeHeader(w)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, upperBound := range h.buckets {
+			cumulative += s.counts[i]
+			h.writeSample(w, "_bucket", s.labelValues, "le", formatValue(upperBound), float64(cumulative))
+		}
+		h.writeSample(w, "_bucket", s.labelValues, "le", "+Inf", float64(s.count))
+		h.writeSample(w, "_sum", s.labelValues, "", "", s.sum)
+		h.writeSample(w, "_count", s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// Sample is a value of a metric collected when the metrics are written
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// collected is a metric whose samples are read from the state they describe, e.g. the size of a store
+type collected struct {
+	desc
+	collect func() []Sample
+}
+
+// NewGaugeFunc registers a gauge whose samples collect returns whenever the metrics are written
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func() []Sample) {
+	r.register(name, &collected{desc: desc{name: name, help: help, kind: "gauge", labels: labels}, collect: collect})
+}
+
+// NewCounterFunc registers a counter whose samples collect returns whenever the metrics are written
+func (r *Registry) NewCounterFunc(name, help string, labels []string, collect func() []Sample) {
+	r.register(name, &collected{desc: desc{name: name, help: help, kind: "counter", labels: labels}, collect: collect})
+}
+
+func (c *collected) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	for _, sample := range c.collect() {
+		c.checkLabels(sample.LabelValues)
+		c.writeSample(w, "", sample.LabelValues, "", "", sample.Value)
+	}
+}
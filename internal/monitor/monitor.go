@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event is a command the server executed
+type Event struct {
+	Time     time.Time
+	ClientId uint64
+	Client   string
+	Command  string
+	Args     []string
+	// TransactionId is the transaction the command ran in, 0 for commands that failed before running or don't
+	// run in a transaction
+	TransactionId uint32
+	// Status is OK or the error code of the command
+	Status   string
+	Duration time.Duration
+}
+
+// Format returns the event as a line, "<time> [<client id> <address>] txn=<id> <status> <duration> <command> <args>"
+// with the command and its arguments quoted
+func (e Event) Format() string {
+	var line strings.Builder
+	fmt.Fprintf(&line, "%s [%d %s] txn=%d %s %s %s", e.Time.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		e.ClientId, e.Client, e.TransactionId, e.Status, e.Duration, strconv.Quote(e.Command))
+	for _, arg := range e.Args {
+		line.WriteString(" ")
+		line.WriteString(strconv.Quote(arg))
+	}
+	return line.String()
+}
+
+// Subscription receives the events published after it was created. Events that don't fit its buffer are
+// dropped, so a slow subscriber never slows down the commands it monitors.
+type Subscription struct {
+	events  chan Event
+	dropped atomic.Uint64
+}
+
+// Events returns the channel of the events. It isn't closed, subscribers stop reading once they unsubscribe.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events dropped because the buffer was full
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Monitor publishes the executed commands to its subscriptions
+type Monitor struct {
+	mu            sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+	// active is the number of subscriptions, read without the lock by every command
+	active atomic.Int32
+}
+
+func New() *Monitor {
+	return &Monitor{subscriptions: make(map[*Subscription]struct{})}
+}
+
+// Active reports whether anyone is subscribed, so commands only build events that are read
+func (m *Monitor) Active() bool {
+	return m.active.Load() > 0
+}
+
+// Subscribe adds a subscription buffering up to bufferSize events
+func (m *Monitor) Subscribe(bufferSize int) *Subscription {
+	subscription := &Subscription{events: make(chan Event, bufferSize)}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscriptions[subscription] = struct{}{}
+	m.active.Add(1)
+	return subscription
+}
+
+// Unsubscribe removes a subscription, it receives no more events
+func (m *Monitor) Unsubscribe(subscription *Subscription) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.subscriptions[subscription]; ok {
+		delete(m.subscriptions, subscription)
+		m.active.Add(-1)
+	}
+}
+
+// Publish sends an event to every subscription without blocking. Subscriptions with a full buffer drop it.
+func (m *Monitor) Publish(event Event) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for subscription := range m.subscriptions {
+		select {
+		case subscription.events <- event:
+		default:
+			subscription.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"meteor/internal/common"
+	"strings"
+)
+
+// AggregateFunction is a function computed over the rows selected by an aggregation query
+type AggregateFunction string
+
+const (
+	AggregateCount AggregateFunction = "COUNT"
+	AggregateSum   AggregateFunction = "SUM"
+	AggregateAvg   AggregateFunction = "AVG"
+	AggregateMin   AggregateFunction = "MIN"
+	AggregateMax   AggregateFunction = "MAX"
+)
+
+// Aggregate is an aggregate function applied to a field, e.g. SUM($value). Field is "*", "$key", "$value"
+// or a value field with a JSON path such as "$value.price".
+type Aggregate struct {
+	Function AggregateFunction
+	Field    string
+}
+
+func (a Aggregate) String() string {
+	return fmt.Sprintf("%s(%s)", a.Function, a.Field)
+}
+
+// AggregationQuery is a parsed aggregation query:
+//
+//	aggregate [, aggregate ...] [WHERE condition] [GROUP BY PREFIX($key, 'separator')]
+type AggregationQuery struct {
+	Aggregates []Aggregate
+	// Filter selects the rows to aggregate. Deleted records are only selected by EXISTS and IS TOMBSTONE conditions.
+	Filter func(string, *common.V) bool
+	// IsGrouped is set for GROUP BY PREFIX, which groups keys by the part before the first GroupSeparator
+	IsGrouped      bool
+	GroupSeparator string
+	// StartKey and EndKey are the inclusive bounds of the keys the condition can select. An unbounded end is MaxKey.
+	StartKey string
+	EndKey   string
+}
+
+// ParseAggregation parses an aggregation query. The WHERE condition uses the same syntax as SCAN and COUNT.
+func ParseAggregation(input string) (*AggregationQuery, error) {
+	p := &ConditionParser{lexer: NewLexer(strings.TrimSpace(input))}
+	p.nextToken()
+	p.nextToken()
+
+	query := &AggregationQuery{}
+	for {
+		aggregate, err := p.parseAggregate()
+		if err != nil {
+			return nil, err
+		}
+		query.Aggregates = append(query.Aggregates, aggregate)
+
+		if p.currentToken.Type != TokenComma {
+			break
+		}
+		p.nextToken()
+	}
+
+	query.Filter = func(key string, value *common.V) bool {
+		return value != nil && value.Type != common.TypeTombstone
+	}
+	query.StartKey, query.EndKey = unboundedKeys.start, unboundedKeys.end
+
+	if isKeyword(p.currentToken, "WHERE") {
+		p.nextToken()
+		expr, err := p.parseOrExpression()
+		if err != nil {
+			return nil, err
+		}
+		query.Filter = newFilterFunc(expr)
+		bounds := keyBoundsOf(expr)
+		query.StartKey, query.EndKey = bounds.start, bounds.end
+	}
+
+	if isKeyword(p.currentToken, "GROUP") {
+		separator, err := p.parseGroupByPrefix()
+		if err != nil {
+			return nil, err
+		}
+		query.IsGrouped = true
+		query.GroupSeparator = separator
+	}
+
+	if p.currentToken.Type != TokenEOF {
+		return nil, fmt.Errorf("unexpected token: %s at position %d", p.currentToken.Value, p.currentToken.Pos)
+	}
+
+	return query, nil
+}
+
+// parseAggregate parses FUNCTION(field), where only COUNT accepts *
+func (p *ConditionParser) parseAggregate() (Aggregate, error) {
+	if p.currentToken.Type != TokenField {
+		return Aggregate{}, fmt.Errorf("expected aggregate function at position %d", p.currentToken.Pos)
+	}
+
+	function := AggregateFunction(strings.ToUpper(p.currentToken.Value))
+	switch function {
+	case AggregateCount, AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+	default:
+		return Aggregate{}, fmt.Errorf("unknown aggregate function %s, expected COUNT, SUM, AVG, MIN or MAX", p.currentToken.Value)
+	}
+	p.nextToken()
+
+	if p.currentToken.Type != TokenLeftParen {
+		return Aggregate{}, fmt.Errorf("expected '(' at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	var field string
+	switch {
+	case p.currentToken.Type == TokenStar && function == AggregateCount:
+		field = "*"
+	case p.currentToken.Type == TokenField:
+		field = normalizeField(p.currentToken.Value)
+		if field == "" {
+			return Aggregate{}, fmt.Errorf("unknown field %s at position %d, expected $key, $value or $value.<json path>", p.currentToken.Value, p.currentToken.Pos)
+		}
+	default:
+		return Aggregate{}, fmt.Errorf("expected field at position %d", p.currentToken.Pos)
+	}
+
+	// Sums and averages only make sense for values
+	if (function == AggregateSum || function == AggregateAvg) && field == "$key" {
+		return Aggregate{}, fmt.Errorf("%s requires $value", function)
+	}
+	p.nextToken()
+
+	if p.currentToken.Type != TokenRightParen {
+		return Aggregate{}, fmt.Errorf("expected ')' at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	return Aggregate{Function: function, Field: field}, nil
+}
+
+// parseGroupByPrefix parses GROUP BY PREFIX($key, 'separator') and returns the separator.
+// The separator may be unquoted, e.g. PREFIX($key, :), since quotes are often stripped by the command line.
+func (p *ConditionParser) parseGroupByPrefix() (string, error) {
+	p.nextToken()
+	if !isKeyword(p.currentToken, "BY") {
+		return "", fmt.Errorf("expected BY at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	if !isKeyword(p.currentToken, "PREFIX") {
+		return "", fmt.Errorf("expected PREFIX at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	if p.currentToken.Type != TokenLeftParen {
+		return "", fmt.Errorf("expected '(' at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	if p.currentToken.Type != TokenField || normalizeField(p.currentToken.Value) != "$key" {
+		return "", fmt.Errorf("PREFIX only supports $key at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	if p.currentToken.Type != TokenComma {
+		return "", fmt.Errorf("expected ',' at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	var separator string
+	if p.currentToken.Type == TokenString {
+		separator = p.currentToken.Value
+		p.nextToken()
+	} else {
+		for p.currentToken.Type != TokenRightParen && p.currentToken.Type != TokenEOF {
+			separator += p.currentToken.Value
+			p.nextToken()
+		}
+	}
+	if separator == "" {
+		return "", fmt.Errorf("PREFIX requires a separator")
+	}
+
+	if p.currentToken.Type != TokenRightParen {
+		return "", fmt.Errorf("expected ')' at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	return separator, nil
+}
+
+// isKeyword checks if the token is the given keyword, ignoring case
+func isKeyword(token Token, keyword string) bool {
+	return token.Type == TokenField && strings.EqualFold(token.Value, keyword)
+}
+
+// normalizeField returns the canonical name of a key or value field, or "" for unknown fields.
+// Value fields may select a part of a JSON document, e.g. $value.user.age is normalized to itself.
+func normalizeField(field string) string {
+	switch field {
+	case "$key", "key", "key_name":
+		return "$key"
+	case "$value", "value":
+		return "$value"
+	}
+
+	for _, prefix := range []string{"$value", "value"} {
+		path, ok := strings.CutPrefix(field, prefix)
+		if ok && (strings.HasPrefix(path, ".") || strings.HasPrefix(path, "[")) {
+			if _, err := common.ParseJsonPath("$" + path); err == nil {
+				return "$value" + path
+			}
+		}
+	}
+	return ""
+}
+
+// valuePath returns the JSON path of a normalized value field, or nil if the field refers to the whole value
+func valuePath(field string) common.JsonPath {
+	path, ok := strings.CutPrefix(field, "$value")
+	if !ok || path == "" {
+		return nil
+	}
+	parsed, err := common.ParseJsonPath("$" + path)
+	if err != nil {
+		return nil
+	}
+	return parsed
+}
+
+// Aggregator computes the aggregates of a query over the rows passed to Add
+type Aggregator struct {
+	query  *AggregationQuery
+	groups map[string][]*accumulator
+}
+
+// NewAggregator creates an aggregator for the query. Ungrouped queries always return a result, even without rows.
+func (q *AggregationQuery) NewAggregator() *Aggregator {
+	aggregator := &Aggregator{
+		query:  q,
+		groups: make(map[string][]*accumulator),
+	}
+	if !q.IsGrouped {
+		aggregator.group("")
+	}
+	return aggregator
+}
+
+func (a *Aggregator) group(name string) []*accumulator {
+	accumulators, ok := a.groups[name]
+	if !ok {
+		accumulators = make([]*accumulator, len(a.query.Aggregates))
+		for i, aggregate := range a.query.Aggregates {
+			accumulators[i] = &accumulator{aggregate: aggregate, path: valuePath(aggregate.Field), isIntSum: true}
+		}
+		a.groups[name] = accumulators
+	}
+	return accumulators
+}
+
+// Add adds a row selected by the query's filter to its group
+func (a *Aggregator) Add(key string, value *common.V) {
+	groupName := ""
+	if a.query.IsGrouped {
+		groupName, _, _ = strings.Cut(key, a.query.GroupSeparator)
+	}
+	for _, accumulator := range a.group(groupName) {
+		accumulator.add(key, value)
+	}
+}
+
+// Result returns the aggregates by name, e.g. {"SUM($value)":10}. Grouped queries return them by group.
+func (a *Aggregator) Result() any {
+	if !a.query.IsGrouped {
+		return groupResult(a.groups[""])
+	}
+
+	result := make(map[string]map[string]any, len(a.groups))
+	for groupName, accumulators := range a.groups {
+		result[groupName] = groupResult(accumulators)
+	}
+	return result
+}
+
+func groupResult(accumulators []*accumulator) map[string]any {
+	result := make(map[string]any, len(accumulators))
+	for _, accumulator := range accumulators {
+		result[accumulator.aggregate.String()] = accumulator.result()
+	}
+	return result
+}
+
+// accumulator holds the running state of one aggregate. Values that aren't numbers are ignored by SUM and AVG.
+// Aggregates over a JSON path ignore the rows where the path is missing or null.
+type accumulator struct {
+	aggregate    Aggregate
+	path         common.JsonPath
+	count        int64
+	numericCount int64
+	sumInt       int64
+	sumFloat     float64
+	isIntSum     bool
+	extreme      *orderedValue
+}
+
+func (acc *accumulator) add(key string, value *common.V) {
+	if acc.path != nil {
+		value = value.JsonPathValue(acc.path)
+		if value == nil || value.Type == common.TypeNull {
+			return
+		}
+	}
+
+	switch acc.aggregate.Function {
+	case AggregateCount:
+		acc.count++
+
+	case AggregateSum, AggregateAvg:
+		intValue, floatValue, isInt, ok := numericValue(value)
+		if !ok {
+			return
+		}
+		acc.numericCount++
+		acc.sumFloat += floatValue
+		if acc.isIntSum {
+			sum := acc.sumInt + intValue
+			// Fall back to the float sum when the value isn't an integer or the integer sum overflows
+			if !isInt || (intValue > 0 && sum < acc.sumInt) || (intValue < 0 && sum > acc.sumInt) {
+				acc.isIntSum = false
+			}
+			acc.sumInt = sum
+		}
+
+	case AggregateMin, AggregateMax:
+		candidate := newOrderedValue(key, value, acc.aggregate.Field)
+		if acc.extreme == nil {
+			acc.extreme = candidate
+			return
+		}
+		comparison := candidate.compare(acc.extreme)
+		if (acc.aggregate.Function == AggregateMin && comparison < 0) || (acc.aggregate.Function == AggregateMax && comparison > 0) {
+			acc.extreme = candidate
+		}
+	}
+}
+
+func (acc *accumulator) result() any {
+	switch acc.aggregate.Function {
+	case AggregateCount:
+		return acc.count
+	case AggregateSum:
+		if acc.isIntSum {
+			return acc.sumInt
+		}
+		return acc.sumFloat
+	case AggregateAvg:
+		if acc.numericCount == 0 {
+			return nil
+		}
+		return acc.sumFloat / float64(acc.numericCount)
+	default:
+		if acc.extreme == nil {
+			return nil
+		}
+		return acc.extreme.native
+	}
+}
+
+// numericValue returns the value as a number. Integers are returned as int64 too so sums of integers stay exact.
+func numericValue(value *common.V) (int64, float64, bool, bool) {
+	if n, err := value.Int64(); err == nil {
+		return n, float64(n), true, true
+	}
+	f, err := value.Float64()
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, 0, false, false
+	}
+	return 0, f, false, true
+}
+
+// orderedValue is a key or value as compared by MIN and MAX. Numbers are compared numerically
+// and sort before everything else, which is compared by its string representation.
+type orderedValue struct {
+	isNumber bool
+	number   float64
+	text     string
+	native   any
+}
+
+func newOrderedValue(key string, value *common.V, field string) *orderedValue {
+	if field == "$key" {
+		return &orderedValue{text: key, native: key}
+	}
+	if _, f, _, ok := numericValue(value); ok {
+		return &orderedValue{isNumber: true, number: f, native: value.Native()}
+	}
+	return &orderedValue{text: value.Format(), native: value.Native()}
+}
+
+func (o *orderedValue) compare(other *orderedValue) int {
+	switch {
+	case o.isNumber && other.isNumber:
+		return cmp.Compare(o.number, other.number)
+	case o.isNumber:
+		return -1
+	case other.isNumber:
+		return 1
+	default:
+		return cmp.Compare(o.text, other.text)
+	}
+}
@@ -0,0 +1,281 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"meteor/internal/common"
+	"net"
+	"strconv"
+)
+
+// The binary protocol frames every request and response with its length, so commands of any size can be sent
+// and several requests can be in flight on one connection. All integers are big endian.
+//
+// A connection starts with the client hello: BinaryMagic followed by the lowest and highest protocol version the
+// client supports (uint16 each). The server answers with BinaryMagic and the chosen version (uint16). Version 0
+// means no common version exists and the connection continues in the text protocol.
+//
+// Frames are a uint32 length followed by the frame body.
+// Request body:  uint32 request id, uint8 flags, uint16 length + command name, uint16 argument count, arguments
+// Argument:      uint8 type, then a uint32 length + bytes for strings, 8 bytes for numbers or 1 byte for bools
+// Response body: uint32 request id, uint8 status, then the result for BinaryStatusOk or a uint16 error code
+// followed by the message for BinaryStatusError
+const (
+	BinaryProtocolVersion = 1
+	// MaxBinaryRequestLength limits the size of a request frame
+	MaxBinaryRequestLength = 64 * 1024 * 1024
+	// MaxBinaryResponseLength limits the size of a response frame, which may hold a whole result set
+	MaxBinaryResponseLength = 512*1024*1024 + 64*1024
+)
+
+// BinaryMagic starts the client and server hello. No text command starts with a zero byte, which is how the
+// server tells the protocols apart.
+var BinaryMagic = [4]byte{0x00, 'M', 'T', 'B'}
+
+// Argument types
+const (
+	BinaryArgString  byte = 1
+	BinaryArgInt64   byte = 2
+	BinaryArgUint64  byte = 3
+	BinaryArgFloat64 byte = 4
+	BinaryArgBool    byte = 5
+)
+
+// Request flags
+const (
+	// BinaryFlagConcurrent lets the server run the request alongside other requests of the connection instead of
+	// after them. Clients set it only for requests that don't depend on the ones sent before, e.g. autocommit reads.
+	BinaryFlagConcurrent byte = 1 << 0
+)
+
+// Response statuses
+const (
+	BinaryStatusOk    byte = 0
+	BinaryStatusError byte = 1
+)
+
+// BinaryRequest is a decoded request frame
+type BinaryRequest struct {
+	RequestId uint32
+	Flags     byte
+	Command   *common.Command
+}
+
+// BinaryParser decodes the request frames of the binary protocol
+type BinaryParser struct{}
+
+func NewBinaryParser() *BinaryParser {
+	return &BinaryParser{}
+}
+
+// Parse decodes a request frame body into a command
+func (p *BinaryParser) Parse(data []byte, conn *net.Conn) (*common.Command, error) {
+	request, err := p.decodeRequest(data, conn)
+	if err != nil {
+		return nil, err
+	}
+	return request.Command, nil
+}
+
+// ReadHandshake reads the client hello and returns the version range the client supports
+func (p *BinaryParser) ReadHandshake(reader io.Reader) (uint16, uint16, error) {
+	var hello [8]byte
+	if _, err := io.ReadFull(reader, hello[:]); err != nil {
+		return 0, 0, unexpectedEOF(err)
+	}
+	if [4]byte(hello[:4]) != BinaryMagic {
+		return 0, 0, errors.New("protocol error: invalid handshake")
+	}
+	return binary.BigEndian.Uint16(hello[4:6]), binary.BigEndian.Uint16(hello[6:8]), nil
+}
+
+// ReadRequest reads the next request frame. io.EOF is returned when the connection is closed between frames.
+// Requests with an invalid body are returned with their id and the decoding error, so the error can be answered.
+func (p *BinaryParser) ReadRequest(reader *bufio.Reader, conn *net.Conn) (*BinaryRequest, error) {
+	body, err := ReadBinaryFrame(reader, MaxBinaryRequestLength)
+	if err != nil {
+		return nil, err
+	}
+	return p.decodeRequest(body, conn)
+}
+
+// ReadBinaryFrame reads a length prefixed frame of at most maxLength bytes and returns its body. The body is
+// read as it arrives, so a length header alone doesn't allocate the frame.
+func ReadBinaryFrame(reader io.Reader, maxLength uint32) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+	if length > maxLength {
+		return nil, fmt.Errorf("protocol error: frame of %d bytes is too big", length)
+	}
+	var body bytes.Buffer
+	if _, err := io.Copy(&body, io.LimitReader(reader, int64(length))); err != nil {
+		return nil, err
+	}
+	if body.Len() < int(length) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return body.Bytes(), nil
+}
+
+// decodeRequest decodes a request body. Typed arguments are converted to the text form commands parse,
+// strings are passed unchanged so they may contain any bytes.
+func (p *BinaryParser) decodeRequest(body []byte, conn *net.Conn) (*BinaryRequest, error) {
+	decoder := &frameDecoder{data: body}
+	request := &BinaryRequest{RequestId: decoder.uint32()}
+	request.Flags = decoder.byte()
+	name := decoder.string16()
+	argc := int(decoder.uint16())
+	if decoder.err != nil {
+		return nil, errors.New("protocol error: truncated request header")
+	}
+
+	args := make([]string, 0, min(argc, len(body)))
+	for range argc {
+		arg, err := decoder.argument()
+		if err != nil {
+			return request, err
+		}
+		args = append(args, arg)
+	}
+	if decoder.err == nil && decoder.pos != len(body) {
+		return request, errors.New("protocol error: trailing bytes after the arguments")
+	}
+	if name == "" {
+		return request, errors.New("protocol error: empty command name")
+	}
+
+	request.Command = &common.Command{Operation: name, Args: args, Connection: conn}
+	return request, nil
+}
+
+// EncodeBinaryRequest encodes a request frame, including the length prefix. Arguments may be strings, []byte,
+// signed and unsigned integers, float64 and bool.
+func EncodeBinaryRequest(requestId uint32, flags byte, name string, args ...any) ([]byte, error) {
+	body := binary.BigEndian.AppendUint32(nil, requestId)
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(len(name)))
+	body = append(body, name...)
+	body = binary.BigEndian.AppendUint16(body, uint16(len(args)))
+
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case string:
+			body = append(body, BinaryArgString)
+			body = binary.BigEndian.AppendUint32(body, uint32(len(arg)))
+			body = append(body, arg...)
+		case []byte:
+			body = append(body, BinaryArgString)
+			body = binary.BigEndian.AppendUint32(body, uint32(len(arg)))
+			body = append(body, arg...)
+		case int:
+			body = append(body, BinaryArgInt64)
+			body = binary.BigEndian.AppendUint64(body, uint64(int64(arg)))
+		case int64:
+			body = append(body, BinaryArgInt64)
+			body = binary.BigEndian.AppendUint64(body, uint64(arg))
+		case uint32:
+			body = append(body, BinaryArgUint64)
+			body = binary.BigEndian.AppendUint64(body, uint64(arg))
+		case uint64:
+			body = append(body, BinaryArgUint64)
+			body = binary.BigEndian.AppendUint64(body, arg)
+		case float64:
+			body = append(body, BinaryArgFloat64)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(arg))
+		case bool:
+			body = append(body, BinaryArgBool)
+			if arg {
+				body = append(body, 1)
+			} else {
+				body = append(body, 0)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported argument type %T", arg)
+		}
+	}
+
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(body))), body...), nil
+}
+
+// frameDecoder reads the fields of a frame body. After the first read past the end, err is set and all
+// further reads return zero values.
+type frameDecoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (d *frameDecoder) next(n int) []byte {
+	if d.err != nil || n > len(d.data)-d.pos {
+		d.err = errors.New("protocol error: truncated frame")
+		return nil
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *frameDecoder) byte() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *frameDecoder) uint16() uint16 {
+	if b := d.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *frameDecoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *frameDecoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *frameDecoder) string16() string {
+	return string(d.next(int(d.uint16())))
+}
+
+func (d *frameDecoder) argument() (string, error) {
+	var arg string
+	switch argType := d.byte(); argType {
+	case BinaryArgString:
+		arg = string(d.next(int(d.uint32())))
+	case BinaryArgInt64:
+		arg = strconv.FormatInt(int64(d.uint64()), 10)
+	case BinaryArgUint64:
+		arg = strconv.FormatUint(d.uint64(), 10)
+	case BinaryArgFloat64:
+		arg = strconv.FormatFloat(math.Float64frombits(d.uint64()), 'g', -1, 64)
+	case BinaryArgBool:
+		arg = strconv.FormatBool(d.byte() != 0)
+	default:
+		if d.err == nil {
+			return "", fmt.Errorf("protocol error: unknown argument type %d", argType)
+		}
+	}
+	if d.err != nil {
+		return "", d.err
+	}
+	return arg, nil
+}
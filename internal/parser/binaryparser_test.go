@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestBinaryRequestRoundTrip(t *testing.T) {
+	frame, err := EncodeBinaryRequest(7, BinaryFlagConcurrent, "PUT", "key", int64(-42), uint64(42), 1.5, true, "line\nbreak")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := NewBinaryParser().ReadRequest(bufio.NewReader(bytes.NewReader(frame)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.RequestId != 7 || request.Flags != BinaryFlagConcurrent || request.Command.Operation != "PUT" {
+		t.Errorf("request = id %d, flags %d, operation %q, want 7, %d, PUT", request.RequestId, request.Flags, request.Command.Operation, BinaryFlagConcurrent)
+	}
+	want := []string{"key", "-42", "42", "1.5", "true", "line\nbreak"}
+	if !slices.Equal(request.Command.Args, want) {
+		t.Errorf("args = %q, want %q", request.Command.Args, want)
+	}
+}
+
+func TestReadBinaryFrame(t *testing.T) {
+	frame := func(length uint32, body string) []byte {
+		return append(binary.BigEndian.AppendUint32(nil, length), body...)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr error
+	}{
+		{"complete frame", frame(5, "hello"), "hello", nil},
+		{"empty frame", frame(0, ""), "", nil},
+		{"no frame", nil, "", io.EOF},
+		{"truncated header", []byte{0, 0}, "", io.ErrUnexpectedEOF},
+		{"truncated body", frame(10, "hello"), "", io.ErrUnexpectedEOF},
+		// The length alone must not allocate the frame, the body is read as it arrives
+		{"truncated body of a large frame", frame(MaxBinaryRequestLength, "hello"), "", io.ErrUnexpectedEOF},
+	}
+
+	for _, test := range tests {
+		body, err := ReadBinaryFrame(bytes.NewReader(test.data), MaxBinaryRequestLength)
+		if !errors.Is(err, test.wantErr) || string(body) != test.want {
+			t.Errorf("%s: ReadBinaryFrame = %q, %v, want %q, %v", test.name, body, err, test.want, test.wantErr)
+		}
+	}
+
+	if _, err := ReadBinaryFrame(bytes.NewReader(frame(MaxBinaryRequestLength+1, "")), MaxBinaryRequestLength); err == nil {
+		t.Error("ReadBinaryFrame accepted a frame over the limit")
+	}
+}
+
+func TestDecodeInvalidRequest(t *testing.T) {
+	body := binary.BigEndian.AppendUint32(nil, 9)
+	body = append(body, 0, 0, 3, 'G', 'E', 'T', 0, 1, 99)
+
+	request, err := NewBinaryParser().decodeRequest(body, nil)
+	if err == nil {
+		t.Fatal("decodeRequest accepted an unknown argument type")
+	}
+	// The request id is returned with the error, so the error can be answered
+	if request == nil || request.RequestId != 9 {
+		t.Errorf("request of the error = %+v, want id 9", request)
+	}
+}
@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"cmp"
 	"fmt"
 	"meteor/internal/common"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"unicode"
@@ -25,6 +28,13 @@ const (
 	TokenGreater
 	TokenGreaterEqual
 	TokenLike
+	TokenILike
+	TokenMatches
+	TokenIn
+	TokenBetween
+	TokenIs
+	TokenExists
+	TokenContains
 
 	// Logical operators
 	TokenAnd
@@ -34,6 +44,8 @@ const (
 	// Delimiters
 	TokenLeftParen
 	TokenRightParen
+	TokenComma
+	TokenStar
 
 	// Special
 	TokenEOF
@@ -105,20 +117,24 @@ func (l *Lexer) readString(quote rune) string {
 	return l.input[start:]
 }
 
-// readIdentifier reads an identifier (field name, operator, etc.)
+// readIdentifier reads an identifier (field name, operator, etc.). Unquoted LIKE patterns such as user_% and
+// field references with a JSON path such as $value.tags[0] are read as identifiers too.
 func (l *Lexer) readIdentifier() string {
 	start := l.pos - 1
 
-	for unicode.IsLetter(l.ch) || unicode.IsDigit(l.ch) || l.ch == '_' || l.ch == '$' {
+	for unicode.IsLetter(l.ch) || unicode.IsDigit(l.ch) || l.ch == '_' || l.ch == '$' || l.ch == '%' || l.ch == '.' || l.ch == '[' || l.ch == ']' {
 		l.readChar()
 	}
 
 	return l.input[start : l.pos-1]
 }
 
-// readNumber reads a numeric literal
+// readNumber reads a numeric literal, optionally negative
 func (l *Lexer) readNumber() string {
 	start := l.pos - 1
+	if l.ch == '-' {
+		l.readChar()
+	}
 
 	for unicode.IsDigit(l.ch) || l.ch == '.' {
 		l.readChar()
@@ -142,6 +158,12 @@ func (l *Lexer) NextToken() Token {
 	case ')':
 		l.readChar()
 		return Token{Type: TokenRightParen, Value: ")", Pos: pos}
+	case ',':
+		l.readChar()
+		return Token{Type: TokenComma, Value: ",", Pos: pos}
+	case '*':
+		l.readChar()
+		return Token{Type: TokenStar, Value: "*", Pos: pos}
 	case '\'', '"':
 		quote := l.ch
 		value := l.readString(quote)
@@ -178,7 +200,7 @@ func (l *Lexer) NextToken() Token {
 		l.readChar()
 		return Token{Type: TokenGreater, Value: ">", Pos: pos}
 	default:
-		if unicode.IsLetter(l.ch) || l.ch == '_' || l.ch == '$' {
+		if unicode.IsLetter(l.ch) || l.ch == '_' || l.ch == '$' || l.ch == '%' {
 			identifier := l.readIdentifier()
 
 			// Check for keywords
@@ -191,10 +213,24 @@ func (l *Lexer) NextToken() Token {
 				return Token{Type: TokenNot, Value: identifier, Pos: pos}
 			case "LIKE":
 				return Token{Type: TokenLike, Value: identifier, Pos: pos}
+			case "ILIKE":
+				return Token{Type: TokenILike, Value: identifier, Pos: pos}
+			case "MATCHES":
+				return Token{Type: TokenMatches, Value: identifier, Pos: pos}
+			case "IN":
+				return Token{Type: TokenIn, Value: identifier, Pos: pos}
+			case "BETWEEN":
+				return Token{Type: TokenBetween, Value: identifier, Pos: pos}
+			case "IS":
+				return Token{Type: TokenIs, Value: identifier, Pos: pos}
+			case "EXISTS":
+				return Token{Type: TokenExists, Value: identifier, Pos: pos}
+			case "CONTAINS":
+				return Token{Type: TokenContains, Value: identifier, Pos: pos}
 			default:
 				return Token{Type: TokenField, Value: identifier, Pos: pos}
 			}
-		} else if unicode.IsDigit(l.ch) {
+		} else if unicode.IsDigit(l.ch) || (l.ch == '-' && unicode.IsDigit(l.peekChar())) {
 			number := l.readNumber()
 			return Token{Type: TokenNumber, Value: number, Pos: pos}
 		}
@@ -209,6 +245,8 @@ func (l *Lexer) NextToken() Token {
 // Expression represents a parsed condition expression
 type Expression interface {
 	Evaluate(key string, value *common.V) bool
+	// String returns the expression in condition syntax, as shown by EXPLAIN
+	String() string
 }
 
 // BinaryExpression represents a binary operation
@@ -229,6 +267,13 @@ func (e *BinaryExpression) Evaluate(key string, value *common.V) bool {
 	}
 }
 
+func (e *BinaryExpression) String() string {
+	if e.Operator == TokenOr {
+		return "(" + e.Left.String() + " OR " + e.Right.String() + ")"
+	}
+	return e.Left.String() + " AND " + e.Right.String()
+}
+
 // UnaryExpression represents a unary operation (NOT)
 type UnaryExpression struct {
 	Operator TokenType
@@ -244,11 +289,53 @@ func (e *UnaryExpression) Evaluate(key string, value *common.V) bool {
 	}
 }
 
+func (e *UnaryExpression) String() string {
+	return "NOT (" + e.Operand.String() + ")"
+}
+
 // ComparisonExpression represents a comparison operation
 type ComparisonExpression struct {
 	Field    string
 	Operator TokenType
 	Value    string
+
+	// path is set for value fields that refer to a part of a JSON document, e.g. $value.user.age
+	path common.JsonPath
+
+	// The right hand side parsed once at parse time, so typed values are not re-parsed on every comparison
+	intValue   int64
+	isInt      bool
+	uintValue  uint64
+	isUint     bool
+	floatValue float64
+	isFloat    bool
+	boolValue  bool
+	isBool     bool
+}
+
+// newComparisonExpression creates a comparison and pre-parses its right hand side as int, float and bool
+func newComparisonExpression(field string, operator TokenType, value string) *ComparisonExpression {
+	e := &ComparisonExpression{
+		Field:    field,
+		Operator: operator,
+		Value:    value,
+		path:     valuePath(field),
+	}
+
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		e.intValue, e.isInt = n, true
+	}
+	if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+		e.uintValue, e.isUint = n, true
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		e.floatValue, e.isFloat = f, true
+	}
+	if b, err := strconv.ParseBool(value); err == nil {
+		e.boolValue, e.isBool = b, true
+	}
+
+	return e
 }
 
 func (e *ComparisonExpression) Evaluate(key string, value *common.V) bool {
@@ -257,14 +344,20 @@ func (e *ComparisonExpression) Evaluate(key string, value *common.V) bool {
 	}
 
 	var leftValue string
-	switch e.Field {
-	case "$key", "key", "key_name":
-		leftValue = key
-	case "$value", "value":
-		leftValue = string(value.Value)
-	default:
-		// TODO: Currently treating unknown fields as key comparisons which is wrong. Example: returns key a for (a=a) comparison. We need to throw error here.
+	if e.Field == "$key" {
 		leftValue = key
+	} else {
+		// Missing and null JSON members don't compare to anything
+		value = resolveValue(e.path, value)
+		if value == nil || (e.path != nil && value.Type == common.TypeNull) {
+			return false
+		}
+
+		// Typed values are compared on their stored type, everything else on the string representation
+		if value.Type.IsNumeric() || value.Type == common.TypeBool {
+			return e.evaluateTyped(value)
+		}
+		leftValue = value.Format()
 	}
 
 	rightValue := e.Value
@@ -274,53 +367,266 @@ func (e *ComparisonExpression) Evaluate(key string, value *common.V) bool {
 		return leftValue == rightValue
 	case TokenNotEqual:
 		return leftValue != rightValue
-	case TokenLess:
+	case TokenLess, TokenLessEqual, TokenGreater, TokenGreaterEqual:
 		// Try numeric comparison first
-		if leftNum, err := strconv.ParseFloat(leftValue, 64); err == nil {
-			if rightNum, err := strconv.ParseFloat(rightValue, 64); err == nil {
-				return leftNum < rightNum
+		if e.isFloat {
+			if leftNum, err := strconv.ParseFloat(leftValue, 64); err == nil {
+				return compareOrdered(e.Operator, leftNum, e.floatValue)
 			}
 		}
-		return leftValue < rightValue
-	case TokenLessEqual:
-		if leftNum, err := strconv.ParseFloat(leftValue, 64); err == nil {
-			if rightNum, err := strconv.ParseFloat(rightValue, 64); err == nil {
-				return leftNum <= rightNum
-			}
+		return compareOrdered(e.Operator, leftValue, rightValue)
+	default:
+		return false
+	}
+}
+
+func (e *ComparisonExpression) String() string {
+	if e.isFloat {
+		return e.Field + " " + operatorString(e.Operator) + " " + e.Value
+	}
+	return e.Field + " " + operatorString(e.Operator) + " " + quote(e.Value)
+}
+
+// evaluateTyped compares a numeric or bool value natively. If the right hand side can't be represented
+// in the value's type, only != matches.
+func (e *ComparisonExpression) evaluateTyped(value *common.V) bool {
+	switch {
+	case value.Type == common.TypeBool:
+		if !e.isBool || len(value.Value) != common.TypeBool.Size() {
+			return e.Operator == TokenNotEqual
 		}
-		return leftValue <= rightValue
-	case TokenGreater:
-		if leftNum, err := strconv.ParseFloat(leftValue, 64); err == nil {
-			if rightNum, err := strconv.ParseFloat(rightValue, 64); err == nil {
-				return leftNum > rightNum
-			}
+		leftBool := value.Value[0] != 0
+		switch e.Operator {
+		case TokenEqual:
+			return leftBool == e.boolValue
+		case TokenNotEqual:
+			return leftBool != e.boolValue
+		default:
+			return false
 		}
-		return leftValue > rightValue
-	case TokenGreaterEqual:
-		if leftNum, err := strconv.ParseFloat(leftValue, 64); err == nil {
-			if rightNum, err := strconv.ParseFloat(rightValue, 64); err == nil {
-				return leftNum >= rightNum
-			}
+
+	case value.Type.IsInteger() && (e.isInt || e.isUint):
+		order, ok := e.compareInteger(value)
+		if !ok {
+			return false
 		}
-		return leftValue >= rightValue
-	case TokenLike:
-		// Simple LIKE implementation with % wildcards
-		if strings.HasSuffix(rightValue, "%") && strings.HasPrefix(rightValue, "%") {
-			// %value% - contains
-			substring := strings.Trim(rightValue, "%")
-			return strings.Contains(leftValue, substring)
-		} else if strings.HasSuffix(rightValue, "%") {
-			// value% - prefix
-			prefix := strings.TrimSuffix(rightValue, "%")
-			return strings.HasPrefix(leftValue, prefix)
-		} else if strings.HasPrefix(rightValue, "%") {
-			// %value - suffix
-			suffix := strings.TrimPrefix(rightValue, "%")
-			return strings.HasSuffix(leftValue, suffix)
-		} else {
-			// No wildcards - exact match
-			return leftValue == rightValue
+		return compareOrdered(e.Operator, order, 0)
+
+	default:
+		if !e.isFloat {
+			return e.Operator == TokenNotEqual
+		}
+		leftFloat, err := value.Float64()
+		if err != nil {
+			return false
+		}
+		return compareOrdered(e.Operator, leftFloat, e.floatValue)
+	}
+}
+
+// compareInteger compares an integer value with an integer right hand side without going through float64, which
+// can't tell apart integers above 2^53. It returns -1, 0 or 1 like cmp.Compare.
+func (e *ComparisonExpression) compareInteger(value *common.V) (int, bool) {
+	if value.Type == common.TypeUint64 {
+		leftUint, ok := value.Native().(uint64)
+		if !ok {
+			return 0, false
+		}
+		if !e.isUint {
+			// Negative right hand side
+			return 1, true
+		}
+		return cmp.Compare(leftUint, e.uintValue), true
+	}
+
+	leftInt, err := value.Int64()
+	if err != nil {
+		return 0, false
+	}
+	if !e.isInt {
+		// Right hand side above the int64 range
+		return -1, true
+	}
+	return cmp.Compare(leftInt, e.intValue), true
+}
+
+// PatternExpression matches a field against a LIKE, ILIKE or MATCHES pattern, compiled once at parse time
+type PatternExpression struct {
+	Field    string
+	Operator TokenType
+	Pattern  string
+
+	path   common.JsonPath
+	regex  *regexp.Regexp
+	escape rune
+
+	// The literal start of a LIKE pattern, used by the planner. isExact is set for patterns without wildcards
+	// and isPrefixOnly for patterns that are the literal prefix followed by %.
+	literalPrefix string
+	isExact       bool
+	isPrefixOnly  bool
+}
+
+func (e *PatternExpression) String() string {
+	expr := e.Field + " " + operatorString(e.Operator) + " " + quote(e.Pattern)
+	if e.Operator != TokenMatches && e.escape != '\\' {
+		expr += " ESCAPE " + quote(string(e.escape))
+	}
+	return expr
+}
+
+func (e *PatternExpression) Evaluate(key string, value *common.V) bool {
+	if value == nil || value.Type == common.TypeTombstone {
+		return false
+	}
+
+	if e.Field == "$key" {
+		return e.regex.MatchString(key)
+	}
+	value = resolveValue(e.path, value)
+	if value == nil || (e.path != nil && value.Type == common.TypeNull) {
+		return false
+	}
+	return e.regex.MatchString(value.Format())
+}
+
+// ContainsExpression checks if a JSON array has an element, a JSON object has a member or a string has a substring
+type ContainsExpression struct {
+	Field string
+	Value string
+
+	path common.JsonPath
+}
+
+func (e *ContainsExpression) String() string {
+	return e.Field + " CONTAINS " + quote(e.Value)
+}
+
+func (e *ContainsExpression) Evaluate(key string, value *common.V) bool {
+	if value == nil || value.Type == common.TypeTombstone {
+		return false
+	}
+
+	if e.Field == "$key" {
+		return strings.Contains(key, e.Value)
+	}
+	value = resolveValue(e.path, value)
+	if value == nil || value.Type == common.TypeNull {
+		return false
+	}
+
+	if value.Type == common.TypeJson {
+		if elements := value.JsonElements(); elements != nil {
+			return slices.ContainsFunc(elements, func(element *common.V) bool {
+				return element.Type != common.TypeNull && element.Format() == e.Value
+			})
 		}
+		return value.HasJsonMember(e.Value)
+	}
+	return strings.Contains(value.Format(), e.Value)
+}
+
+// presenceCheck is the state of a record checked by EXISTS, IS TOMBSTONE and IS NULL
+type presenceCheck int
+
+const (
+	presenceExists presenceCheck = iota
+	presenceTombstone
+	presenceNull
+)
+
+// PresenceExpression checks whether a record exists, is deleted or holds a null value.
+// For JSON paths it checks whether the path exists, and missing paths count as null.
+type PresenceExpression struct {
+	Field string
+	Check presenceCheck
+
+	path common.JsonPath
+}
+
+func (e *PresenceExpression) Evaluate(key string, value *common.V) bool {
+	if value == nil {
+		return false
+	}
+
+	switch e.Check {
+	case presenceExists:
+		return value.Type != common.TypeTombstone && resolveValue(e.path, value) != nil
+	case presenceTombstone:
+		return value.Type == common.TypeTombstone
+	case presenceNull:
+		if e.Field == "$key" {
+			return false
+		}
+		value = resolveValue(e.path, value)
+		return value == nil || value.Type == common.TypeNull
+	default:
+		return false
+	}
+}
+
+func (e *PresenceExpression) String() string {
+	switch e.Check {
+	case presenceTombstone:
+		return e.Field + " IS TOMBSTONE"
+	case presenceNull:
+		return e.Field + " IS NULL"
+	default:
+		return e.Field + " EXISTS"
+	}
+}
+
+// resolveValue returns the part of the value selected by a field's JSON path, or nil if the path doesn't exist.
+// Fields without a path refer to the whole value.
+func resolveValue(path common.JsonPath, value *common.V) *common.V {
+	if path == nil {
+		return value
+	}
+	return value.JsonPathValue(path)
+}
+
+// matchesTombstones checks if the expression asks about deleted records with EXISTS or IS TOMBSTONE
+func matchesTombstones(expr Expression) bool {
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		return matchesTombstones(e.Left) || matchesTombstones(e.Right)
+	case *UnaryExpression:
+		return matchesTombstones(e.Operand)
+	case *PresenceExpression:
+		return e.Check == presenceExists || e.Check == presenceTombstone
+	default:
+		return false
+	}
+}
+
+// newFilterFunc turns an expression into a filter function. Deleted records are only evaluated by expressions
+// that ask about them, so that e.g. NOT $value = 5 doesn't match deleted keys.
+func newFilterFunc(expr Expression) func(string, *common.V) bool {
+	if matchesTombstones(expr) {
+		return func(key string, value *common.V) bool {
+			return value != nil && expr.Evaluate(key, value)
+		}
+	}
+	return func(key string, value *common.V) bool {
+		return value != nil && value.Type != common.TypeTombstone && expr.Evaluate(key, value)
+	}
+}
+
+// compareOrdered applies a comparison operator to two ordered values
+func compareOrdered[T cmp.Ordered](operator TokenType, left, right T) bool {
+	switch operator {
+	case TokenEqual:
+		return left == right
+	case TokenNotEqual:
+		return left != right
+	case TokenLess:
+		return left < right
+	case TokenLessEqual:
+		return left <= right
+	case TokenGreater:
+		return left > right
+	case TokenGreaterEqual:
+		return left >= right
 	default:
 		return false
 	}
@@ -355,6 +661,16 @@ func (p *ConditionParser) nextToken() {
 
 // ParseExpression parses a condition expression and returns a filter function
 func (p *ConditionParser) ParseExpression() (func(string, *common.V) bool, error) {
+	expr, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return newFilterFunc(expr), nil
+}
+
+// Parse parses a condition expression into its expression tree
+func (p *ConditionParser) Parse() (Expression, error) {
 	expr, err := p.parseOrExpression()
 	if err != nil {
 		return nil, err
@@ -364,9 +680,7 @@ func (p *ConditionParser) ParseExpression() (func(string, *common.V) bool, error
 		return nil, fmt.Errorf("unexpected token: %s at position %d", p.currentToken.Value, p.currentToken.Pos)
 	}
 
-	return func(key string, value *common.V) bool {
-		return expr.Evaluate(key, value)
-	}, nil
+	return expr, nil
 }
 
 // parseOrExpression parses OR expressions (lowest precedence)
@@ -459,13 +773,50 @@ func (p *ConditionParser) parseComparisonExpression() (Expression, error) {
 	}
 
 	// Parse field operator value or value operator field
-	if p.currentToken.Type != TokenField && p.currentToken.Type != TokenString && p.currentToken.Type != TokenNumber {
+	if !isOperandToken(p.currentToken) {
 		return nil, fmt.Errorf("expected field, string, or number at position %d", p.currentToken.Pos)
 	}
 
 	leftToken := p.currentToken
 	p.nextToken()
 
+	// NOT IN, NOT BETWEEN, NOT LIKE, NOT ILIKE, NOT MATCHES and NOT EXISTS negate the predicate
+	negate := false
+	if p.currentToken.Type == TokenNot && isNegatableOperator(p.peekToken.Type) {
+		negate = true
+		p.nextToken()
+	}
+
+	var expr Expression
+	var err error
+	switch p.currentToken.Type {
+	case TokenIn:
+		expr, err = p.parseInExpression(leftToken)
+	case TokenBetween:
+		expr, err = p.parseBetweenExpression(leftToken)
+	case TokenLike, TokenILike, TokenMatches:
+		expr, err = p.parsePatternExpression(leftToken)
+	case TokenIs:
+		expr, err = p.parseIsExpression(leftToken)
+	case TokenExists:
+		expr, err = p.parseExistsExpression(leftToken)
+	case TokenContains:
+		expr, err = p.parseContainsExpression(leftToken)
+	default:
+		expr, err = p.parseBinaryComparison(leftToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if negate {
+		expr = &UnaryExpression{Operator: TokenNot, Operand: expr}
+	}
+	return expr, nil
+}
+
+// parseBinaryComparison parses the operator and right hand side of field op value or value op field
+func (p *ConditionParser) parseBinaryComparison(leftToken Token) (Expression, error) {
 	if !isComparisonOperator(p.currentToken.Type) {
 		return nil, fmt.Errorf("expected comparison operator at position %d", p.currentToken.Pos)
 	}
@@ -473,57 +824,314 @@ func (p *ConditionParser) parseComparisonExpression() (Expression, error) {
 	operator := p.currentToken.Type
 	p.nextToken()
 
-	if p.currentToken.Type != TokenField && p.currentToken.Type != TokenString && p.currentToken.Type != TokenNumber {
+	if !isOperandToken(p.currentToken) {
 		return nil, fmt.Errorf("expected field, string, or number at position %d", p.currentToken.Pos)
 	}
 
 	rightToken := p.currentToken
 	p.nextToken()
 
-	// Determine which is the field and which is the value
-	var field, value string
-
-	// Handle both directions: field op value AND value op field
-	if isFieldToken(leftToken) && !isFieldToken(rightToken) {
-		// Normal: field op value
-		field = leftToken.Value
-		value = rightToken.Value
-	} else if !isFieldToken(leftToken) && isFieldToken(rightToken) {
-		// Reverse: value op field
-		field = rightToken.Value
-		value = leftToken.Value
+	field, value, isReversed, err := resolveFieldAndValue(leftToken, rightToken)
+	if err != nil {
+		return nil, err
+	}
+	if isReversed {
 		// Reverse the operator for correct evaluation
 		operator = reverseOperator(operator)
-	} else if isFieldToken(leftToken) && isFieldToken(rightToken) {
-		// Both are fields - treat left as field, right as value
-		field = leftToken.Value
-		value = rightToken.Value
+	}
+
+	return newComparisonExpression(field, operator, value), nil
+}
+
+// parseInExpression parses field IN (value, value, ...) as an OR of equality comparisons
+func (p *ConditionParser) parseInExpression(fieldToken Token) (Expression, error) {
+	field, err := fieldName(fieldToken)
+	if err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	if p.currentToken.Type != TokenLeftParen {
+		return nil, fmt.Errorf("expected '(' after IN at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	var expr Expression
+	for {
+		if !isOperandToken(p.currentToken) {
+			return nil, fmt.Errorf("expected string or number at position %d", p.currentToken.Pos)
+		}
+		comparison := newComparisonExpression(field, TokenEqual, p.currentToken.Value)
+		if expr == nil {
+			expr = comparison
+		} else {
+			expr = &BinaryExpression{Left: expr, Operator: TokenOr, Right: comparison}
+		}
+		p.nextToken()
+
+		if p.currentToken.Type != TokenComma {
+			break
+		}
+		p.nextToken()
+	}
+
+	if p.currentToken.Type != TokenRightParen {
+		return nil, fmt.Errorf("expected ')' at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	return expr, nil
+}
+
+// parseBetweenExpression parses field BETWEEN low AND high, both bounds inclusive
+func (p *ConditionParser) parseBetweenExpression(fieldToken Token) (Expression, error) {
+	field, err := fieldName(fieldToken)
+	if err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	if !isOperandToken(p.currentToken) {
+		return nil, fmt.Errorf("expected string or number at position %d", p.currentToken.Pos)
+	}
+	low := p.currentToken.Value
+	p.nextToken()
+
+	if p.currentToken.Type != TokenAnd {
+		return nil, fmt.Errorf("expected AND in BETWEEN at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	if !isOperandToken(p.currentToken) {
+		return nil, fmt.Errorf("expected string or number at position %d", p.currentToken.Pos)
+	}
+	high := p.currentToken.Value
+	p.nextToken()
+
+	return &BinaryExpression{
+		Left:     newComparisonExpression(field, TokenGreaterEqual, low),
+		Operator: TokenAnd,
+		Right:    newComparisonExpression(field, TokenLessEqual, high),
+	}, nil
+}
+
+// parsePatternExpression parses field LIKE|ILIKE 'pattern' [ESCAPE 'c'] and field MATCHES 'regex'
+func (p *ConditionParser) parsePatternExpression(leftToken Token) (Expression, error) {
+	operator := p.currentToken.Type
+	p.nextToken()
+
+	if !isOperandToken(p.currentToken) {
+		return nil, fmt.Errorf("expected pattern at position %d", p.currentToken.Pos)
+	}
+	rightToken := p.currentToken
+	p.nextToken()
+
+	field, pattern, _, err := resolveFieldAndValue(leftToken, rightToken)
+	if err != nil {
+		return nil, err
+	}
+
+	escape := '\\'
+	if operator != TokenMatches && isKeyword(p.currentToken, "ESCAPE") {
+		p.nextToken()
+		escapeRunes := []rune(p.currentToken.Value)
+		if !isOperandToken(p.currentToken) || len(escapeRunes) != 1 {
+			return nil, fmt.Errorf("ESCAPE requires a single character at position %d", p.currentToken.Pos)
+		}
+		escape = escapeRunes[0]
+		p.nextToken()
+	}
+
+	expr := &PatternExpression{Field: field, Operator: operator, Pattern: pattern, path: valuePath(field), escape: escape}
+	if operator == TokenMatches {
+		expr.regex, err = regexp.Compile(pattern)
 	} else {
+		expr.regex, err = likeToRegexp(pattern, escape, operator == TokenILike)
+		if operator == TokenLike {
+			expr.literalPrefix, expr.isExact, expr.isPrefixOnly = likeLiteralPrefix(pattern, escape)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+
+	return expr, nil
+}
+
+// parseIsExpression parses field IS [NOT] NULL and field IS [NOT] TOMBSTONE
+func (p *ConditionParser) parseIsExpression(fieldToken Token) (Expression, error) {
+	field, err := fieldName(fieldToken)
+	if err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	negate := false
+	if p.currentToken.Type == TokenNot {
+		negate = true
+		p.nextToken()
+	}
+
+	var check presenceCheck
+	switch {
+	case isKeyword(p.currentToken, "NULL"):
+		check = presenceNull
+	case isKeyword(p.currentToken, "TOMBSTONE"):
+		check = presenceTombstone
+	default:
+		return nil, fmt.Errorf("expected NULL or TOMBSTONE after IS at position %d", p.currentToken.Pos)
+	}
+	p.nextToken()
+
+	var expr Expression = &PresenceExpression{Field: field, Check: check, path: valuePath(field)}
+	if negate {
+		expr = &UnaryExpression{Operator: TokenNot, Operand: expr}
+	}
+	return expr, nil
+}
+
+// parseExistsExpression parses field EXISTS
+func (p *ConditionParser) parseExistsExpression(fieldToken Token) (Expression, error) {
+	field, err := fieldName(fieldToken)
+	if err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	return &PresenceExpression{Field: field, Check: presenceExists, path: valuePath(field)}, nil
+}
+
+// parseContainsExpression parses field CONTAINS value
+func (p *ConditionParser) parseContainsExpression(fieldToken Token) (Expression, error) {
+	field, err := fieldName(fieldToken)
+	if err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	if !isOperandToken(p.currentToken) {
+		return nil, fmt.Errorf("expected string or number after CONTAINS at position %d", p.currentToken.Pos)
+	}
+	value := p.currentToken.Value
+	p.nextToken()
+
+	return &ContainsExpression{Field: field, Value: value, path: valuePath(field)}, nil
+}
+
+// resolveFieldAndValue determines which of the two operands is the field. If both are fields, the left one is
+// the field and the right one a value. It reports whether the field is on the right hand side.
+func resolveFieldAndValue(leftToken, rightToken Token) (string, string, bool, error) {
+	switch {
+	case isFieldToken(leftToken):
+		// Normal: field op value
+		field, err := fieldName(leftToken)
+		return field, rightToken.Value, false, err
+	case isFieldToken(rightToken):
+		// Reverse: value op field
+		field, err := fieldName(rightToken)
+		return field, leftToken.Value, true, err
+	case leftToken.Type == TokenField:
+		return "", "", false, fmt.Errorf("unknown field %s at position %d, expected $key, $value or $value.<json path>", leftToken.Value, leftToken.Pos)
+	default:
 		// Both are values - invalid
-		return nil, fmt.Errorf("comparison requires at least one field reference")
+		return "", "", false, fmt.Errorf("comparison requires at least one field reference")
 	}
+}
 
-	return &ComparisonExpression{
-		Field:    field,
-		Operator: operator,
-		Value:    value,
-	}, nil
+// fieldName returns the canonical name of a field token and rejects unknown fields
+func fieldName(token Token) (string, error) {
+	field := normalizeField(token.Value)
+	if !isFieldToken(token) || field == "" {
+		return "", fmt.Errorf("unknown field %s at position %d, expected $key, $value or $value.<json path>", token.Value, token.Pos)
+	}
+	return field, nil
+}
+
+// likeToRegexp translates a SQL LIKE pattern to an anchored regular expression.
+// % matches any sequence of characters, _ matches a single character and the escape character makes the next one literal.
+func likeToRegexp(pattern string, escape rune, caseInsensitive bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+	if caseInsensitive {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("(?s)^")
+
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == escape:
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("pattern ends with escape character")
+	}
+
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// likeLiteralPrefix returns the part of a LIKE pattern before the first wildcard. It reports whether the pattern
+// has no wildcards and whether the rest of the pattern only consists of %.
+func likeLiteralPrefix(pattern string, escape rune) (string, bool, bool) {
+	var prefix strings.Builder
+	escaped := false
+	for i, r := range pattern {
+		switch {
+		case escaped:
+			prefix.WriteRune(r)
+			escaped = false
+		case r == escape:
+			escaped = true
+		case r == '%' || r == '_':
+			rest := pattern[i:]
+			return prefix.String(), false, strings.Trim(rest, "%") == ""
+		default:
+			prefix.WriteRune(r)
+		}
+	}
+	return prefix.String(), true, false
+}
+
+// isOperandToken checks if the token can be a field or a value
+func isOperandToken(token Token) bool {
+	return token.Type == TokenField || token.Type == TokenString || token.Type == TokenNumber
 }
 
 // isComparisonOperator checks if the token is a comparison operator
 func isComparisonOperator(tokenType TokenType) bool {
 	switch tokenType {
-	case TokenEqual, TokenNotEqual, TokenLess, TokenLessEqual, TokenGreater, TokenGreaterEqual, TokenLike:
+	case TokenEqual, TokenNotEqual, TokenLess, TokenLessEqual, TokenGreater, TokenGreaterEqual:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNegatableOperator checks if the operator can be preceded by NOT
+func isNegatableOperator(tokenType TokenType) bool {
+	switch tokenType {
+	case TokenIn, TokenBetween, TokenLike, TokenILike, TokenMatches, TokenExists, TokenContains:
 		return true
 	default:
 		return false
 	}
 }
 
-// isFieldToken checks if the token represents a field
+// isFieldToken checks if the token represents a field. Unquoted words that aren't field names are values,
+// except words starting with $, which are rejected as unknown fields.
 func isFieldToken(token Token) bool {
 	if token.Type == TokenField {
-		return true
+		return normalizeField(token.Value) != "" || strings.HasPrefix(token.Value, "$")
 	}
 	// Strings can also be field names if they start with $
 	if token.Type == TokenString && strings.HasPrefix(token.Value, "$") {
@@ -532,6 +1140,40 @@ func isFieldToken(token Token) bool {
 	return false
 }
 
+// operatorString returns the condition syntax of an operator
+func operatorString(op TokenType) string {
+	switch op {
+	case TokenEqual:
+		return "="
+	case TokenNotEqual:
+		return "!="
+	case TokenLess:
+		return "<"
+	case TokenLessEqual:
+		return "<="
+	case TokenGreater:
+		return ">"
+	case TokenGreaterEqual:
+		return ">="
+	case TokenLike:
+		return "LIKE"
+	case TokenILike:
+		return "ILIKE"
+	case TokenMatches:
+		return "MATCHES"
+	default:
+		return "?"
+	}
+}
+
+// quote quotes a value for display, choosing the quote character that doesn't occur in the value
+func quote(value string) string {
+	if strings.ContainsRune(value, '\'') {
+		return "\"" + value + "\""
+	}
+	return "'" + value + "'"
+}
+
 // reverseOperator reverses comparison operators for reverse expressions
 func reverseOperator(op TokenType) TokenType {
 	switch op {
@@ -544,6 +1186,6 @@ func reverseOperator(op TokenType) TokenType {
 	case TokenGreaterEqual:
 		return TokenLessEqual
 	default:
-		return op // Equal, NotEqual, Like remain the same
+		return op // Equal and NotEqual remain the same
 	}
 }
@@ -0,0 +1,379 @@
+package parser
+
+import (
+	"fmt"
+	"meteor/internal/common"
+	"strconv"
+	"strings"
+)
+
+// MaxKey is greater than every valid UTF-8 key. It is the end of key ranges that are unbounded above,
+// the same upper bound range tombstones use for prefixes.
+const MaxKey = "\xff"
+
+// ScanAccess is the way a scan plan reads its candidate keys
+type ScanAccess int
+
+const (
+	// AccessFull reads every key
+	AccessFull ScanAccess = iota
+	// AccessPrefix reads the keys starting with the plan's prefix
+	AccessPrefix
+	// AccessRange reads the keys in the plan's inclusive key range
+	AccessRange
+	// AccessNone reads nothing because the key conditions contradict each other
+	AccessNone
+)
+
+// ScanPlan describes how a condition is executed: the candidate keys are read by prefix or range where the
+// condition bounds the key, and the residual part of the condition is applied to them afterwards.
+type ScanPlan struct {
+	Access ScanAccess
+	Prefix string
+	// StartKey and EndKey are the inclusive bounds of a prefix or range access. An unbounded end is MaxKey.
+	StartKey string
+	EndKey   string
+	// Residual is the part of the condition not implied by the key bounds, nil if nothing is left to check
+	Residual Expression
+	// Filter applies the residual condition to the candidate records
+	Filter func(string, *common.V) bool
+	// Index is set if the candidate keys are looked up in a secondary index by IndexConditions. The keys found
+	// are limited to the key bounds, and the access is used instead if the index can't be read.
+	Index           *common.IndexDefinition
+	IndexConditions []common.IndexCondition
+}
+
+// PlanScan parses a SCAN or COUNT condition and plans its execution using the given secondary indexes.
+// The condition * selects all records.
+func PlanScan(condition string, indexes []*common.IndexDefinition) (*ScanPlan, error) {
+	if strings.TrimSpace(condition) == "*" {
+		return &ScanPlan{Access: AccessFull, EndKey: MaxKey, Filter: existingRecords}, nil
+	}
+
+	expr, err := NewConditionParser(condition).Parse()
+	if err != nil {
+		return nil, err
+	}
+	plan := planExpression(expr)
+	plan.chooseIndex(expr, indexes)
+	return plan, nil
+}
+
+func planExpression(expr Expression) *ScanPlan {
+	bounds := keyBoundsOf(expr)
+	plan := &ScanPlan{StartKey: bounds.start, EndKey: bounds.end, Residual: expr}
+
+	switch {
+	case bounds.isEmpty():
+		plan.Access = AccessNone
+	case bounds.isPrefix:
+		plan.Access = AccessPrefix
+		plan.Prefix = bounds.prefix
+	case bounds.isUnbounded():
+		plan.Access = AccessFull
+	default:
+		plan.Access = AccessRange
+	}
+
+	// Conditions on deleted records are kept whole, since key comparisons never match deleted records
+	if plan.Access != AccessFull && !matchesTombstones(expr) {
+		plan.Residual = residualExpression(expr)
+	}
+
+	switch {
+	case plan.Access == AccessNone:
+		plan.Filter = func(string, *common.V) bool { return false }
+	case plan.Residual == nil:
+		plan.Filter = existingRecords
+	default:
+		plan.Filter = newFilterFunc(plan.Residual)
+	}
+	return plan
+}
+
+// existingRecords is the filter of conditions without a residual, which select all records that aren't deleted
+func existingRecords(key string, value *common.V) bool {
+	return value != nil && value.Type != common.TypeTombstone
+}
+
+// chooseIndex picks the index with conditions on its field among the conjuncts of the condition. Equality
+// conditions are preferred over comparisons, and indexes over narrower prefixes on a tie. Indexes are not used
+// when the condition selects a single key, or if their prefix doesn't contain every key the condition can match.
+func (p *ScanPlan) chooseIndex(expr Expression, indexes []*common.IndexDefinition) {
+	if p.Access == AccessNone || (p.Access == AccessRange && p.StartKey == p.EndKey) {
+		return
+	}
+
+	bestScore := 0
+	for _, index := range indexes {
+		if index.Prefix != "" && (p.StartKey < index.Prefix || p.EndKey > index.Prefix+MaxKey) {
+			continue
+		}
+
+		conditions := indexConditionsOf(expr, canonicalField(index.Field))
+		if len(conditions) == 0 {
+			continue
+		}
+		score := 1
+		for _, condition := range conditions {
+			if condition.Operator == "=" {
+				score = 2
+			}
+		}
+		if score > bestScore || (score == bestScore && len(index.Prefix) > len(p.Index.Prefix)) {
+			p.Index, p.IndexConditions, bestScore = index, conditions, score
+		}
+	}
+}
+
+// indexConditionsOf collects the conjuncts of the condition that compare the field, and the ORs of equalities
+// on it such as IN lists. Comparisons with != can't use an index.
+func indexConditionsOf(expr Expression, field string) []common.IndexCondition {
+	var conditions []common.IndexCondition
+	for _, conjunct := range conjuncts(expr) {
+		if values, ok := equalityValues(conjunct, field); ok {
+			conditions = append(conditions, common.IndexCondition{Operator: "=", Values: values})
+			continue
+		}
+		if e, ok := conjunct.(*ComparisonExpression); ok && canonicalField(e.Field) == field {
+			switch e.Operator {
+			case TokenLess, TokenLessEqual, TokenGreater, TokenGreaterEqual:
+				conditions = append(conditions, common.IndexCondition{Operator: operatorString(e.Operator), Values: []string{e.Value}})
+			}
+		}
+	}
+	return conditions
+}
+
+// equalityValues returns the values of an equality on the field, or of an OR of such equalities
+func equalityValues(expr Expression, field string) ([]string, bool) {
+	switch e := expr.(type) {
+	case *ComparisonExpression:
+		if canonicalField(e.Field) == field && e.Operator == TokenEqual {
+			return []string{e.Value}, true
+		}
+	case *BinaryExpression:
+		if e.Operator != TokenOr {
+			return nil, false
+		}
+		left, ok := equalityValues(e.Left, field)
+		if !ok {
+			return nil, false
+		}
+		right, ok := equalityValues(e.Right, field)
+		if !ok {
+			return nil, false
+		}
+		return append(left, right...), true
+	}
+	return nil, false
+}
+
+// ParseIndexField validates the field of a secondary index and returns its canonical form, e.g. $value.email
+// for value['email']. Only the value and parts of JSON values can be indexed.
+func ParseIndexField(field string) (string, error) {
+	normalized := normalizeField(field)
+	if normalized == "" || normalized == "$key" {
+		return "", fmt.Errorf("invalid index field %s, expected $value or $value.<json path>", field)
+	}
+	return canonicalField(normalized), nil
+}
+
+// canonicalField writes the JSON path of a normalized value field in dot notation, so that fields referring
+// to the same part of a value compare equal
+func canonicalField(field string) string {
+	if path := valuePath(field); path != nil {
+		return "$value" + strings.TrimPrefix(path.String(), "$")
+	}
+	return field
+}
+
+// String describes the plan, e.g. PREFIX SCAN 'user:' FILTER $value > 30
+func (p *ScanPlan) String() string {
+	if p.Access == AccessNone || p.Residual == nil {
+		return p.AccessString()
+	}
+	return p.AccessString() + " FILTER " + p.Residual.String()
+}
+
+// AccessString describes how the candidate keys are read, e.g. RANGE SCAN FROM 'a' TO 'b'
+// or INDEX SCAN email_idx ($value.email = 'a@b.c')
+func (p *ScanPlan) AccessString() string {
+	if p.Index != nil {
+		conditions := make([]string, len(p.IndexConditions))
+		for i, condition := range p.IndexConditions {
+			conditions[i] = p.Index.Field + " " + indexConditionString(condition)
+		}
+		return "INDEX SCAN " + p.Index.Name + " (" + strings.Join(conditions, " AND ") + ")"
+	}
+
+	switch p.Access {
+	case AccessNone:
+		return "EMPTY"
+	case AccessPrefix:
+		return "PREFIX SCAN " + quote(p.Prefix)
+	case AccessRange:
+		return "RANGE SCAN " + p.rangeString()
+	default:
+		return "FULL SCAN"
+	}
+}
+
+func indexConditionString(condition common.IndexCondition) string {
+	values := make([]string, len(condition.Values))
+	for i, value := range condition.Values {
+		// Numbers are shown unquoted, as in comparisons
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			values[i] = value
+		} else {
+			values[i] = quote(value)
+		}
+	}
+	if condition.Operator == "=" && len(values) > 1 {
+		return "IN (" + strings.Join(values, ", ") + ")"
+	}
+	return condition.Operator + " " + values[0]
+}
+
+func (p *ScanPlan) rangeString() string {
+	var parts []string
+	if p.StartKey != "" {
+		parts = append(parts, "FROM "+quote(p.StartKey))
+	}
+	if p.EndKey != MaxKey {
+		parts = append(parts, "TO "+quote(p.EndKey))
+	}
+	if len(parts) == 0 {
+		return "ALL"
+	}
+	return strings.Join(parts, " ")
+}
+
+// keyBounds is an inclusive key range that contains every key a condition can match
+type keyBounds struct {
+	start string
+	end   string
+	// prefix is set if the bounds are exactly the keys with the prefix
+	prefix   string
+	isPrefix bool
+}
+
+var unboundedKeys = keyBounds{start: "", end: MaxKey}
+
+func prefixBounds(prefix string) keyBounds {
+	if prefix == "" {
+		return unboundedKeys
+	}
+	return keyBounds{start: prefix, end: prefix + MaxKey, prefix: prefix, isPrefix: true}
+}
+
+func (b keyBounds) isEmpty() bool {
+	return b.start > b.end
+}
+
+func (b keyBounds) isUnbounded() bool {
+	return b.start == "" && b.end == MaxKey
+}
+
+// intersect returns the bounds of keys matching both conditions
+func (b keyBounds) intersect(other keyBounds) keyBounds {
+	result := keyBounds{start: max(b.start, other.start), end: min(b.end, other.end)}
+	// Keep the prefix form if one side is contained in the other
+	for _, side := range []keyBounds{b, other} {
+		if side.isPrefix && result.start == side.start && result.end == side.end {
+			return side
+		}
+	}
+	return result
+}
+
+// union returns the bounds of keys matching either condition
+func (b keyBounds) union(other keyBounds) keyBounds {
+	result := keyBounds{start: min(b.start, other.start), end: max(b.end, other.end)}
+	for _, side := range []keyBounds{b, other} {
+		if side.isPrefix && result.start == side.start && result.end == side.end {
+			return side
+		}
+	}
+	return result
+}
+
+// keyBoundsOf extracts the key range a condition is limited to. Conditions that don't restrict the key are unbounded.
+func keyBoundsOf(expr Expression) keyBounds {
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		if e.Operator == TokenAnd {
+			return keyBoundsOf(e.Left).intersect(keyBoundsOf(e.Right))
+		}
+		return keyBoundsOf(e.Left).union(keyBoundsOf(e.Right))
+
+	case *ComparisonExpression:
+		if e.Field != "$key" {
+			return unboundedKeys
+		}
+		switch e.Operator {
+		case TokenEqual:
+			return keyBounds{start: e.Value, end: e.Value}
+		case TokenGreater, TokenGreaterEqual:
+			// Numbers are compared numerically, which doesn't follow the key order
+			if !e.isFloat {
+				return keyBounds{start: e.Value, end: MaxKey}
+			}
+		case TokenLess, TokenLessEqual:
+			if !e.isFloat {
+				return keyBounds{start: "", end: e.Value}
+			}
+		}
+		return unboundedKeys
+
+	case *PatternExpression:
+		if e.Field != "$key" || e.Operator != TokenLike {
+			return unboundedKeys
+		}
+		if e.isExact {
+			return keyBounds{start: e.literalPrefix, end: e.literalPrefix}
+		}
+		return prefixBounds(e.literalPrefix)
+
+	default:
+		return unboundedKeys
+	}
+}
+
+// residualExpression removes the conjuncts of the condition that are implied by its key bounds. It returns nil
+// if all of them are. Only inclusive bounds, equality and prefix patterns are exactly represented by the bounds.
+func residualExpression(expr Expression) Expression {
+	var residual Expression
+	for _, conjunct := range conjuncts(expr) {
+		if isImpliedByKeyBounds(conjunct) {
+			continue
+		}
+		if residual == nil {
+			residual = conjunct
+		} else {
+			residual = &BinaryExpression{Left: residual, Operator: TokenAnd, Right: conjunct}
+		}
+	}
+	return residual
+}
+
+func conjuncts(expr Expression) []Expression {
+	if e, ok := expr.(*BinaryExpression); ok && e.Operator == TokenAnd {
+		return append(conjuncts(e.Left), conjuncts(e.Right)...)
+	}
+	return []Expression{expr}
+}
+
+func isImpliedByKeyBounds(expr Expression) bool {
+	switch e := expr.(type) {
+	case *ComparisonExpression:
+		if e.Field != "$key" {
+			return false
+		}
+		return e.Operator == TokenEqual || (!e.isFloat && (e.Operator == TokenGreaterEqual || e.Operator == TokenLessEqual))
+	case *PatternExpression:
+		return e.Field == "$key" && e.Operator == TokenLike && e.literalPrefix != "" && (e.isExact || e.isPrefixOnly)
+	default:
+		return false
+	}
+}
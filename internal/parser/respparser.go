@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"meteor/internal/common"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Limits of a single request, the same as Redis uses by default
+	maxRespBulkLength   = 512 * 1024 * 1024
+	maxRespArrayLength  = 1024 * 1024
+	maxRespInlineLength = 64 * 1024
+)
+
+// RespParser parses requests of the Redis serialization protocol (RESP2 and RESP3). Clients send commands as
+// arrays of bulk strings, e.g. *2\r\n$3\r\nGET\r\n$3\r\nkey\r\n, or as inline commands such as PING\r\n.
+// Requests are the same in both protocol versions, only the replies differ.
+type RespParser struct{}
+
+func NewRespParser() *RespParser {
+	return &RespParser{}
+}
+
+// Parse parses a single complete request
+func (p *RespParser) Parse(data []byte, conn *net.Conn) (*common.Command, error) {
+	return p.ReadCommand(bufio.NewReader(bytes.NewReader(data)), conn)
+}
+
+// ReadCommand reads the next request from a connection. Requests may arrive in several reads or several
+// at once when the client pipelines them, so the reader is kept for the lifetime of the connection.
+// Empty requests are skipped. io.EOF is returned when the connection is closed between requests.
+func (p *RespParser) ReadCommand(reader *bufio.Reader, conn *net.Conn) (*common.Command, error) {
+	for {
+		parts, err := readRespRequest(reader)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) == 0 {
+			continue
+		}
+
+		return &common.Command{
+			Operation:  parts[0],
+			Args:       parts[1:],
+			Connection: conn,
+		}, nil
+	}
+}
+
+func readRespRequest(reader *bufio.Reader) ([]string, error) {
+	prefix, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if prefix[0] != '*' {
+		line, err := readRespLine(reader, maxRespInlineLength)
+		if err != nil {
+			return nil, err
+		}
+		// Inline commands split like the text protocol, so quoted arguments may contain spaces
+		return parseQuotedArgs(line), nil
+	}
+
+	line, err := readRespLine(reader, maxRespInlineLength)
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count > maxRespArrayLength {
+		return nil, fmt.Errorf("protocol error: invalid multibulk length %q", line[1:])
+	}
+
+	// A null or empty array is an empty request
+	parts := make([]string, 0, max(count, 0))
+	for range count {
+		part, err := readRespBulkString(reader)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+func readRespBulkString(reader *bufio.Reader) (string, error) {
+	line, err := readRespLine(reader, maxRespInlineLength)
+	if err != nil {
+		return "", err
+	}
+	if len(line) == 0 || line[0] != '$' {
+		return "", fmt.Errorf("protocol error: expected '$', got %q", line)
+	}
+	length, err := strconv.Atoi(line[1:])
+	if err != nil || length < 0 || length > maxRespBulkLength {
+		return "", fmt.Errorf("protocol error: invalid bulk length %q", line[1:])
+	}
+
+	// The payload is read as it arrives, so the announced length alone doesn't allocate it
+	var buffer bytes.Buffer
+	if _, err := io.Copy(&buffer, io.LimitReader(reader, int64(length)+2)); err != nil {
+		return "", err
+	}
+	if buffer.Len() < length+2 {
+		return "", io.ErrUnexpectedEOF
+	}
+	data := buffer.Bytes()
+	if data[length] != '\r' || data[length+1] != '\n' {
+		return "", errors.New("protocol error: bulk string not terminated by CRLF")
+	}
+	return string(data[:length]), nil
+}
+
+// readRespLine reads a line terminated by \r\n, or \n for inline commands typed by hand
+func readRespLine(reader *bufio.Reader, maxLength int) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			if len(line) > 0 {
+				return "", unexpectedEOF(err)
+			}
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxLength {
+			return "", errors.New("protocol error: too big request")
+		}
+		if !isPrefix {
+			return strings.TrimSuffix(string(line), "\r"), nil
+		}
+	}
+}
+
+// unexpectedEOF reports a connection closed in the middle of a request
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
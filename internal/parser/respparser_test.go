@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadRespRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr error
+	}{
+		{"array of bulk strings", "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n", []string{"GET", "key"}, nil},
+		{"binary safe bulk string", "*2\r\n$3\r\nGET\r\n$5\r\na\r\nb\x00\r\n", []string{"GET", "a\r\nb\x00"}, nil},
+		{"empty bulk string", "*2\r\n$3\r\nGET\r\n$0\r\n\r\n", []string{"GET", ""}, nil},
+		{"inline command", "SET k \"a b\"\r\n", []string{"SET", "k", "a b"}, nil},
+		{"inline command with a newline only", "PING\n", []string{"PING"}, nil},
+		{"empty array", "*0\r\n", []string{}, nil},
+		{"no request", "", nil, io.EOF},
+		{"truncated bulk string", "*1\r\n$5\r\nab", nil, io.ErrUnexpectedEOF},
+		// The announced length alone must not allocate the bulk string, it is read as it arrives
+		{"truncated large bulk string", "*1\r\n$500000000\r\nab", nil, io.ErrUnexpectedEOF},
+	}
+
+	for _, test := range tests {
+		got, err := readRespRequest(bufio.NewReader(strings.NewReader(test.data)))
+		if !errors.Is(err, test.wantErr) || !slices.Equal(got, test.want) {
+			t.Errorf("%s: readRespRequest = %q, %v, want %q, %v", test.name, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestReadRespRequestErrors(t *testing.T) {
+	for _, data := range []string{
+		"*1\r\n$3\r\nGETXX",
+		"*1\r\n:3\r\n",
+		"*1\r\n$-1\r\n",
+		"*1\r\n$600000000\r\n",
+		"*x\r\n",
+		"*2000000\r\n",
+		strings.Repeat("A", maxRespInlineLength+1) + "\r\n",
+	} {
+		if parts, err := readRespRequest(bufio.NewReader(strings.NewReader(data))); err == nil {
+			t.Errorf("readRespRequest(%.20q) = %q, want an error", data, parts)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package parser
 
 import (
 	"errors"
-	"log"
 	"meteor/internal/common"
 	"net"
 	"strings"
@@ -42,6 +41,8 @@ func parseQuotedArgs(input string) []string {
 	var args []string
 	var current strings.Builder
 	var inSingleQuote, inDoubleQuote bool = false, false
+	// quoted is set once the current argument had quotes, so '' is an empty argument
+	quoted := false
 	
 	runes := []rune(input)
 	for i := 0; i < len(runes); i++ {
@@ -49,28 +50,28 @@ func parseQuotedArgs(input string) []string {
 		
 		switch char {
 		case '\'':
-			log.Println("inSingleQuote", inSingleQuote)
 			if !inDoubleQuote {
 				inSingleQuote = !inSingleQuote
+				quoted = true
 			} else {
 				current.WriteRune(char)
 			}
 		case '"':
-			log.Println("inDoubleQuote", inDoubleQuote)
 			if !inSingleQuote {
 				inDoubleQuote = !inDoubleQuote
+				quoted = true
 			} else {
 				current.WriteRune(char)
 			}
 		case ' ', '\t', '\n', '\r':
-			log.Println("inSingleQuote", inSingleQuote, "inDoubleQuote", inDoubleQuote)
 			if inSingleQuote || inDoubleQuote {
 				current.WriteRune(char)
 			} else {
 				// End of current argument
-				if current.Len() > 0 {
+				if current.Len() > 0 || quoted {
 					args = append(args, current.String())
 					current.Reset()
+					quoted = false
 				}
 				// Skip consecutive whitespace
 				for i+1 < len(runes) && isWhitespace(runes[i+1]) {
@@ -78,13 +79,12 @@ func parseQuotedArgs(input string) []string {
 				}
 			}
 		default:
-			log.Println("default", char)
 			current.WriteRune(char)
 		}
 	}
 	
 	// Add the last argument if there's any content
-	if current.Len() > 0 {
+	if current.Len() > 0 || quoted {
 		args = append(args, current.String())
 	}
 	
@@ -0,0 +1,167 @@
+package skiplist
+
+import "math/rand/v2"
+
+// maxLevel bounds the levels of the nodes, enough for 4^16 items
+const maxLevel = 16
+
+// SkipList is a set of items ordered by a compare function. Inserts, deletes and seeks take O(log n) time.
+// It isn't safe for concurrent use, its owner guards it with its own lock.
+type SkipList[T any] struct {
+	compare func(a, b T) int
+	head    *node[T]
+	level   int
+	length  int
+}
+
+type node[T any] struct {
+	item T
+	next []*node[T]
+}
+
+func New[T any](compare func(a, b T) int) *SkipList[T] {
+	return &SkipList[T]{
+		compare: compare,
+		head:    &node[T]{next: make([]*node[T], maxLevel)},
+		level:   1,
+	}
+}
+
+// Len returns the number of items
+func (s *SkipList[T]) Len() int {
+	return s.length
+}
+
+// Insert adds an item. It returns false and leaves the list unchanged if an equal item exists.
+func (s *SkipList[T]) Insert(item T) bool {
+	var update [maxLevel]*node[T]
+	previous := s.predecessors(item, false, &update)
+	if next := previous.next[0]; next != nil && s.compare(next.item, item) == 0 {
+		return false
+	}
+
+	level := randomLevel()
+	for i := s.level; i < level; i++ {
+		update[i] = s.head
+	}
+	s.level = max(s.level, level)
+
+	inserted := &node[T]{item: item, next: make([]*node[T], level)}
+	for i := 0; i < level; i++ {
+		inserted.next[i] = update[i].next[i]
+		update[i].next[i] = inserted
+	}
+	s.length++
+	return true
+}
+
+// Delete removes the item equal to item and reports whether there was one
+func (s *SkipList[T]) Delete(item T) bool {
+	var update [maxLevel]*node[T]
+	previous := s.predecessors(item, false, &update)
+	deleted := previous.next[0]
+	if deleted == nil || s.compare(deleted.item, item) != 0 {
+		return false
+	}
+
+	for i := 0; i < len(deleted.next); i++ {
+		update[i].next[i] = deleted.next[i]
+	}
+	for s.level > 1 && s.head.next[s.level-1] == nil {
+		s.level--
+	}
+	s.length--
+	return true
+}
+
+// Ceiling returns the smallest item greater than or equal to item
+func (s *SkipList[T]) Ceiling(item T) (T, bool) {
+	return s.item(s.predecessors(item, false, nil).next[0])
+}
+
+// Higher returns the smallest item greater than item
+func (s *SkipList[T]) Higher(item T) (T, bool) {
+	return s.item(s.predecessors(item, true, nil).next[0])
+}
+
+// Floor returns the largest item less than or equal to item
+func (s *SkipList[T]) Floor(item T) (T, bool) {
+	return s.item(s.predecessors(item, true, nil))
+}
+
+// Lower returns the largest item less than item
+func (s *SkipList[T]) Lower(item T) (T, bool) {
+	return s.item(s.predecessors(item, false, nil))
+}
+
+// First returns the smallest item
+func (s *SkipList[T]) First() (T, bool) {
+	return s.item(s.head.next[0])
+}
+
+// Last returns the largest item
+func (s *SkipList[T]) Last() (T, bool) {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.next[i] != nil {
+			x = x.next[i]
+		}
+	}
+	return s.item(x)
+}
+
+// Ascend calls fn for the items in order, starting at the smallest item greater than or equal to pivot,
+// until fn returns false
+func (s *SkipList[T]) Ascend(pivot T, fn func(item T) bool) {
+	for x := s.predecessors(pivot, false, nil).next[0]; x != nil; x = x.next[0] {
+		if !fn(x.item) {
+			return
+		}
+	}
+}
+
+// AscendAll calls fn for all items in order until fn returns false
+func (s *SkipList[T]) AscendAll(fn func(item T) bool) {
+	for x := s.head.next[0]; x != nil; x = x.next[0] {
+		if !fn(x.item) {
+			return
+		}
+	}
+}
+
+// predecessors returns the last node before the items greater than or equal to item, or greater than item if
+// inclusive is set. The head is returned if there is none. update receives the last such node of every level.
+func (s *SkipList[T]) predecessors(item T, inclusive bool, update *[maxLevel]*node[T]) *node[T] {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for next := x.next[i]; next != nil; next = x.next[i] {
+			c := s.compare(next.item, item)
+			if c > 0 || (c == 0 && !inclusive) {
+				break
+			}
+			x = next
+		}
+		if update != nil {
+			update[i] = x
+		}
+	}
+	return x
+}
+
+// item returns the item of a node, false for nil and the head
+func (s *SkipList[T]) item(x *node[T]) (T, bool) {
+	if x == nil || x == s.head {
+		var zero T
+		return zero, false
+	}
+	return x.item, true
+}
+
+// randomLevel returns the level of a new node, each level with a quarter of the probability of the one below
+func randomLevel() int {
+	level := 1
+	for level < maxLevel && rand.Uint32()&3 == 0 {
+		level++
+	}
+	return level
+}
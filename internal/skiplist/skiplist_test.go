@@ -0,0 +1,168 @@
+package skiplist
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func newInts(items ...int) *SkipList[int] {
+	s := New(cmp.Compare[int])
+	for _, item := range items {
+		s.Insert(item)
+	}
+	return s
+}
+
+func TestSeek(t *testing.T) {
+	s := newInts(10, 20, 30)
+	tests := []struct {
+		name   string
+		seek   func(int) (int, bool)
+		item   int
+		want   int
+		wantOk bool
+	}{
+		{"Ceiling below the first", s.Ceiling, 5, 10, true},
+		{"Ceiling of an item", s.Ceiling, 20, 20, true},
+		{"Ceiling between items", s.Ceiling, 21, 30, true},
+		{"Ceiling above the last", s.Ceiling, 31, 0, false},
+		{"Higher of an item", s.Higher, 20, 30, true},
+		{"Higher of the last", s.Higher, 30, 0, false},
+		{"Floor above the last", s.Floor, 35, 30, true},
+		{"Floor of an item", s.Floor, 20, 20, true},
+		{"Floor between items", s.Floor, 19, 10, true},
+		{"Floor below the first", s.Floor, 9, 0, false},
+		{"Lower of an item", s.Lower, 20, 10, true},
+		{"Lower of the first", s.Lower, 10, 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := test.seek(test.item)
+		if got != test.want || ok != test.wantOk {
+			t.Errorf("%s: seek(%d) = %d, %v, want %d, %v", test.name, test.item, got, ok, test.want, test.wantOk)
+		}
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	s := newInts()
+	for name, seek := range map[string]func(int) (int, bool){"Ceiling": s.Ceiling, "Higher": s.Higher, "Floor": s.Floor, "Lower": s.Lower} {
+		if _, ok := seek(0); ok {
+			t.Errorf("%s of an empty list found an item", name)
+		}
+	}
+	if _, ok := s.First(); ok {
+		t.Error("First of an empty list found an item")
+	}
+	if _, ok := s.Last(); ok {
+		t.Error("Last of an empty list found an item")
+	}
+	if s.Delete(1) {
+		t.Error("Delete from an empty list reported an item")
+	}
+}
+
+func TestInsertDelete(t *testing.T) {
+	s := newInts(1, 2, 3)
+	if s.Insert(2) {
+		t.Error("Insert of an existing item reported an insert")
+	}
+	if s.Delete(4) {
+		t.Error("Delete of a missing item reported a delete")
+	}
+
+	// Deleting the first and last items moves the ends
+	if !s.Delete(1) || !s.Delete(3) {
+		t.Fatal("Delete of an existing item reported no delete")
+	}
+	if first, _ := s.First(); first != 2 {
+		t.Errorf("First after deleting 1 = %d, want 2", first)
+	}
+	if last, _ := s.Last(); last != 2 {
+		t.Errorf("Last after deleting 3 = %d, want 2", last)
+	}
+	if got, ok := s.Ceiling(1); got != 2 || !ok {
+		t.Errorf("Ceiling(1) after deleting 1 = %d, %v, want 2, true", got, ok)
+	}
+
+	if !s.Delete(2) || s.Len() != 0 {
+		t.Errorf("Len after deleting all items = %d, want 0", s.Len())
+	}
+	if _, ok := s.Floor(10); ok {
+		t.Error("Floor after deleting all items found an item")
+	}
+	if !s.Insert(5) || s.Len() != 1 {
+		t.Error("Insert after deleting all items failed")
+	}
+}
+
+// TestRandom compares the list with a sorted slice after random inserts and deletes
+func TestRandom(t *testing.T) {
+	s := New(cmp.Compare[int])
+	var want []int
+	for range 5000 {
+		item := rand.IntN(1000)
+		i, found := slices.BinarySearch(want, item)
+		if rand.IntN(3) == 0 {
+			if s.Delete(item) != found {
+				t.Fatalf("Delete(%d) disagrees with the slice", item)
+			}
+			if found {
+				want = slices.Delete(want, i, i+1)
+			}
+		} else {
+			if s.Insert(item) == found {
+				t.Fatalf("Insert(%d) disagrees with the slice", item)
+			}
+			if !found {
+				want = slices.Insert(want, i, item)
+			}
+		}
+	}
+
+	if s.Len() != len(want) {
+		t.Fatalf("Len = %d, want %d", s.Len(), len(want))
+	}
+	var got []int
+	s.AscendAll(func(item int) bool {
+		got = append(got, item)
+		return true
+	})
+	if !slices.Equal(got, want) {
+		t.Fatal("AscendAll returned other items than the slice")
+	}
+
+	for item := -1; item <= 1001; item++ {
+		i, found := slices.BinarySearch(want, item)
+		floor, ok := s.Floor(item)
+		switch {
+		case found && (!ok || floor != item):
+			t.Fatalf("Floor(%d) = %d, %v, want the item", item, floor, ok)
+		case !found && i == 0 && ok:
+			t.Fatalf("Floor(%d) = %d, want none", item, floor)
+		case !found && i > 0 && (!ok || floor != want[i-1]):
+			t.Fatalf("Floor(%d) = %d, %v, want %d", item, floor, ok, want[i-1])
+		}
+		ceiling, ok := s.Ceiling(item)
+		switch {
+		case i == len(want) && ok:
+			t.Fatalf("Ceiling(%d) = %d, want none", item, ceiling)
+		case i < len(want) && (!ok || ceiling != want[i]):
+			t.Fatalf("Ceiling(%d) = %d, %v, want %d", item, ceiling, ok, want[i])
+		}
+	}
+}
+
+func TestAscend(t *testing.T) {
+	s := newInts(1, 3, 5, 7)
+	var got []int
+	s.Ascend(2, func(item int) bool {
+		got = append(got, item)
+		return item < 5
+	})
+	if !slices.Equal(got, []int{3, 5}) {
+		t.Errorf("Ascend from 2 until 5 = %v, want [3 5]", got)
+	}
+}
@@ -0,0 +1,180 @@
+package slowlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits of the recorded arguments, the same as Redis uses, so entries of commands with large values stay small
+const (
+	maxArgs      = 32
+	maxArgLength = 128
+)
+
+// Options configures which commands the slow log records and where
+type Options struct {
+	// Threshold is the duration from which commands are recorded, the slow log is disabled if it is 0
+	Threshold time.Duration
+	// MaxEntries is the number of entries kept in memory, older entries are dropped
+	MaxEntries int
+	// RedactArgs records the number of arguments instead of the arguments, which may hold keys and values
+	RedactArgs bool
+	// File is a file every entry is appended to as a JSON line, none if empty
+	File string
+}
+
+// Entry is a command that took at least the threshold
+type Entry struct {
+	Id      uint64    `json:"id"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Args    []string  `json:"args"`
+	Client  string    `json:"client,omitempty"`
+	// DurationMicros is the execution time, LockWaitMicros the part of it spent acquiring locks
+	DurationMicros int64  `json:"duration_us"`
+	LockWaitMicros int64  `json:"lock_wait_us"`
+	TransactionId  uint32 `json:"transaction_id,omitempty"`
+	IsolationLevel string `json:"isolation_level,omitempty"`
+	RowsExamined   int    `json:"rows_examined"`
+	RowsReturned   int    `json:"rows_returned"`
+	Error          string `json:"error,omitempty"`
+}
+
+// SlowLog keeps the latest slow commands in a ring of MaxEntries entries
+type SlowLog struct {
+	opts Options
+
+	mu      sync.Mutex
+	entries []Entry
+	// next is the position of the next entry in entries once it is full
+	next   int
+	lastId uint64
+	file   *os.File
+}
+
+// New returns a slow log. The file, if any, is opened for appending and closed by Close.
+func New(opts Options) (*SlowLog, error) {
+	if opts.Threshold < 0 {
+		return nil, fmt.Errorf("slow log threshold can't be negative, got %s", opts.Threshold)
+	}
+	if opts.MaxEntries < 0 {
+		return nil, fmt.Errorf("slow log max entries can't be negative, got %d", opts.MaxEntries)
+	}
+
+	s := &SlowLog{opts: opts}
+	if opts.File != "" && opts.Threshold > 0 {
+		file, err := os.OpenFile(opts.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open slow log file: %w", err)
+		}
+		s.file = file
+	}
+	return s, nil
+}
+
+// IsSlow reports whether a command that took duration is recorded
+func (s *SlowLog) IsSlow(duration time.Duration) bool {
+	return s.opts.Threshold > 0 && duration >= s.opts.Threshold
+}
+
+// Add records an entry and assigns it the next id. The arguments are redacted if the slow log is configured to,
+// otherwise capped to maxArgs arguments of at most maxArgLength bytes.
+func (s *SlowLog) Add(entry Entry) {
+	if s.opts.RedactArgs && len(entry.Args) > 0 {
+		entry.Args = []string{fmt.Sprintf("[%d arguments redacted]", len(entry.Args))}
+	} else {
+		entry.Args = capArgs(entry.Args)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastId++
+	entry.Id = s.lastId
+
+	if s.opts.MaxEntries > 0 {
+		if len(s.entries) < s.opts.MaxEntries {
+			s.entries = append(s.entries, entry)
+		} else {
+			s.entries[s.next] = entry
+			s.next = (s.next + 1) % s.opts.MaxEntries
+		}
+	}
+
+	if s.file != nil {
+		line, err := json.Marshal(entry)
+		if err == nil {
+			_, err = s.file.Write(append(line, '\n'))
+		}
+		if err != nil {
+			slog.Error("Failed to append to slow log file", "file", s.opts.File, "error", err)
+		}
+	}
+}
+
+// capArgs copies the arguments, replacing the ones after the first maxArgs-1 with a count and truncating long
+// arguments, so an entry doesn't keep large values in memory
+func capArgs(args []string) []string {
+	capped := make([]string, 0, min(len(args), maxArgs))
+	for i, arg := range args {
+		if i == maxArgs-1 && len(args) > maxArgs {
+			capped = append(capped, fmt.Sprintf("... (%d more arguments)", len(args)-i))
+			break
+		}
+		// Arguments may be slices of a larger request, they are cloned so they don't keep it in memory
+		if len(arg) > maxArgLength {
+			arg = fmt.Sprintf("%s... (%d more bytes)", arg[:maxArgLength], len(arg)-maxArgLength)
+		} else {
+			arg = strings.Clone(arg)
+		}
+		capped = append(capped, arg)
+	}
+	return capped
+}
+
+// Get returns the latest n entries, newest first. n < 0 returns all entries.
+func (s *SlowLog) Get(n int) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n < 0 || n > len(s.entries) {
+		n = len(s.entries)
+	}
+	entries := make([]Entry, 0, n)
+	// The newest entry is just before next, entries wrap around once the ring is full
+	for i := range n {
+		entries = append(entries, s.entries[(s.next-1-i+2*len(s.entries))%len(s.entries)])
+	}
+	return entries
+}
+
+// Len returns the number of entries kept
+func (s *SlowLog) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Reset drops all entries. Entries already appended to the file stay there.
+func (s *SlowLog) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = nil
+	s.next = 0
+}
+
+// Close closes the file of the slow log
+func (s *SlowLog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -52,6 +52,28 @@ func (s *BufferStore) Size() (int, error) {
 	return totalSize, nil
 }
 
+// ShardSizes returns the number of keys in each shard
+func (s *BufferStore) ShardSizes() ([]int, error) {
+	sizes := make([]int, len(s.tableShards))
+	for i, shard := range s.tableShards {
+		size, err := shard.Size()
+		if err != nil {
+			return nil, err
+		}
+		sizes[i] = size
+	}
+	return sizes, nil
+}
+
+// ShardStats returns the stats of each shard
+func (s *BufferStore) ShardStats() []datatable.TableStats {
+	stats := make([]datatable.TableStats, len(s.tableShards))
+	for i, shard := range s.tableShards {
+		stats[i] = shard.Stats()
+	}
+	return stats
+}
+
 func (s *BufferStore) Reset() error {
 	for _, shard := range s.tableShards {
 		shard.Clear()
@@ -117,3 +139,28 @@ func (s *BufferStore) CountWithFilter(filterFunc func(string, *common.V) bool) i
 	}
 	return count
 }
+
+// Iterator merges the iterators of all shards. A key lives in a single shard, so the shards never return the same key.
+func (s *BufferStore) Iterator(opts common.IteratorOptions) common.KVIterator {
+	iterators := make([]common.KVIterator, 0, len(s.tableShards))
+	for _, shard := range s.tableShards {
+		iterators = append(iterators, shard.Iterator(opts))
+	}
+	return common.NewMergeIterator(opts.Descending, iterators...)
+}
+
+// PutRangeTombstone adds the tombstone to every shard since a range spans keys of all shards
+func (s *BufferStore) PutRangeTombstone(rangeTombstone *common.RangeTombstone) error {
+	for _, shard := range s.tableShards {
+		err := shard.PutRangeTombstone(rangeTombstone)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RangeTombstones returns the range tombstones of the store. All shards hold the same tombstones.
+func (s *BufferStore) RangeTombstones() []*common.RangeTombstone {
+	return s.tableShards[0].RangeTombstones()
+}
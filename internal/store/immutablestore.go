@@ -49,3 +49,23 @@ func (s *ImmutableStore) GetLatestGsn(key string) (uint32, error) {
 func (s *ImmutableStore) GetVersionAtOrBeforeGsn(key string, maxGsn uint32) *common.V {
 	return s.table.GetVersionAtOrBeforeGsn(key, maxGsn)
 }
+
+// Iterator walks the key-value pairs of the immutable store in key order
+func (s *ImmutableStore) Iterator(opts common.IteratorOptions) common.KVIterator {
+	return s.table.Iterator(opts)
+}
+
+// PutRangeTombstone marks all keys covered by the tombstone as deleted at the tombstone's GSN
+func (s *ImmutableStore) PutRangeTombstone(rangeTombstone *common.RangeTombstone) error {
+	return s.table.PutRangeTombstone(rangeTombstone)
+}
+
+// RangeTombstones returns all range tombstones in the immutable store
+func (s *ImmutableStore) RangeTombstones() []*common.RangeTombstone {
+	return s.table.RangeTombstones()
+}
+
+// Stats returns the stats of the immutable store's table
+func (s *ImmutableStore) Stats() datatable.TableStats {
+	return s.table.Stats()
+}
@@ -22,4 +22,12 @@ type Store interface {
 	ScanWithFilter(filterFunc func(string, *common.V) bool) map[string]*common.V
 	// CountWithFilter returns the count of keys that match the filter function
 	CountWithFilter(filterFunc func(string, *common.V) bool) int
+	// Iterator walks the key-value pairs in the bounds of opts in key order, reading values as it advances
+	Iterator(opts common.IteratorOptions) common.KVIterator
+
+	// PutRangeTombstone marks all keys covered by the tombstone as deleted at the tombstone's GSN.
+	// Get, GetVersionAtOrBeforeGsn and the scans return a tombstone for covered keys written before it.
+	PutRangeTombstone(rangeTombstone *common.RangeTombstone) error
+	// RangeTombstones returns all range tombstones in the store
+	RangeTombstones() []*common.RangeTombstone
 }
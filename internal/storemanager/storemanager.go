@@ -2,6 +2,7 @@ package storemanager
 
 import (
 	"meteor/internal/common"
+	"meteor/internal/indexmanager"
 	"meteor/internal/store"
 )
 
@@ -20,6 +21,7 @@ const (
 type StoreManager struct {
 	BufferStore        store.Store   // In-memory mutable store
 	ImmutableStores    []store.Store // In-memory immutable stores (being flushed)
+	IndexManager       *indexmanager.IndexManager // Secondary indexes over committed values
 	// TODO: Add disk-based storage levels:
 	// DiskStores         []DiskStore   // On-disk immutable stores (SSTables)
 	// CompactionManager  *CompactionManager // Manages background compaction
@@ -33,6 +35,7 @@ func NewStoreManager() (*StoreManager, error) {
 	return &StoreManager{
 		BufferStore:        bufferStore,
 		ImmutableStores:    immutableStores,
+		IndexManager:       indexmanager.NewIndexManager(),
 	}, nil
 }
 
@@ -41,7 +44,11 @@ func NewStoreManager() (*StoreManager, error) {
 // - Check buffer store size after each put
 // - Trigger flush to immutable store when threshold exceeded
 func (sm *StoreManager) PutTxnRowToBufferStore(transactionRow *common.TransactionRow) error {
-	sm.BufferStore.Put(transactionRow.Payload.Key, transactionRow.Payload.NewValue)
+	if rangeTombstone := common.RangeTombstoneFromTransactionRow(transactionRow); rangeTombstone != nil {
+		return sm.PutRangeTombstone(rangeTombstone)
+	}
+
+	sm.Put(transactionRow.Payload.Key, transactionRow.Payload.NewValue)
 	
 	// TODO: Add size checking and flushing logic:
 	// if sm.shouldFlushBufferStore() {
@@ -51,6 +58,41 @@ func (sm *StoreManager) PutTxnRowToBufferStore(transactionRow *common.Transactio
 	return nil
 }
 
+// Put adds a committed version of a key to the buffer store and updates the secondary indexes covering the key
+func (sm *StoreManager) Put(key *common.K, value *common.V) error {
+	err := sm.BufferStore.Put(key, value)
+	if err != nil {
+		return err
+	}
+
+	sm.IndexManager.ApplyWrite(key, value)
+	return nil
+}
+
+// PutRangeTombstone adds a committed range delete to the buffer store and removes the covered index entries
+func (sm *StoreManager) PutRangeTombstone(rangeTombstone *common.RangeTombstone) error {
+	err := sm.BufferStore.PutRangeTombstone(rangeTombstone)
+	if err != nil {
+		return err
+	}
+
+	sm.IndexManager.ApplyRangeTombstone(rangeTombstone)
+	return nil
+}
+
+// ApplyIndexTxnRow creates or drops the index described by a CREATE_INDEX or DROP_INDEX row
+func (sm *StoreManager) ApplyIndexTxnRow(transactionRow *common.TransactionRow) error {
+	definition, err := common.IndexDefinitionFromTransactionRow(transactionRow)
+	if err != nil {
+		return err
+	}
+
+	if transactionRow.Operation == common.DB_OP_DROP_INDEX {
+		return sm.IndexManager.Drop(definition.Name)
+	}
+	return sm.IndexManager.Create(definition, transactionRow.Payload.Key.Gsn, sm.BufferStore)
+}
+
 // Size returns the total size across all storage levels
 // TODO: Include sizes from immutable stores and disk stores
 func (sm *StoreManager) Size() (int, error) {
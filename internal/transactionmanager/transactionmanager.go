@@ -1,6 +1,7 @@
 package transactionmanager
 
 import (
+	"cmp"
 	"errors"
 	"meteor/internal/common"
 	"meteor/internal/lockmanager"
@@ -16,9 +17,16 @@ import (
 type TransactionManager struct {
 	transactionStoreMap map[uint32]store.Store
 	connToTransactionIdsMap map[*net.Conn][]uint32
+	// txnToConnMap is the connection of every transaction in connToTransactionIdsMap
+	txnToConnMap map[uint32]*net.Conn
 	txnToIsolationLevelMap map[uint32]string
 	// GSN at transaction start for snapshot isolation
 	txnStartGsnMap map[uint32]uint32
+	// time the isolation level of a transaction was set, i.e. when it started
+	txnStartTimeMap map[uint32]time.Time
+	// stateM guards the isolation levels, start GSNs and transactions of connections, which are also read to
+	// report the active transactions and clients
+	stateM sync.RWMutex
 	walManager *walmanager.WalManager
 	lockManager *lockmanager.LockManager
 	currentTransactionId atomic.Uint32
@@ -33,8 +41,10 @@ func NewTransactionManager(walManager *walmanager.WalManager) (*TransactionManag
 		walManager: walManager,
 		transactionStoreMap: make(map[uint32]store.Store),
 		connToTransactionIdsMap: make(map[*net.Conn][]uint32),
+		txnToConnMap: make(map[uint32]*net.Conn),
 		txnToIsolationLevelMap: make(map[uint32]string),
 		txnStartGsnMap: make(map[uint32]uint32),
+		txnStartTimeMap: make(map[uint32]time.Time),
 		lockManager: lockmanager.NewLockManager(),
 		currentTransactionId: atomic.Uint32{},
 		transactionIdBatchStart: transactionIdBatchStart,
@@ -71,6 +81,10 @@ func (tm *TransactionManager) AddTransaction(transactionRow *common.TransactionR
 		transactionStore = store.NewBufferStore()
 		tm.transactionStoreMap[transactionRow.TransactionId] = transactionStore
 	}
+
+	if rangeTombstone := common.RangeTombstoneFromTransactionRow(transactionRow); rangeTombstone != nil {
+		return transactionStore.PutRangeTombstone(rangeTombstone)
+	}
 	transactionStore.Put(transactionRow.Payload.Key, transactionRow.Payload.NewValue)
 
 	return nil
@@ -92,8 +106,12 @@ func (tm *TransactionManager) ClearTransactionStore(transactionId uint32) {
 	
 	// Clean up transaction state
 	delete(tm.transactionStoreMap, transactionId)
+	tm.stateM.Lock()
 	delete(tm.txnToIsolationLevelMap, transactionId)
 	delete(tm.txnStartGsnMap, transactionId)
+	delete(tm.txnStartTimeMap, transactionId)
+	tm.unregisterTransactionForConnection(transactionId)
+	tm.stateM.Unlock()
 }
 
 func (tm *TransactionManager) isTransactionIdAllowedForConnection(transactionId uint32, conn *net.Conn) bool {
@@ -103,6 +121,8 @@ func (tm *TransactionManager) isTransactionIdAllowedForConnection(transactionId
 		return true
 	}
 
+	tm.stateM.RLock()
+	defer tm.stateM.RUnlock()
 	transactionIds, ok := tm.connToTransactionIdsMap[conn]
 	if !ok {
 		return false
@@ -120,6 +140,8 @@ func (tm *TransactionManager) IsNewTransactionId(transactionId uint32) bool {
 }
 
 func (tm *TransactionManager) registerTransactionForConnection(transactionId uint32, conn *net.Conn) {
+	tm.stateM.Lock()
+	defer tm.stateM.Unlock()
 	transactionIds, ok := tm.connToTransactionIdsMap[conn]
 	if !ok {
 		tm.connToTransactionIdsMap[conn] = make([]uint32, 0)
@@ -136,9 +158,53 @@ func (tm *TransactionManager) registerTransactionForConnection(transactionId uin
 	}
 	if !isPresent {
 		tm.connToTransactionIdsMap[conn] = append(transactionIds, transactionId)
+		tm.txnToConnMap[transactionId] = conn
+	}
+}
+
+// unregisterTransactionForConnection removes an ended transaction from its connection, so connections don't
+// collect the ids of all transactions they ran (assumes stateM is held)
+func (tm *TransactionManager) unregisterTransactionForConnection(transactionId uint32) {
+	conn, ok := tm.txnToConnMap[transactionId]
+	if !ok {
+		return
+	}
+	delete(tm.txnToConnMap, transactionId)
+
+	transactionIds := slices.DeleteFunc(tm.connToTransactionIdsMap[conn], func(tId uint32) bool {
+		return tId == transactionId
+	})
+	if len(transactionIds) == 0 {
+		delete(tm.connToTransactionIdsMap, conn)
+	} else {
+		tm.connToTransactionIdsMap[conn] = transactionIds
 	}
 }
 
+// ConnectionTransactions returns the ids of the active transactions started on a connection, sorted
+func (tm *TransactionManager) ConnectionTransactions(conn *net.Conn) []uint32 {
+	tm.stateM.RLock()
+	defer tm.stateM.RUnlock()
+	transactionIds := make([]uint32, 0)
+	for _, transactionId := range tm.connToTransactionIdsMap[conn] {
+		if _, ok := tm.txnToIsolationLevelMap[transactionId]; ok {
+			transactionIds = append(transactionIds, transactionId)
+		}
+	}
+	slices.Sort(transactionIds)
+	return transactionIds
+}
+
+// ForgetConnection drops the transaction ids of a closed connection. Its transactions must have ended.
+func (tm *TransactionManager) ForgetConnection(conn *net.Conn) {
+	tm.stateM.Lock()
+	defer tm.stateM.Unlock()
+	for _, transactionId := range tm.connToTransactionIdsMap[conn] {
+		delete(tm.txnToConnMap, transactionId)
+	}
+	delete(tm.connToTransactionIdsMap, conn)
+}
+
 func (tm *TransactionManager) GetStoreByTransactionId(transactionId uint32, conn *net.Conn) (store.Store, error) {
 	if !tm.isTransactionIdAllowedForConnection(transactionId, conn) {
 		return nil,errors.New("transaction id not allowed for connection")
@@ -152,9 +218,12 @@ func (tm *TransactionManager) GetStoreByTransactionId(transactionId uint32, conn
 }
 
 func (tm *TransactionManager) EnsureIsolationLevel(transactionId uint32, isolationLevel string) error {
+	tm.stateM.Lock()
+	defer tm.stateM.Unlock()
 	txnIsolationLevel, ok := tm.txnToIsolationLevelMap[transactionId]
 	if !ok {
 		tm.txnToIsolationLevelMap[transactionId] = isolationLevel
+		tm.txnStartTimeMap[transactionId] = time.Now()
 	} else if txnIsolationLevel != isolationLevel {
 		return errors.New("transaction isolation level mismatch: " + txnIsolationLevel + " != " + isolationLevel)
 	}
@@ -162,26 +231,92 @@ func (tm *TransactionManager) EnsureIsolationLevel(transactionId uint32, isolati
 }
 
 func (tm *TransactionManager) GetIsolationLevel(transactionId uint32) (string, error) {
+	tm.stateM.Lock()
+	defer tm.stateM.Unlock()
 	txnIsolationLevel, ok := tm.txnToIsolationLevelMap[transactionId]
 	if !ok {
 		// if not found, default to read_COMMITTED
 		tm.txnToIsolationLevelMap[transactionId] = common.TXN_ISOLATION_READ_COMMITTED
+		tm.txnStartTimeMap[transactionId] = time.Now()
 		return common.TXN_ISOLATION_READ_COMMITTED, nil
 	}
 	return txnIsolationLevel, nil
 }
 
+// LookupIsolationLevel returns the isolation level of an active transaction without assigning a default
+func (tm *TransactionManager) LookupIsolationLevel(transactionId uint32) (string, bool) {
+	tm.stateM.RLock()
+	defer tm.stateM.RUnlock()
+	isolationLevel, ok := tm.txnToIsolationLevelMap[transactionId]
+	return isolationLevel, ok
+}
+
 // SetTransactionStartGsn sets the GSN at transaction start for snapshot isolation
 func (tm *TransactionManager) SetTransactionStartGsn(transactionId uint32, gsn uint32) {
+	tm.stateM.Lock()
+	defer tm.stateM.Unlock()
 	tm.txnStartGsnMap[transactionId] = gsn
 }
 
 // GetTransactionStartGsn gets the GSN at transaction start for snapshot isolation
 func (tm *TransactionManager) GetTransactionStartGsn(transactionId uint32) (uint32, bool) {
+	tm.stateM.RLock()
+	defer tm.stateM.RUnlock()
 	gsn, exists := tm.txnStartGsnMap[transactionId]
 	return gsn, exists
 }
 
+// CountByIsolationLevel returns the number of active transactions of each isolation level
+func (tm *TransactionManager) CountByIsolationLevel() map[string]int {
+	tm.stateM.RLock()
+	defer tm.stateM.RUnlock()
+
+	counts := make(map[string]int)
+	for _, isolationLevel := range tm.txnToIsolationLevelMap {
+		counts[isolationLevel]++
+	}
+	return counts
+}
+
+// TransactionInfo describes an active transaction
+type TransactionInfo struct {
+	Id             uint32
+	IsolationLevel string
+	// StartGsn is the snapshot of SNAPSHOT_ISOLATION transactions, 0 for the other isolation levels
+	StartGsn  uint32
+	StartedAt time.Time
+}
+
+// Transactions returns the active transactions sorted by id
+func (tm *TransactionManager) Transactions() []TransactionInfo {
+	tm.stateM.RLock()
+	defer tm.stateM.RUnlock()
+
+	transactions := make([]TransactionInfo, 0, len(tm.txnToIsolationLevelMap))
+	for transactionId, isolationLevel := range tm.txnToIsolationLevelMap {
+		transactions = append(transactions, TransactionInfo{
+			Id:             transactionId,
+			IsolationLevel: isolationLevel,
+			StartGsn:       tm.txnStartGsnMap[transactionId],
+			StartedAt:      tm.txnStartTimeMap[transactionId],
+		})
+	}
+	slices.SortFunc(transactions, func(a, b TransactionInfo) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+	return transactions
+}
+
+// NextTransactionId returns the id the next transaction gets, unless a new batch of ids is allocated first
+func (tm *TransactionManager) NextTransactionId() uint32 {
+	return tm.currentTransactionId.Load() + 1
+}
+
+// GetLockStatistics returns the statistics of the lock manager
+func (tm *TransactionManager) GetLockStatistics() map[string]any {
+	return tm.lockManager.GetLockStatistics()
+}
+
 // AcquireReadLock acquires appropriate read locks based on isolation level
 func (tm *TransactionManager) AcquireReadLock(transactionId uint32, key string, isolationLevel string) error {
 	timeout := 30 * time.Second
@@ -361,6 +496,13 @@ func (tm *TransactionManager) AcquireRangeLock(transactionId uint32, startKey, e
 	return tm.lockManager.AcquireRangeLock(transactionId, startKey, endKey, timeout)
 }
 
+// AcquireRangeWriteLock acquires a range lock at every isolation level, so a range delete waits for the
+// transactions writing keys in the range
+func (tm *TransactionManager) AcquireRangeWriteLock(transactionId uint32, startKey, endKey string) error {
+	timeout := 30 * time.Second
+	return tm.lockManager.AcquireRangeLock(transactionId, startKey, endKey, timeout)
+}
+
 // AcquirePredicateLock acquires a predicate lock for serializable isolation
 func (tm *TransactionManager) AcquirePredicateLock(transactionId uint32, predicate string) error {
 	isolationLevel, err := tm.GetIsolationLevel(transactionId)
@@ -425,11 +567,7 @@ func (tm *TransactionManager) ReadRangeValues(transactionId uint32, startKey, en
 	}
 
 	// Merge buffer store results, but transaction store takes precedence
-	for key, value := range bufferResults {
-		if _, exists := result[key]; !exists {
-			result[key] = value
-		}
-	}
+	mergeBufferResults(result, bufferResults, transactionStore)
 
 	return result, nil
 }
@@ -482,11 +620,7 @@ func (tm *TransactionManager) ReadPrefixValues(transactionId uint32, prefix stri
 	}
 
 	// Merge buffer store results, but transaction store takes precedence
-	for key, value := range bufferResults {
-		if _, exists := result[key]; !exists {
-			result[key] = value
-		}
-	}
+	mergeBufferResults(result, bufferResults, transactionStore)
 
 	return result, nil
 }
@@ -538,16 +672,125 @@ func (tm *TransactionManager) ReadFilteredValues(transactionId uint32, filterFun
 		
 		bufferResults = bufferStore.ScanWithFilter(snapshotFilter)
 
+		// Return the values as of the snapshot rather than the latest ones
+		for key := range bufferResults {
+			bufferResults[key] = tm.getVersionAtGsn(key, startGsn, bufferStore)
+		}
+
 	default:
 		return nil, errors.New("unknown isolation level: " + isolationLevel)
 	}
 
 	// Merge buffer store results, but transaction store takes precedence
-	for key, value := range bufferResults {
-		if _, exists := result[key]; !exists {
-			result[key] = value
+	mergeBufferResults(result, bufferResults, transactionStore)
+
+	return result, nil
+}
+
+// GetReadGsn returns the GSN the transaction reads committed versions at: the start GSN for snapshot isolation,
+// and 0 for the latest versions otherwise
+func (tm *TransactionManager) GetReadGsn(transactionId uint32) (uint32, error) {
+	isolationLevel, err := tm.GetIsolationLevel(transactionId)
+	if err != nil {
+		return 0, err
+	}
+
+	if isolationLevel != common.TXN_ISOLATION_SNAPSHOT_ISOLATION {
+		return 0, nil
+	}
+	startGsn, exists := tm.GetTransactionStartGsn(transactionId)
+	if !exists {
+		return 0, errors.New("transaction start GSN not found for snapshot isolation")
+	}
+	return startGsn, nil
+}
+
+// ReadKeyValues reads the given keys, e.g. the candidates found by a secondary index, and the keys in
+// [startKey, endKey] written by the transaction, respecting transaction isolation. Missing keys are left out.
+func (tm *TransactionManager) ReadKeyValues(transactionId uint32, keys []string, startKey, endKey string, bufferStore store.Store, conn *net.Conn) (map[string]*common.V, error) {
+	transactionStore, err := tm.GetStoreByTransactionId(transactionId, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys written by the transaction aren't in the index yet
+	if transactionStore != nil {
+		for key := range transactionStore.ScanRange(startKey, endKey) {
+			keys = append(keys, key)
 		}
 	}
 
+	result := make(map[string]*common.V)
+	for _, key := range keys {
+		if _, exists := result[key]; exists || key < startKey || key > endKey {
+			continue
+		}
+		value, err := tm.ReadValue(transactionId, key, bufferStore, conn)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			result[key] = value
+		}
+	}
 	return result, nil
 }
+
+// IterateValues returns an iterator over the key-value pairs in the bounds of opts, respecting transaction isolation.
+// Values written by the transaction take precedence over the buffer store. Deleted keys are returned as tombstones.
+func (tm *TransactionManager) IterateValues(transactionId uint32, opts common.IteratorOptions, bufferStore store.Store, conn *net.Conn) (common.KVIterator, error) {
+	isolationLevel, err := tm.GetIsolationLevel(transactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionStore, err := tm.GetStoreByTransactionId(transactionId, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read from buffer store based on isolation level
+	switch isolationLevel {
+	case common.TXN_ISOLATION_READ_COMMITTED,
+		 common.TXN_ISOLATION_REPEATABLE_READ,
+		 common.TXN_ISOLATION_SERIALIZABLE:
+		opts.MaxGsn = 0
+
+	case common.TXN_ISOLATION_SNAPSHOT_ISOLATION:
+		// For snapshot isolation, read the versions at transaction start
+		startGsn, exists := tm.GetTransactionStartGsn(transactionId)
+		if !exists {
+			return nil, errors.New("transaction start GSN not found for snapshot isolation")
+		}
+		opts.MaxGsn = startGsn
+
+	default:
+		return nil, errors.New("unknown isolation level: " + isolationLevel)
+	}
+
+	bufferIterator := bufferStore.Iterator(opts)
+	if transactionStore == nil {
+		return bufferIterator, nil
+	}
+
+	// Keys written by the transaction, including keys covered by its range deletes, are taken from the transaction store only
+	bufferIterator = common.NewFilterIterator(bufferIterator, func(key string, value *common.V) bool {
+		return transactionStore.Get(key) == nil
+	})
+	opts.MaxGsn = 0
+	return common.NewMergeIterator(opts.Descending, transactionStore.Iterator(opts), bufferIterator), nil
+}
+
+// mergeBufferResults adds buffer store results for keys the transaction hasn't decided yet. Keys written by the
+// transaction, including keys covered by its range deletes, are taken from the transaction store only.
+func mergeBufferResults(result map[string]*common.V, bufferResults map[string]*common.V, transactionStore store.Store) {
+	for key, value := range bufferResults {
+		if _, exists := result[key]; exists {
+			continue
+		}
+		if transactionStore != nil && transactionStore.Get(key) != nil {
+			continue
+		}
+		result[key] = value
+	}
+}
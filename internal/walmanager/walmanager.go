@@ -3,13 +3,21 @@ package walmanager
 import (
 	"fmt"
 	"meteor/internal/common"
+	"meteor/internal/metrics"
 	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-var walFileName = "meteor.wal"
+// WalFileName is the name of the WAL file in the data directory
+const WalFileName = "meteor.wal"
+
+var (
+	walBytesWritten  = metrics.Default.NewCounter("meteor_wal_bytes_written_total", "Bytes of rows appended to the WAL.")
+	walFsyncDuration = metrics.Default.NewHistogram("meteor_wal_fsync_duration_seconds", "Duration of WAL fsyncs in seconds.", metrics.DurationBuckets)
+)
 
 type WalManager struct {
 	lso atomic.Int64
@@ -19,7 +27,8 @@ type WalManager struct {
 	walRowStartOffset int64
 }
 
-func NewWalManager() (*WalManager, error) {
+func NewWalManager(dir string) (*WalManager, error) {
+	walFileName := filepath.Join(dir, WalFileName)
 	var walFile *os.File
 	var walHeader *WalHeader = &WalHeader{
 		Version: 1,
@@ -136,6 +145,17 @@ func (w *WalManager) AddRow(row *common.TransactionRow) error {
 	}
 
 	w.lso.Store(newOffset)
+	walBytesWritten.Add(float64(newOffset - lso))
+
+	// A transaction is durable once its commit row is on disk, the rows before it are synced along with it
+	if row.State == common.TRANSACTION_STATE_COMMIT {
+		t0 := time.Now()
+		err = w.walFile.Sync()
+		walFsyncDuration.Observe(time.Since(t0).Seconds())
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -177,6 +197,11 @@ func (w *WalManager) ReadRow() (*common.TransactionRow, error) {
 	}, nil
 }
 
+// Offset returns the offset in the WAL file the next row is written at
+func (w *WalManager) Offset() int64 {
+	return w.lso.Load()
+}
+
 func (w *WalManager) ResetOffsetToFirstRow() {
 	w.m.Lock()
 	defer w.m.Unlock()
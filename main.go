@@ -1,11 +1,37 @@
 package main
 
 import (
+	"flag"
 	"meteor/internal/config"
 	"meteor/server"
 )
 
 func main() {
-	config.LoadConfig()
+	configFile := flag.String("config", "", "path of the config file, defaults to config.json in the working directory")
+	dataDir := flag.String("data-dir", "", "directory of the WAL and the lock file, overrides dataDir of the config")
+	port := flag.String("port", "", "port of the text and binary protocol, overrides port of the config")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate of the server, overrides tlsCertFile of the config")
+	tlsKey := flag.String("tls-key", "", "PEM private key of the certificate, overrides tlsKeyFile of the config")
+	tlsClientCa := flag.String("tls-client-ca", "", "PEM CA of client certificates, overrides tlsClientCaFile of the config")
+	flag.Parse()
+
+	overrides := make(map[string]any)
+	if *dataDir != "" {
+		overrides["dataDir"] = *dataDir
+	}
+	if *port != "" {
+		overrides["port"] = *port
+	}
+	if *tlsCert != "" {
+		overrides["tlsCertFile"] = *tlsCert
+	}
+	if *tlsKey != "" {
+		overrides["tlsKeyFile"] = *tlsKey
+	}
+	if *tlsClientCa != "" {
+		overrides["tlsClientCaFile"] = *tlsClientCa
+	}
+
+	config.LoadConfig(*configFile, overrides)
 	server.Init()
 }
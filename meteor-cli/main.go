@@ -2,7 +2,13 @@ package main
 
 import (
 	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
 	"fmt"
+	"meteor/internal/commands"
+	"meteor/internal/common"
+	"meteor/internal/parser"
 	"net"
 	"os"
 	"strconv"
@@ -19,31 +25,41 @@ type TransactionState struct {
 // MeteorCLI represents the CLI client
 type MeteorCLI struct {
 	conn      net.Conn
+	connReader *bufio.Reader
 	txnState  TransactionState
 	host      string
 	port      string
 	connected bool
 	prompt    string
 	reader    *bufio.Reader
+	tlsConfig *tls.Config
 }
 
-// NewMeteorCLI creates a new CLI instance
-func NewMeteorCLI(host, port string) *MeteorCLI {
+// NewMeteorCLI creates a new CLI instance. The connection uses TLS if tlsConfig is set.
+func NewMeteorCLI(host, port string, tlsConfig *tls.Config) *MeteorCLI {
 	return &MeteorCLI{
-		host:   host,
-		port:   port,
-		prompt: "meteor> ",
-		reader: bufio.NewReader(os.Stdin),
+		host:      host,
+		port:      port,
+		prompt:    "meteor> ",
+		reader:    bufio.NewReader(os.Stdin),
+		tlsConfig: tlsConfig,
 	}
 }
 
 // Connect establishes connection to the meteor database
 func (cli *MeteorCLI) Connect() error {
-	conn, err := net.Dial("tcp", cli.host+":"+cli.port)
+	var conn net.Conn
+	var err error
+	if cli.tlsConfig != nil {
+		conn, err = tls.Dial("tcp", cli.host+":"+cli.port, cli.tlsConfig)
+	} else {
+		conn, err = net.Dial("tcp", cli.host+":"+cli.port)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to connect to meteor database: %v", err)
 	}
 	cli.conn = conn
+	cli.connReader = bufio.NewReader(conn)
 	cli.connected = true
 	fmt.Printf("Connected to meteor database at %s:%s\n", cli.host, cli.port)
 	return nil
@@ -71,17 +87,54 @@ func (cli *MeteorCLI) SendCommand(command string) (string, error) {
 	}
 
 	// Read response
-	buffer := make([]byte, 4096)
-	cli.conn.SetReadDeadline(time.Now().Add(60 * time.Second)) // 1 minute timeout
-	n, err := cli.conn.Read(buffer)
+	response, err := cli.readResponseLine()
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %v", err)
+		return "", err
+	}
+
+	cmd, err := parser.NewStringParser().Parse([]byte(command), nil)
+	if err != nil {
+		return response, nil
+	}
+
+	// Streamed results arrive row by row until the END marker, print the rows as they come
+	if commands.IsStream(cmd) {
+		for !strings.HasPrefix(response, "END") && !strings.HasPrefix(response, "error:") {
+			fmt.Println(response)
+			response, err = cli.readResponseLine()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// INFO reports its sections in several lines, followed by the END marker
+	if strings.EqualFold(cmd.Operation, "INFO") && !strings.HasPrefix(response, "error:") {
+		var lines []string
+		for response != "END" {
+			lines = append(lines, response)
+			response, err = cli.readResponseLine()
+			if err != nil {
+				return "", err
+			}
+		}
+		response = strings.Join(lines, "\n")
 	}
 
-	response := strings.TrimSpace(string(buffer[:n]))
 	return response, nil
 }
 
+// readResponseLine reads one line of the response. Every response and every streamed row ends with a newline.
+func (cli *MeteorCLI) readResponseLine() (string, error) {
+	cli.conn.SetReadDeadline(time.Now().Add(60 * time.Second)) // 1 minute timeout
+	line, err := cli.connReader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %v", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+
 // ParseCommand handles transaction state and forwards commands to server
 func (cli *MeteorCLI) ParseCommand(input string) (string, error) {
 	input = strings.TrimSpace(input)
@@ -180,8 +233,12 @@ func (cli *MeteorCLI) handleRollback(input string) (string, error) {
 func (cli *MeteorCLI) handleDataCommand(input string) (string, error) {
 	var command string
 	if cli.txnState.InTransaction {
-		// Add transaction ID to the command for transactional operations
-		command = fmt.Sprintf("%s %s", input, cli.txnState.TransactionID)
+		// Add transaction ID to the command for transactional operations, after TXN for MGET, MDEL and AGG
+		transactionId := cli.txnState.TransactionID
+		if fields := strings.Fields(input); len(fields) > 0 && common.NeedsTransactionMarker(fields[0]) {
+			transactionId = common.TransactionMarker + " " + transactionId
+		}
+		command = fmt.Sprintf("%s %s", input, transactionId)
 	} else {
 		// Send command as-is for non-transactional operations
 		command = input
@@ -250,12 +307,35 @@ func (cli *MeteorCLI) showHelp() {
 	fmt.Println("                             Valid isolation levels: READ_COMMITTED (default),")
 	fmt.Println("                             REPEATABLE_READ, SNAPSHOT_ISOLATION, SERIALIZABLE")
 	fmt.Println("  PUT <key> <value>        - Insert or update a key-value pair")
+	fmt.Println("  PUT <key> <value> NX|XX|IF_VERSION <gsn> - Conditional insert or update")
+	fmt.Println("  PUT <key> <value> TYPE <type> - Store a typed value (int64, float64, bool, bytes, json, ...)")
+	fmt.Println("  CAS <key> <expected> <new> - Set key only if it holds the expected value")
+	fmt.Println("  VERSION <key>            - Latest committed version (gsn) of a key")
 	fmt.Println("  GET <key>                - Retrieve value for a key")
+	fmt.Println("  GET <key> PATH <path>    - Retrieve part of a json value (e.g., $.user.age)")
+	fmt.Println("  JSONSET <key> <path> <value> - Update part of a json value")
 	fmt.Println("  DELETE <key>             - Delete a key")
+	fmt.Println("  MGET <key> [key ...]     - Retrieve values for multiple keys atomically")
+	fmt.Println("  MSET <key> <value> [...] - Set multiple key-value pairs atomically")
+	fmt.Println("  MDEL <key> [key ...]     - Delete multiple keys atomically")
+	fmt.Println("  DELRANGE <start> <end>   - Delete all keys in a range")
+	fmt.Println("  DELPREFIX <prefix>       - Delete all keys with a prefix")
+	fmt.Println("  INCR|DECR <key>          - Atomically increment or decrement a counter by 1")
+	fmt.Println("  INCRBY|DECRBY <key> <n>  - Atomically increment or decrement a counter by n")
+	fmt.Println("  INCRBYFLOAT <key> <n>    - Atomically increment a float counter by n")
 	fmt.Println("  CGET \"<WHERE condition>\" - Conditional get with WHERE clause")
 	fmt.Println("  RGET <startKey> <endKey> - Range get between start and end keys")
 	fmt.Println("  COUNT [\"<WHERE condition>\"] - Count records, optionally with WHERE clause")
+	fmt.Println("  AGG <aggregates> [WHERE <condition>] [GROUP BY PREFIX($key, ':')] - SUM/AVG/MIN/MAX/COUNT aggregates")
 	fmt.Println("  SCAN <pattern> [\"<WHERE condition>\"] - Scan with pattern and optional filter")
+	fmt.Println("  RGET|SCAN ... [ORDER BY key ASC|DESC] [LIMIT <n>] [OFFSET <n>] [CURSOR <cursor>] - Order and page results")
+	fmt.Println("  RGET|SCAN ... STREAM     - Stream results row by row")
+	fmt.Println("  EXPLAIN SCAN|COUNT \"<condition>\" - Show how a condition is executed")
+	fmt.Println("  CREATE INDEX <name> ON PREFIX <prefix> (<field>) - Index a value field for SCAN and COUNT")
+	fmt.Println("  DROP INDEX <name> - Remove a secondary index")
+	fmt.Println("  AUTH <user> <password>   - Authenticate the connection")
+	fmt.Println("  USER ADD|DEL|PASSWORD|LIST ... - Manage users, the first user added gets all grants")
+	fmt.Println("  GRANT|REVOKE <read,write,admin|ALL> ON PREFIX <prefix> TO|FROM <user> - Manage grants")
 	fmt.Println("  COMMIT                   - Commit current transaction")
 	fmt.Println("  ROLLBACK                 - Rollback current transaction")
 	fmt.Println("  STATUS                   - Show current transaction status")
@@ -320,25 +400,75 @@ func (cli *MeteorCLI) Run() {
 }
 
 
+// tlsFlags are the command line flags configuring TLS
+type tlsFlags struct {
+	enabled    bool
+	caFile     string
+	certFile   string
+	keyFile    string
+	serverName string
+}
+
+// config returns the TLS config of the flags, nil if TLS isn't used. A CA or client certificate implies TLS.
+func (f *tlsFlags) config() (*tls.Config, error) {
+	if !f.enabled && f.caFile == "" && f.certFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: f.serverName}
+	if f.caFile != "" {
+		pem, err := os.ReadFile(f.caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", f.caFile)
+		}
+	}
+	if f.certFile != "" || f.keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
 func main() {
 	// Default connection parameters
 	host := "localhost"
 	port := "5050"
 
+	var tlsOptions tlsFlags
+	flag.BoolVar(&tlsOptions.enabled, "tls", false, "connect with TLS")
+	flag.StringVar(&tlsOptions.caFile, "tls-ca", "", "PEM CA to verify the server certificate with, the system CAs if empty")
+	flag.StringVar(&tlsOptions.certFile, "tls-cert", "", "PEM client certificate, for servers requiring mutual TLS")
+	flag.StringVar(&tlsOptions.keyFile, "tls-key", "", "PEM private key of the client certificate")
+	flag.StringVar(&tlsOptions.serverName, "tls-server-name", "", "name to verify the server certificate against, the host if empty")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: meteor-cli [flags] [host] [port]")
+		fmt.Fprintln(flag.CommandLine.Output(), "Default: meteor-cli localhost 5050")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
 	// Check for command line arguments
-	if len(os.Args) > 1 {
-		if os.Args[1] == "--help" || os.Args[1] == "-h" {
-			fmt.Println("Usage: meteor-cli [host] [port]")
-			fmt.Println("Default: meteor-cli localhost 5050")
-			return
-		}
-		host = os.Args[1]
+	if flag.NArg() > 0 {
+		host = flag.Arg(0)
+	}
+	if flag.NArg() > 1 {
+		port = flag.Arg(1)
 	}
-	if len(os.Args) > 2 {
-		port = os.Args[2]
+
+	tlsConfig, err := tlsOptions.config()
+	if err != nil {
+		fmt.Printf("Invalid TLS options: %v\n", err)
+		os.Exit(1)
 	}
 
-	cli := NewMeteorCLI(host, port)
+	cli := NewMeteorCLI(host, port, tlsConfig)
 
 	// Connect to database
 	if err := cli.Connect(); err != nil {
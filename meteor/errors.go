@@ -0,0 +1,47 @@
+package meteor
+
+import (
+	"errors"
+	"meteor/internal/clientapi"
+	"meteor/internal/common"
+)
+
+// ErrorCode classifies the errors of commands
+type ErrorCode = common.ErrorCode
+
+const (
+	CodeGeneric         = common.ErrorCodeGeneric
+	CodeUnknownCommand  = common.ErrorCodeUnknownCommand
+	CodeInvalidArgument = common.ErrorCodeInvalidArgument
+	CodeTransaction     = common.ErrorCodeTransaction
+	CodeConflict        = common.ErrorCodeConflict
+	CodeDeadlock        = common.ErrorCodeDeadlock
+	CodeLockTimeout     = common.ErrorCodeLockTimeout
+	CodeConditionNotMet = common.ErrorCodeConditionNotMet
+)
+
+// Error is an error of a command. It matches the Err* values of its code with errors.Is, e.g.
+// errors.Is(err, meteor.ErrConflict).
+type Error = clientapi.Error
+
+var (
+	ErrUnknownCommand  = clientapi.ErrUnknownCommand
+	ErrInvalidArgument = clientapi.ErrInvalidArgument
+	ErrTransaction     = clientapi.ErrTransaction
+	ErrConflict        = clientapi.ErrConflict
+	ErrDeadlock        = clientapi.ErrDeadlock
+	ErrLockTimeout     = clientapi.ErrLockTimeout
+	ErrConditionNotMet = clientapi.ErrConditionNotMet
+
+	// ErrNotFound is returned by Get for keys that don't exist
+	ErrNotFound = clientapi.ErrNotFound
+	// ErrClosed is returned by the methods of a closed DB
+	ErrClosed = errors.New("meteor: database is closed")
+	// ErrTxDone is returned by the methods of a Tx after Commit or Rollback
+	ErrTxDone = clientapi.ErrTxDone
+)
+
+// commandError converts the error of a command to an *Error
+func commandError(err error) error {
+	return &Error{Code: common.ClassifyError(err), Message: err.Error()}
+}
@@ -0,0 +1,147 @@
+// Package meteor runs a Meteor database inside the process. It offers the commands of the server, with the same
+// transaction and lock managers, without the network.
+//
+//	db, err := meteor.Open("data", nil)
+//	...
+//	defer db.Close()
+//
+//	err = db.Put("user:1", "alice")
+//	value, err := db.Get("user:1")
+//
+//	tx, err := db.Begin(meteor.SnapshotIsolation)
+//	...
+//	err = tx.Put("user:1", "bob")
+//	err = tx.Commit()
+package meteor
+
+import (
+	"meteor/internal/clientapi"
+	"meteor/internal/commands"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// Options configures a DB. Zero values use the defaults.
+type Options struct {
+	// DisableWal keeps writes in memory only, they are lost when the DB is closed
+	DisableWal bool
+}
+
+// DB is an open database. It is safe for concurrent use.
+type DB struct {
+	dm *dbmanager.DBManager
+	// conn is the connection reference of the commands run outside a Tx
+	conn *net.Conn
+
+	// mu is held for reading by running commands, so Close waits for them
+	mu     sync.RWMutex
+	closed bool
+}
+
+// KeyValue is a key and its value, as returned by Scan and RGet
+type KeyValue = clientapi.KeyValue
+
+// Page is a page of results. Cursor is set when a limit cut the results short and continues after the page.
+type Page = clientapi.Page
+
+// ReadOptions page the results of Scan and RGet
+type ReadOptions = clientapi.ReadOptions
+
+// Open opens the database in dir, creating the directory if needed, and recovers its data from the WAL.
+// The directory is locked until Close, so it can't be opened twice.
+func Open(dir string, opts *Options) (*DB, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	dm, err := dbmanager.NewDBManager(dbmanager.Options{Dir: dir, UseWal: !opts.DisableWal})
+	if err != nil {
+		return nil, err
+	}
+	return &DB{dm: dm, conn: new(net.Conn)}, nil
+}
+
+// Close closes the database after the running commands finished. Transactions that weren't committed are lost.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	db.closed = true
+	return db.dm.Close()
+}
+
+// Exec runs any command, e.g. Exec("COUNT", "*"), and returns its text result
+func (db *DB) Exec(name string, args ...string) (string, error) {
+	return db.exec(db.conn, name, args...)
+}
+
+// exec runs a command for a connection. Transactions belong to the connection they were started on, so every
+// Tx has its own.
+func (db *DB) exec(conn *net.Conn, name string, args ...string) (string, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return "", ErrClosed
+	}
+
+	spec, ok := commands.Get(name)
+	if !ok {
+		return "", &Error{Code: CodeUnknownCommand, Message: "unknown operation " + strconv.Quote(name)}
+	}
+	cmd := &common.Command{Operation: name, Args: args, Connection: conn}
+	// Streamed results are written to a network connection
+	if commands.IsStream(cmd) {
+		return "", &Error{Code: CodeInvalidArgument, Message: "STREAM is not supported in process"}
+	}
+
+	result, err := spec.Handler(db.dm, cmd)
+	if err != nil {
+		return "", commandError(err)
+	}
+	return string(result), nil
+}
+
+// release rolls back the transactions still open on a connection and forgets the connection
+func (db *DB) release(conn *net.Conn) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if !db.closed {
+		commands.RollbackConnection(db.dm, conn)
+	}
+}
+
+// Get returns the value of a key, or ErrNotFound. Numbers, bools and JSON documents are returned as their JSON text.
+func (db *DB) Get(key string) (string, error) {
+	return clientapi.Get(db.Exec, key)
+}
+
+// Put sets the value of a key
+func (db *DB) Put(key, value string) error {
+	_, err := db.Exec("PUT", key, value)
+	return err
+}
+
+// Delete deletes a key
+func (db *DB) Delete(key string) error {
+	_, err := db.Exec("DELETE", key)
+	return err
+}
+
+// Scan returns the keys and values matching a condition, e.g. "$key LIKE 'user:%' AND $value > 10", in key order
+func (db *DB) Scan(condition string, opts *ReadOptions) (*Page, error) {
+	return clientapi.ReadPage(db.Exec, "SCAN", []string{condition}, opts)
+}
+
+// RGet returns the keys and values from startKey to endKey, both included, in key order
+func (db *DB) RGet(startKey, endKey string, opts *ReadOptions) (*Page, error) {
+	return clientapi.ReadPage(db.Exec, "RGET", []string{startKey, endKey}, opts)
+}
+
+// Count returns the number of keys matching a condition, "*" counts all keys
+func (db *DB) Count(condition string) (int64, error) {
+	return clientapi.Count(db.Exec, condition)
+}
@@ -0,0 +1,122 @@
+package meteor
+
+import (
+	"errors"
+	"meteor/internal/clientapi"
+	"meteor/internal/common"
+	"net"
+)
+
+// Isolation is the isolation level of a transaction
+type Isolation = clientapi.Isolation
+
+const (
+	ReadCommitted     = clientapi.ReadCommitted
+	RepeatableRead    = clientapi.RepeatableRead
+	SnapshotIsolation = clientapi.SnapshotIsolation
+	Serializable      = clientapi.Serializable
+)
+
+// Tx is a transaction. Its writes are applied when it commits, and it holds its locks until Commit or
+// Rollback. A Tx must not be used concurrently.
+type Tx struct {
+	db            *DB
+	conn          *net.Conn
+	transactionId string
+	done          bool
+}
+
+// Begin starts a transaction. An empty isolation level starts a READ_COMMITTED transaction.
+func (db *DB) Begin(isolation Isolation) (*Tx, error) {
+	var args []string
+	if isolation != "" {
+		args = append(args, string(isolation))
+	}
+
+	conn := new(net.Conn)
+	transactionId, err := db.exec(conn, "BEGIN", args...)
+	if err != nil {
+		db.release(conn)
+		return nil, err
+	}
+	return &Tx{db: db, conn: conn, transactionId: transactionId}, nil
+}
+
+// Update runs fn in a transaction and commits it. If fn returns an error the transaction is rolled back.
+func (db *DB) Update(isolation Isolation, fn func(tx *Tx) error) error {
+	tx, err := db.Begin(isolation)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Exec runs any command in the transaction and returns its text result. The transaction id is appended to args, after TXN for MGET, MDEL and AGG.
+func (tx *Tx) Exec(name string, args ...string) (string, error) {
+	if tx.done {
+		return "", ErrTxDone
+	}
+	return tx.db.exec(tx.conn, name, common.AppendTransactionId(name, args, tx.transactionId)...)
+}
+
+// Commit commits the transaction. The transaction can't be used afterwards, even if the commit failed.
+func (tx *Tx) Commit() error {
+	return tx.end("COMMIT")
+}
+
+// Rollback rolls the transaction back. Transactions already rolled back after an error are rolled back
+// without error.
+func (tx *Tx) Rollback() error {
+	err := tx.end("ROLLBACK")
+	if errors.Is(err, ErrTransaction) {
+		return nil
+	}
+	return err
+}
+
+func (tx *Tx) end(operation string) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	_, err := tx.db.exec(tx.conn, operation, tx.transactionId)
+	// Every Tx has its own connection, which is done with the transaction
+	tx.db.release(tx.conn)
+	return err
+}
+
+// Get returns the value of a key as seen by the transaction, or ErrNotFound
+func (tx *Tx) Get(key string) (string, error) {
+	return clientapi.Get(tx.Exec, key)
+}
+
+// Put sets the value of a key when the transaction commits
+func (tx *Tx) Put(key, value string) error {
+	_, err := tx.Exec("PUT", key, value)
+	return err
+}
+
+// Delete deletes a key when the transaction commits
+func (tx *Tx) Delete(key string) error {
+	_, err := tx.Exec("DELETE", key)
+	return err
+}
+
+// Scan returns the keys and values matching a condition as seen by the transaction
+func (tx *Tx) Scan(condition string, opts *ReadOptions) (*Page, error) {
+	return clientapi.ReadPage(tx.Exec, "SCAN", []string{condition}, opts)
+}
+
+// RGet returns the keys and values from startKey to endKey as seen by the transaction
+func (tx *Tx) RGet(startKey, endKey string, opts *ReadOptions) (*Page, error) {
+	return clientapi.ReadPage(tx.Exec, "RGET", []string{startKey, endKey}, opts)
+}
+
+// Count returns the number of keys matching a condition as seen by the transaction
+func (tx *Tx) Count(condition string) (int64, error) {
+	return clientapi.Count(tx.Exec, condition)
+}
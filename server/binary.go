@@ -0,0 +1,145 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"meteor/internal/commands"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"meteor/internal/parser"
+	"net"
+	"sync"
+)
+
+// maxConcurrentBinaryRequests limits the requests of one connection running concurrently
+const maxConcurrentBinaryRequests = 128
+
+// binarySession serves a connection in the binary protocol. Requests run in the order they arrive unless
+// the client flags them as concurrent; responses carry the request id, so they may be sent in any order.
+type binarySession struct {
+	dm   *dbmanager.DBManager
+	conn *net.Conn
+
+	writeMu sync.Mutex
+	writer  *bufio.Writer
+
+	inFlight sync.WaitGroup
+	slots    chan struct{}
+}
+
+func handleBinaryConnection(dm *dbmanager.DBManager, ctx context.Context, conn net.Conn, reader *bufio.Reader) {
+	binaryParser := parser.NewBinaryParser()
+	minVersion, maxVersion, err := binaryParser.ReadHandshake(reader)
+	if err != nil {
+		slog.Error("Failed to read binary handshake", "error", err)
+		conn.Close()
+		return
+	}
+
+	var version uint16
+	if minVersion <= parser.BinaryProtocolVersion && parser.BinaryProtocolVersion <= maxVersion {
+		version = parser.BinaryProtocolVersion
+	}
+	hello := binary.BigEndian.AppendUint16(parser.BinaryMagic[:], version)
+	if _, err := conn.Write(hello); err != nil {
+		slog.Error("Failed to write to connection", "error", err)
+		conn.Close()
+		return
+	}
+	if version == 0 {
+		// No common version, the client continues in the text protocol. Commands it sent after the handshake
+		// may already be buffered in the reader.
+		handleTextConnection(dm, ctx, &bufferedConn{Conn: conn, reader: reader})
+		return
+	}
+	defer conn.Close()
+
+	// Transactions belong to the connection they were started on, so all requests share one reference
+	connRef := &conn
+	session := &binarySession{
+		dm:     dm,
+		conn:   connRef,
+		writer: bufio.NewWriter(conn),
+		slots:  make(chan struct{}, maxConcurrentBinaryRequests),
+	}
+	defer dm.AuthManager.Logout(connRef)
+	defer trackConnection(dm, connRef, "binary")()
+	loginCertificateUser(dm, conn, connRef)
+	defer session.inFlight.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Closing connection", "remoteAddr", conn.RemoteAddr().String())
+			return
+		default:
+		}
+
+		request, err := binaryParser.ReadRequest(reader, connRef)
+		if err != nil {
+			if request != nil {
+				// The frame was read completely, so the connection can continue after the error
+				session.writeError(request.RequestId, common.ErrorCodeProtocol, err.Error())
+				continue
+			}
+			if err != io.EOF {
+				slog.Error("Failed to read from connection", "error", err)
+			}
+			slog.Info("Connection closed", "remoteAddr", conn.RemoteAddr().String())
+			return
+		}
+
+		if request.Flags&parser.BinaryFlagConcurrent == 0 {
+			session.execute(request)
+			continue
+		}
+
+		session.slots <- struct{}{}
+		session.inFlight.Add(1)
+		go func() {
+			defer func() {
+				<-session.slots
+				session.inFlight.Done()
+			}()
+			session.execute(request)
+		}()
+	}
+}
+
+func (s *binarySession) execute(request *parser.BinaryRequest) {
+	if commands.IsStream(request.Command) {
+		s.writeError(request.RequestId, common.ErrorCodeInvalidArgument, "STREAM is not supported over the binary protocol")
+		return
+	}
+
+	res, err := executeCommand(s.dm, request.Command)
+	if err != nil {
+		s.writeError(request.RequestId, common.ClassifyError(err), err.Error())
+		return
+	}
+	s.writeResponse(request.RequestId, parser.BinaryStatusOk, res)
+}
+
+func (s *binarySession) writeError(requestId uint32, code common.ErrorCode, message string) {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	s.writeResponse(requestId, parser.BinaryStatusError, append(payload, message...))
+}
+
+// writeResponse writes a response frame. Frames of concurrent requests must not interleave, so writes are serialized.
+func (s *binarySession) writeResponse(requestId uint32, status byte, payload []byte) {
+	header := binary.BigEndian.AppendUint32(nil, uint32(5+len(payload)))
+	header = binary.BigEndian.AppendUint32(header, requestId)
+	header = append(header, status)
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.writer.Write(header)
+	s.writer.Write(payload)
+	if err := s.writer.Flush(); err != nil && !errors.Is(err, net.ErrClosed) {
+		slog.Error("Failed to write to connection", "error", err)
+	}
+}
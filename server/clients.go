@@ -0,0 +1,20 @@
+package server
+
+import (
+	"meteor/internal/commands"
+	"meteor/internal/dbmanager"
+	"net"
+)
+
+// trackConnection registers the connection of a client and counts it until the returned function is called.
+// The handler of the connection calls it once it stopped running commands, so the transactions the client
+// left open, e.g. because CLIENT KILL closed the connection, are rolled back and release their locks.
+func trackConnection(dm *dbmanager.DBManager, conn *net.Conn, protocol string) func() {
+	dm.ClientManager.Register(conn, protocol)
+	activeConnections.Inc(protocol)
+	return func() {
+		commands.RollbackConnection(dm, conn)
+		dm.ClientManager.Unregister(conn)
+		activeConnections.Dec(protocol)
+	}
+}
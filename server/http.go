@@ -0,0 +1,574 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"meteor/internal/authmanager"
+	"meteor/internal/commands"
+	"meteor/internal/common"
+	"meteor/internal/config"
+	"meteor/internal/dbmanager"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// httpTransactionTimeout rolls back transactions whose client stopped using them
+	httpTransactionTimeout = 5 * time.Minute
+	// maxHttpBodyLength limits request bodies, the same limit RESP has for a bulk string
+	maxHttpBodyLength = 512 * 1024 * 1024
+)
+
+// httpTransaction is a transaction started over HTTP. Transactions belong to a connection, and HTTP requests
+// arrive on any connection, so each transaction has its own connection reference. Requests of a transaction
+// run one at a time.
+type httpTransaction struct {
+	mu            sync.Mutex
+	transactionId string
+	conn          *net.Conn
+	// user began the transaction, only requests of the same user can use it
+	user     string
+	lastUsed time.Time
+}
+
+// httpApi serves the JSON API. Every endpoint runs the registered commands, so HTTP clients get the same
+// validation and semantics as the other protocols.
+type httpApi struct {
+	dm *dbmanager.DBManager
+
+	mu           sync.Mutex
+	transactions map[string]*httpTransaction
+}
+
+// httpError is an error reply with the status code it is sent with
+type httpError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func runHttpServer(dm *dbmanager.DBManager, ctx context.Context, wg *sync.WaitGroup, port string, tlsConfig *tls.Config) {
+	defer wg.Done()
+	api := &httpApi{dm: dm, transactions: make(map[string]*httpTransaction)}
+	httpServer := &http.Server{
+		Addr:      config.Config.Host + ":" + port,
+		Handler:   api.routes(),
+		TLSConfig: tlsConfig,
+		ConnState: trackHttpConnection,
+	}
+
+	go func() {
+		<-ctx.Done()
+		slog.Info("Context cancelled, closing http server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+	go api.expireTransactions(ctx)
+
+	slog.Info("Http server started", "host", config.Config.Host, "port", port, "tls", tlsConfig != nil)
+	var err error
+	if tlsConfig != nil {
+		// The certificates come from the TLS config
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Failed to listen", "error", err)
+	}
+}
+
+func (api *httpApi) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /kv/{key...}", api.handle(api.getKey))
+	mux.HandleFunc("PUT /kv/{key...}", api.handle(api.putKey))
+	mux.HandleFunc("DELETE /kv/{key...}", api.handle(api.deleteKey))
+	mux.HandleFunc("POST /scan", api.handle(api.scan))
+	mux.HandleFunc("POST /txn", api.handle(api.beginTransaction))
+	mux.HandleFunc("POST /txn/{token}/commit", api.handle(api.endTransaction("COMMIT")))
+	mux.HandleFunc("POST /txn/{token}/rollback", api.handle(api.endTransaction("ROLLBACK")))
+	mux.HandleFunc("GET /commands", api.handle(api.listCommands))
+	mux.HandleFunc("POST /command", api.handle(api.command))
+	mux.HandleFunc("GET /metrics", api.metrics)
+	mux.HandleFunc("/", api.handle(func(r *http.Request) (int, any, error) {
+		return 0, nil, &httpError{status: http.StatusNotFound, code: "NOT_FOUND", message: "no endpoint " + r.Method + " " + r.URL.Path}
+	}))
+	return mux
+}
+
+// httpUserKey is the request context key of the authenticated user
+type httpUserKey struct{}
+
+// handle turns an endpoint returning a status and a value into a handler writing them as JSON. Once users
+// exist, requests authenticate with HTTP basic auth and their commands run as that user.
+func (api *httpApi) handle(endpoint func(r *http.Request) (int, any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxHttpBodyLength)
+		var status int
+		var body any
+		r, err := api.authenticate(r)
+		if err == nil {
+			status, body, err = endpoint(r)
+		}
+		if err != nil {
+			status, body = errorReply(err)
+		}
+		writeJson(w, status, body)
+	}
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="meteor"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("Failed to write http response", "error", err)
+	}
+}
+
+// authenticate checks the basic auth credentials of a request once access control is enabled, and adds the
+// user to the request context. Requests without credentials run as the user their client certificate names.
+func (api *httpApi) authenticate(r *http.Request) (*http.Request, error) {
+	if !api.dm.AuthManager.Enabled() {
+		return r, nil
+	}
+	name, password, ok := r.BasicAuth()
+	if ok {
+		if err := api.dm.AuthManager.Authenticate(name, password); err != nil {
+			return r, err
+		}
+	} else {
+		name = certificateUser(r.TLS)
+		if _, exists := api.dm.AuthManager.User(name); name == "" || !exists {
+			return r, authmanager.ErrAuthenticationRequired
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), httpUserKey{}, name)), nil
+}
+
+func requestUser(r *http.Request) string {
+	user, _ := r.Context().Value(httpUserKey{}).(string)
+	return user
+}
+
+// connection returns a new connection reference that runs commands as the user of the request. Connections
+// are released once their request or transaction is done.
+func (api *httpApi) connection(r *http.Request) *net.Conn {
+	conn := new(net.Conn)
+	if user := requestUser(r); user != "" {
+		api.dm.AuthManager.Login(conn, user)
+	}
+	return conn
+}
+
+// release logs a connection out and drops what the transaction manager keeps for it, like a closed connection.
+// Transactions still open on it are rolled back.
+func (api *httpApi) release(conn *net.Conn) {
+	commands.RollbackConnection(api.dm, conn)
+	api.dm.AuthManager.Logout(conn)
+}
+
+// errorReply maps command errors to a status code and a JSON error body
+func errorReply(err error) (int, any) {
+	var replyError *httpError
+	if !errors.As(err, &replyError) {
+		code := common.ClassifyError(err)
+		replyError = &httpError{status: httpStatusOf(code), code: code.String(), message: err.Error()}
+	}
+	return replyError.status, map[string]any{
+		"error": map[string]string{"code": replyError.code, "message": replyError.message},
+	}
+}
+
+func httpStatusOf(code common.ErrorCode) int {
+	switch code {
+	case common.ErrorCodeUnknownCommand, common.ErrorCodeTransaction:
+		return http.StatusNotFound
+	case common.ErrorCodeInvalidArgument, common.ErrorCodeProtocol:
+		return http.StatusBadRequest
+	case common.ErrorCodeConflict, common.ErrorCodeDeadlock, common.ErrorCodeLockTimeout:
+		return http.StatusConflict
+	case common.ErrorCodeConditionNotMet:
+		return http.StatusPreconditionFailed
+	case common.ErrorCodeAuthentication:
+		return http.StatusUnauthorized
+	case common.ErrorCodePermission:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func invalidRequest(format string, args ...any) error {
+	return &httpError{status: http.StatusBadRequest, code: common.ErrorCodeInvalidArgument.String(), message: fmt.Sprintf(format, args...)}
+}
+
+// call runs a registered command, inside the transaction of the txn query parameter if one is given
+func (api *httpApi) call(r *http.Request, operation string, args ...string) ([]byte, error) {
+	token := r.URL.Query().Get("txn")
+	if token == "" {
+		conn := api.connection(r)
+		defer api.release(conn)
+		return executeCommand(api.dm, &common.Command{Operation: operation, Args: args, Connection: conn})
+	}
+
+	txn, err := api.transaction(r, token)
+	if err != nil {
+		return nil, err
+	}
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	txn.lastUsed = time.Now()
+	args = common.AppendTransactionId(operation, args, txn.transactionId)
+	return executeCommand(api.dm, &common.Command{Operation: operation, Args: args, Connection: txn.conn})
+}
+
+func (api *httpApi) transaction(r *http.Request, token string) (*httpTransaction, error) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	txn, ok := api.transactions[token]
+	if !ok {
+		return nil, &httpError{status: http.StatusNotFound, code: common.ErrorCodeTransaction.String(), message: "transaction not found"}
+	}
+	if txn.user != requestUser(r) {
+		return nil, &httpError{status: http.StatusForbidden, code: common.ErrorCodePermission.String(), message: "transaction belongs to another user"}
+	}
+	return txn, nil
+}
+
+// GET /kv/{key} returns {"key": ..., "value": ...}. Values are returned in their JSON form.
+func (api *httpApi) getKey(r *http.Request) (int, any, error) {
+	key := r.PathValue("key")
+	res, err := api.call(r, "MGET", key)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var values []json.RawMessage
+	if err := json.Unmarshal(res, &values); err != nil {
+		return 0, nil, err
+	}
+	if len(values) != 1 || string(values[0]) == "null" {
+		return 0, nil, &httpError{status: http.StatusNotFound, code: "NOT_FOUND", message: "key not found"}
+	}
+	return http.StatusOK, map[string]any{"key": key, "value": values[0]}, nil
+}
+
+// putRequest is the body of PUT /kv/{key} with a JSON content type
+type putRequest struct {
+	Value     json.RawMessage `json:"value"`
+	Type      string          `json:"type"`
+	Condition string          `json:"condition"`
+	IfVersion *uint32         `json:"ifVersion"`
+}
+
+// PUT /kv/{key} stores the body as a string, or for a JSON content type the value of {"value": ...} with the
+// type of the JSON value unless a type is given
+func (api *httpApi) putKey(r *http.Request) (int, any, error) {
+	key := r.PathValue("key")
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return 0, nil, invalidRequest("failed to read body: %v", err)
+	}
+
+	args := []string{key, string(body)}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		args, err = putArgs(key, body)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	res, err := api.call(r, "PUT", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]string{"result": string(res)}, nil
+}
+
+func putArgs(key string, body []byte) ([]string, error) {
+	var request putRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, invalidRequest("invalid body: %v", err)
+	}
+	if len(request.Value) == 0 {
+		return nil, invalidRequest("body must have a value")
+	}
+
+	value, valueType, err := putValue(request.Value)
+	if err != nil {
+		return nil, err
+	}
+	if request.Type != "" {
+		valueType = request.Type
+	}
+
+	args := []string{key, value}
+	switch {
+	case request.Condition != "" && request.IfVersion != nil:
+		return nil, invalidRequest("condition and ifVersion can't be combined")
+	case request.Condition != "":
+		args = append(args, request.Condition)
+	case request.IfVersion != nil:
+		args = append(args, "IF_VERSION", strconv.FormatUint(uint64(*request.IfVersion), 10))
+	}
+	return append(args, "TYPE", valueType), nil
+}
+
+// putValue converts a JSON value to the text form and type PUT stores it as
+func putValue(raw json.RawMessage) (string, string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "", "", invalidRequest("invalid value: %v", err)
+	}
+
+	switch value := value.(type) {
+	case string:
+		return value, "string", nil
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return value.String(), "int64", nil
+		}
+		return value.String(), "float64", nil
+	case bool:
+		return strconv.FormatBool(value), "bool", nil
+	case nil:
+		return "", "", invalidRequest("value can't be null, use DELETE to remove a key")
+	default:
+		return string(raw), "json", nil
+	}
+}
+
+// DELETE /kv/{key}
+func (api *httpApi) deleteKey(r *http.Request) (int, any, error) {
+	res, err := api.call(r, "DELETE", r.PathValue("key"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]string{"result": string(res)}, nil
+}
+
+// scanRequest is the body of POST /scan
+type scanRequest struct {
+	Condition string `json:"condition"`
+	Order     string `json:"order"`
+	Limit     *int   `json:"limit"`
+	Offset    *int   `json:"offset"`
+	Cursor    string `json:"cursor"`
+}
+
+// POST /scan returns {"results": [{"key": ..., "value": ...}], "cursor": ...} in key order. The cursor is only
+// returned when a limit cut the results short.
+func (api *httpApi) scan(r *http.Request) (int, any, error) {
+	var request scanRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return 0, nil, invalidRequest("invalid body: %v", err)
+	}
+	if request.Condition == "" {
+		return 0, nil, invalidRequest("body must have a condition")
+	}
+
+	// Ordering the results always returns them as a page, so the reply has the same form with and without a limit
+	order := "ASC"
+	if request.Order != "" {
+		order = strings.ToUpper(request.Order)
+	}
+	args := []string{request.Condition, "ORDER", "BY", "key", order}
+	if request.Limit != nil {
+		args = append(args, "LIMIT", strconv.Itoa(*request.Limit))
+	}
+	if request.Offset != nil {
+		args = append(args, "OFFSET", strconv.Itoa(*request.Offset))
+	}
+	if request.Cursor != "" {
+		args = append(args, "CURSOR", request.Cursor)
+	}
+
+	res, err := api.call(r, "SCAN", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, json.RawMessage(res), nil
+}
+
+// beginRequest is the optional body of POST /txn
+type beginRequest struct {
+	Isolation string `json:"isolation"`
+}
+
+// POST /txn begins a transaction and returns its token. Requests pass the token as the txn query parameter
+// to run in the transaction.
+func (api *httpApi) beginTransaction(r *http.Request) (int, any, error) {
+	var request beginRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		return 0, nil, invalidRequest("invalid body: %v", err)
+	}
+
+	var args []string
+	if request.Isolation != "" {
+		args = append(args, request.Isolation)
+	}
+	txn := &httpTransaction{conn: api.connection(r), user: requestUser(r), lastUsed: time.Now()}
+	res, err := executeCommand(api.dm, &common.Command{Operation: "BEGIN", Args: args, Connection: txn.conn})
+	if err != nil {
+		api.release(txn.conn)
+		return 0, nil, err
+	}
+	txn.transactionId = string(res)
+
+	// Tokens can't be guessed, unlike transaction ids, so only the client that began the transaction can use it
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return 0, nil, err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	api.mu.Lock()
+	api.transactions[token] = txn
+	api.mu.Unlock()
+	return http.StatusCreated, map[string]string{"token": token}, nil
+}
+
+// POST /txn/{token}/commit and /txn/{token}/rollback end a transaction. The token can't be used afterwards,
+// even if the commit failed, since a failed commit rolls the transaction back.
+func (api *httpApi) endTransaction(operation string) func(r *http.Request) (int, any, error) {
+	return func(r *http.Request) (int, any, error) {
+		token := r.PathValue("token")
+		txn, err := api.transaction(r, token)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		res, err := api.finish(token, txn, operation)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, map[string]string{"result": string(res)}, nil
+	}
+}
+
+func (api *httpApi) finish(token string, txn *httpTransaction, operation string) ([]byte, error) {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+
+	api.mu.Lock()
+	delete(api.transactions, token)
+	api.mu.Unlock()
+	defer api.release(txn.conn)
+	return executeCommand(api.dm, &common.Command{Operation: operation, Args: []string{txn.transactionId}, Connection: txn.conn})
+}
+
+// expireTransactions rolls back transactions that weren't used for httpTransactionTimeout
+func (api *httpApi) expireTransactions(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			api.mu.Lock()
+			expired := make(map[string]*httpTransaction)
+			for token, txn := range api.transactions {
+				if txn.mu.TryLock() {
+					if now.Sub(txn.lastUsed) > httpTransactionTimeout {
+						expired[token] = txn
+					}
+					txn.mu.Unlock()
+				}
+			}
+			api.mu.Unlock()
+
+			for token, txn := range expired {
+				slog.Info("Rolling back expired http transaction", "transactionId", txn.transactionId)
+				if _, err := api.finish(token, txn, "ROLLBACK"); err != nil {
+					slog.Error("Failed to roll back expired http transaction", "transactionId", txn.transactionId, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// commandArg and commandInfo describe the CommandSpecs of the registry
+type commandArg struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Description string `json:"description"`
+}
+
+type commandInfo struct {
+	Name string `json:"name"`
+	// Category is the category a user needs a grant of to run the command
+	Category string       `json:"category"`
+	Args     []commandArg `json:"args"`
+}
+
+// GET /commands lists the registered commands and their arguments
+func (api *httpApi) listCommands(r *http.Request) (int, any, error) {
+	specs := commands.List()
+	infos := make([]commandInfo, 0, len(specs))
+	for _, spec := range specs {
+		info := commandInfo{Name: spec.Name, Category: spec.Category, Args: make([]commandArg, 0, len(spec.Args))}
+		for _, arg := range spec.Args {
+			info.Args = append(info.Args, commandArg(arg))
+		}
+		infos = append(infos, info)
+	}
+	return http.StatusOK, infos, nil
+}
+
+// commandRequest is the body of POST /command
+type commandRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+// POST /command runs any registered command and returns its text result, e.g. for COUNT or EXPLAIN
+func (api *httpApi) command(r *http.Request) (int, any, error) {
+	var request commandRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return 0, nil, invalidRequest("invalid body: %v", err)
+	}
+
+	spec, ok := commands.Get(request.Command)
+	if !ok {
+		return 0, nil, fmt.Errorf("unknown operation %q", request.Command)
+	}
+	if commands.IsStream(&common.Command{Operation: spec.Name, Args: request.Args}) {
+		return 0, nil, invalidRequest("STREAM is not supported over HTTP")
+	}
+	// Transactions are controlled with the /txn endpoints, so their tokens stay valid
+	switch strings.ToUpper(spec.Name) {
+	case "BEGIN", "COMMIT", "ROLLBACK":
+		return 0, nil, invalidRequest("use the /txn endpoints for %s", spec.Name)
+	}
+
+	res, err := api.call(r, spec.Name, request.Args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]string{"result": string(res)}, nil
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"log/slog"
+	"meteor/internal/authmanager"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"meteor/internal/metrics"
+	"meteor/internal/store"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+var activeConnections = metrics.Default.NewGauge("meteor_connections_active", "Open client connections by protocol.", "protocol")
+
+// isolationLevels are reported even without active transactions, so their series always exist
+var isolationLevels = []string{
+	common.TXN_ISOLATION_READ_COMMITTED,
+	common.TXN_ISOLATION_REPEATABLE_READ,
+	common.TXN_ISOLATION_SNAPSHOT_ISOLATION,
+	common.TXN_ISOLATION_SERIALIZABLE,
+}
+
+// trackHttpConnection counts the connections of the HTTP API, which the http server opens and closes itself
+func trackHttpConnection(conn net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		activeConnections.Inc("http")
+	case http.StateClosed, http.StateHijacked:
+		activeConnections.Dec("http")
+	}
+}
+
+// registerDbMetrics registers the metrics that are read from the database when they are scraped
+func registerDbMetrics(dm *dbmanager.DBManager) {
+	metrics.Default.NewGaugeFunc("meteor_transactions_active", "Active transactions by isolation level.", []string{"isolation"}, func() []metrics.Sample {
+		counts := dm.TransactionManager.CountByIsolationLevel()
+		samples := make([]metrics.Sample, 0, len(isolationLevels))
+		for _, isolationLevel := range isolationLevels {
+			samples = append(samples, metrics.Sample{LabelValues: []string{isolationLevel}, Value: float64(counts[isolationLevel])})
+		}
+		return samples
+	})
+
+	lockStatistic := func(name string) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			value, _ := dm.TransactionManager.GetLockStatistics()[name].(uint64)
+			return []metrics.Sample{{Value: float64(value)}}
+		}
+	}
+	metrics.Default.NewCounterFunc("meteor_lock_waits_total", "Lock requests that waited for another transaction.", nil, lockStatistic("lock_waits"))
+	metrics.Default.NewCounterFunc("meteor_lock_timeouts_total", "Lock requests that timed out waiting.", nil, lockStatistic("lock_timeouts"))
+	metrics.Default.NewCounterFunc("meteor_deadlocks_total", "Lock requests refused because they would deadlock.", nil, lockStatistic("deadlocks"))
+
+	metrics.Default.NewGaugeFunc("meteor_store_keys", "Keys in each shard of the buffer store and in each immutable store.", []string{"store", "shard"}, func() []metrics.Sample {
+		var samples []metrics.Sample
+		if bufferStore, ok := dm.StoreManager.BufferStore.(*store.BufferStore); ok {
+			sizes, err := bufferStore.ShardSizes()
+			if err != nil {
+				slog.Error("Failed to read store size", "error", err)
+			}
+			for shard, size := range sizes {
+				samples = append(samples, metrics.Sample{LabelValues: []string{"buffer", strconv.Itoa(shard)}, Value: float64(size)})
+			}
+		}
+		for i, immutableStore := range dm.StoreManager.ImmutableStores {
+			size, err := immutableStore.Size()
+			if err != nil {
+				slog.Error("Failed to read store size", "error", err)
+				continue
+			}
+			samples = append(samples, metrics.Sample{LabelValues: []string{"immutable", strconv.Itoa(i)}, Value: float64(size)})
+		}
+		return samples
+	})
+}
+
+// GET /metrics serves the metrics in the Prometheus text format. Once users exist, scrapers authenticate like
+// other requests and need the admin category.
+func (api *httpApi) metrics(w http.ResponseWriter, r *http.Request) {
+	r, err := api.authenticate(r)
+	if err == nil {
+		conn := api.connection(r)
+		_, err = api.dm.AuthManager.Authorize(conn, authmanager.CategoryAdmin, nil)
+		api.release(conn)
+	}
+	if err != nil {
+		status, body := errorReply(err)
+		writeJson(w, status, body)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := metrics.Default.Write(w); err != nil {
+		slog.Error("Failed to write metrics", "error", err)
+	}
+}
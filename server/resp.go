@@ -0,0 +1,870 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"meteor/internal/authmanager"
+	"meteor/internal/commands"
+	"meteor/internal/common"
+	"meteor/internal/dbmanager"
+	"meteor/internal/parser"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// respCommand runs a Redis command by translating it to registry commands and writes its reply. Errors are sent
+// to the client as error replies.
+type respCommand func(s *respSession, w *respWriter, args []string) error
+
+// respCommands maps the Redis commands with their own reply format. Other registry commands are passed through
+// unchanged and their result is sent as a bulk string.
+var respCommands map[string]respCommand
+
+func init() {
+	respCommands = map[string]respCommand{
+		"PING":        respPing,
+		"ECHO":        respEcho,
+		"SELECT":      respSelect,
+		"HELLO":       respHello,
+		"AUTH":        respAuth,
+		"COMMAND":     respCommandInfo,
+		"CLIENT":      respClient,
+		"MONITOR":     respMonitor,
+		"GET":         respGet,
+		"SET":         respSet,
+		"SETNX":       respSetNx,
+		"MGET":        respMget,
+		"MSET":        respMset,
+		"DEL":         respDel,
+		"UNLINK":      respDel,
+		"EXISTS":      respExists,
+		"INCR":        respCounter("INCR"),
+		"DECR":        respCounter("DECR"),
+		"INCRBY":      respCounter("INCRBY"),
+		"DECRBY":      respCounter("DECRBY"),
+		"INCRBYFLOAT": respCounter("INCRBYFLOAT"),
+		"SCAN":        respScan,
+		"KEYS":        respKeys,
+		"DBSIZE":      respDbSize,
+		"MULTI":       respMulti,
+		"EXEC":        respExec,
+		"DISCARD":     respDiscard,
+		"WATCH":       respWatch,
+		"UNWATCH":     respUnwatch,
+	}
+}
+
+// respSession is the state of a RESP connection: the negotiated protocol version, the commands queued after
+// MULTI and the SCAN cursors handed out to the client
+type respSession struct {
+	dm       *dbmanager.DBManager
+	conn     *net.Conn
+	protocol int
+
+	// transactionId is the transaction commands run in while EXEC or a multi-key command executes, empty otherwise
+	transactionId string
+	inMulti       bool
+	multiFailed   bool
+	queued        []*common.Command
+
+	// Redis clients expect numeric SCAN cursors, so the cursors of SCAN are handed out by id
+	cursors      map[uint64]string
+	nextCursorId uint64
+}
+
+// handleRespConnection serves a connection of the RESP listener. Requests are read from a buffered reader,
+// so pipelined requests are answered in order and the replies are flushed once no more requests are pending.
+func handleRespConnection(dm *dbmanager.DBManager, ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	defer dm.AuthManager.Logout(&conn)
+	defer trackConnection(dm, &conn, "resp")()
+	loginCertificateUser(dm, conn, &conn)
+
+	respParser := parser.NewRespParser()
+	reader := bufio.NewReader(conn)
+	writer := &respWriter{w: bufio.NewWriter(conn), protocol: 2}
+	session := &respSession{dm: dm, conn: &conn, protocol: 2, cursors: make(map[uint64]string)}
+
+	for {
+		if ctx.Err() != nil {
+			slog.Info("Closing connection", "remoteAddr", conn.RemoteAddr().String())
+			return
+		}
+
+		cmd, err := respParser.ReadCommand(reader, &conn)
+		if err != nil {
+			if err != io.EOF {
+				slog.Error("Failed to read from connection", "error", err)
+				writer.Error(err.Error())
+				writer.w.Flush()
+			}
+			slog.Info("Connection closed", "remoteAddr", conn.RemoteAddr().String())
+			return
+		}
+
+		quit := session.handle(writer, cmd)
+
+		if reader.Buffered() == 0 || quit {
+			if err := writer.w.Flush(); err != nil {
+				slog.Error("Failed to write to connection", "error", err)
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// handle runs a request, or queues it after MULTI. It returns true if the client asked to close the connection.
+func (s *respSession) handle(w *respWriter, cmd *common.Command) bool {
+	name := strings.ToUpper(cmd.Operation)
+	if name == "QUIT" {
+		w.SimpleString("OK")
+		return true
+	}
+
+	if s.inMulti && !slices.Contains([]string{"EXEC", "DISCARD", "MULTI", "WATCH"}, name) {
+		if !s.isKnownCommand(name) {
+			s.multiFailed = true
+			w.Error(fmt.Sprintf("unknown command '%s'", cmd.Operation))
+			return false
+		}
+		s.queued = append(s.queued, cmd)
+		w.SimpleString("QUEUED")
+		return false
+	}
+
+	if err := s.run(w, name, cmd.Args); err != nil {
+		writeRespError(w, err)
+	}
+	return false
+}
+
+// writeRespError sends a command error. Authentication errors get the codes Redis clients expect.
+func writeRespError(w *respWriter, err error) {
+	switch common.ClassifyError(err) {
+	case common.ErrorCodeAuthentication:
+		if errors.Is(err, authmanager.ErrInvalidCredentials) {
+			w.CodedError("WRONGPASS", "invalid username-password pair or user is disabled.")
+		} else {
+			w.CodedError("NOAUTH", "Authentication required.")
+		}
+	case common.ErrorCodePermission:
+		w.CodedError("NOPERM", err.Error())
+	default:
+		w.Error(err.Error())
+	}
+}
+
+func (s *respSession) isKnownCommand(name string) bool {
+	if _, ok := respCommands[name]; ok {
+		return true
+	}
+	_, ok := commands.Get(name)
+	return ok
+}
+
+// run executes a command, either one with a Redis reply format or a registry command passed through
+func (s *respSession) run(w *respWriter, name string, args []string) error {
+	if command, ok := respCommands[name]; ok {
+		return command(s, w, args)
+	}
+
+	if _, ok := commands.Get(name); !ok {
+		return fmt.Errorf("unknown command '%s'", name)
+	}
+	// Streamed results are written to the connection in the text format, and transactions are managed by MULTI
+	if commands.IsStream(&common.Command{Operation: name, Args: args}) {
+		return errors.New("STREAM is not supported over RESP")
+	}
+	if s.transactionId != "" && slices.Contains([]string{"BEGIN", "COMMIT", "ROLLBACK"}, name) {
+		return fmt.Errorf("%s is not allowed in MULTI", name)
+	}
+
+	res, err := s.call(name, args...)
+	if err != nil {
+		return err
+	}
+	w.Bulk(string(res))
+	return nil
+}
+
+// call runs a registry command, in the session's transaction if there is one
+func (s *respSession) call(operation string, args ...string) ([]byte, error) {
+	if s.transactionId != "" {
+		args = common.AppendTransactionId(operation, args, s.transactionId)
+	}
+	return executeCommand(s.dm, &common.Command{Operation: operation, Args: args, Connection: s.conn})
+}
+
+// client runs the registry CLIENT command, which doesn't take a transaction id even while EXEC runs
+func (s *respSession) client(args ...string) ([]byte, error) {
+	return executeCommand(s.dm, &common.Command{Operation: "CLIENT", Args: args, Connection: s.conn})
+}
+
+// inTransaction runs fn in one transaction, so commands made of several registry commands are atomic.
+// If the session is already in a transaction, fn runs in it.
+func (s *respSession) inTransaction(fn func() error) error {
+	if s.transactionId != "" {
+		return fn()
+	}
+
+	res, err := s.call("BEGIN")
+	if err != nil {
+		return err
+	}
+	s.transactionId = string(res)
+	defer func() { s.transactionId = "" }()
+
+	if err := fn(); err != nil {
+		// A failed command has already rolled the transaction back, this only releases what is left
+		_, _ = s.call("ROLLBACK")
+		return err
+	}
+	_, err = s.call("COMMIT")
+	return err
+}
+
+// getValues reads keys with MGET. Missing and deleted keys are nil.
+func (s *respSession) getValues(keys []string) ([]*string, error) {
+	res, err := s.call("MGET", keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	var encoded []json.RawMessage
+	if err := json.Unmarshal(res, &encoded); err != nil {
+		return nil, err
+	}
+
+	values := make([]*string, len(encoded))
+	for i, raw := range encoded {
+		switch {
+		case string(raw) == "null":
+			continue
+		case raw[0] == '"':
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return nil, err
+			}
+			values[i] = &value
+		default:
+			// Numbers, bools and JSON documents are sent in their JSON form
+			value := string(raw)
+			values[i] = &value
+		}
+	}
+	return values, nil
+}
+
+func wrongArgs(name string) error {
+	return fmt.Errorf("wrong number of arguments for '%s' command", strings.ToLower(name))
+}
+
+func writeBulkOrNull(w *respWriter, value *string) {
+	if value == nil {
+		w.Null()
+		return
+	}
+	w.Bulk(*value)
+}
+
+func respPing(s *respSession, w *respWriter, args []string) error {
+	switch len(args) {
+	case 0:
+		w.SimpleString("PONG")
+	case 1:
+		w.Bulk(args[0])
+	default:
+		return wrongArgs("ping")
+	}
+	return nil
+}
+
+func respEcho(s *respSession, w *respWriter, args []string) error {
+	if len(args) != 1 {
+		return wrongArgs("echo")
+	}
+	w.Bulk(args[0])
+	return nil
+}
+
+// respSelect accepts database 0 only, Meteor has a single keyspace
+func respSelect(s *respSession, w *respWriter, args []string) error {
+	if len(args) != 1 {
+		return wrongArgs("select")
+	}
+	if args[0] != "0" {
+		return errors.New("DB index is out of range")
+	}
+	w.SimpleString("OK")
+	return nil
+}
+
+// respAuth authenticates the connection. AUTH <password> authenticates as the user named default, as in Redis.
+func respAuth(s *respSession, w *respWriter, args []string) error {
+	switch len(args) {
+	case 1:
+		args = []string{"default", args[0]}
+	case 2:
+	default:
+		return wrongArgs("auth")
+	}
+
+	if _, err := s.call("AUTH", args...); err != nil {
+		return err
+	}
+	w.SimpleString("OK")
+	return nil
+}
+
+// respHello switches the protocol version and describes the server
+func respHello(s *respSession, w *respWriter, args []string) error {
+	if len(args) > 0 {
+		protocol, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.New("Protocol version is not an integer or out of range")
+		}
+		if protocol != 2 && protocol != 3 {
+			w.CodedError("NOPROTO", "unsupported protocol version")
+			return nil
+		}
+
+		// Options are parsed first and applied like Redis does, AUTH before SETNAME, so a name is only set for
+		// an authenticated connection
+		var auth []string
+		var name *string
+		for rest := args[1:]; len(rest) > 0; {
+			switch {
+			case strings.EqualFold(rest[0], "SETNAME") && len(rest) >= 2:
+				name = &rest[1]
+				rest = rest[2:]
+			case strings.EqualFold(rest[0], "AUTH") && len(rest) >= 3:
+				auth = rest[1:3]
+				rest = rest[3:]
+			default:
+				return fmt.Errorf("syntax error in HELLO option '%s'", rest[0])
+			}
+		}
+
+		if auth != nil {
+			if _, err := s.call("AUTH", auth...); err != nil {
+				writeRespError(w, err)
+				return nil
+			}
+		}
+		if name != nil {
+			if _, err := s.client("SETNAME", *name); err != nil {
+				return err
+			}
+		}
+		s.protocol, w.protocol = protocol, protocol
+	}
+
+	w.MapHeader(6)
+	w.Bulk("server")
+	w.Bulk("meteor")
+	w.Bulk("proto")
+	w.Integer(int64(s.protocol))
+	w.Bulk("mode")
+	w.Bulk("standalone")
+	w.Bulk("role")
+	w.Bulk("master")
+	w.Bulk("modules")
+	w.ArrayHeader(0)
+	w.Bulk("version")
+	w.Bulk("1.0.0")
+	return nil
+}
+
+// respCommandInfo answers COMMAND and its subcommands, which clients send on connect, with no command details
+func respCommandInfo(s *respSession, w *respWriter, args []string) error {
+	w.ArrayHeader(0)
+	return nil
+}
+
+// respClient answers CLIENT with the registry command. LIST is formatted as lines of key=value fields, the way
+// Redis clients parse it.
+func respClient(s *respSession, w *respWriter, args []string) error {
+	if len(args) == 0 {
+		return wrongArgs("client")
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "SETNAME":
+		if len(args) != 2 {
+			return wrongArgs("client|setname")
+		}
+		if _, err := s.client(args...); err != nil {
+			return err
+		}
+		w.SimpleString("OK")
+	case "GETNAME":
+		res, err := s.client("GETNAME")
+		if err != nil {
+			return err
+		}
+		if len(res) == 0 {
+			w.Null()
+		} else {
+			w.Bulk(string(res))
+		}
+	case "ID":
+		res, err := s.client("ID")
+		if err != nil {
+			return err
+		}
+		id, err := strconv.ParseInt(string(res), 10, 64)
+		if err != nil {
+			return err
+		}
+		w.Integer(id)
+	case "LIST":
+		res, err := s.client("LIST")
+		if err != nil {
+			return err
+		}
+		var clients []struct {
+			Id           uint64   `json:"id"`
+			Name         string   `json:"name"`
+			Addr         string   `json:"addr"`
+			User         string   `json:"user"`
+			AgeSeconds   int64    `json:"age_seconds"`
+			IdleSeconds  int64    `json:"idle_seconds"`
+			LastCommand  string   `json:"last_command"`
+			Transactions []uint32 `json:"transactions"`
+		}
+		if err := json.Unmarshal(res, &clients); err != nil {
+			return err
+		}
+		var lines strings.Builder
+		for _, client := range clients {
+			fmt.Fprintf(&lines, "id=%d addr=%s name=%s age=%d idle=%d user=%s cmd=%s txns=%d\n",
+				client.Id, client.Addr, client.Name, client.AgeSeconds, client.IdleSeconds, client.User,
+				strings.ToLower(client.LastCommand), len(client.Transactions))
+		}
+		w.Bulk(lines.String())
+	case "KILL":
+		// The old form CLIENT KILL addr replies OK, the filter form the number of killed clients
+		if len(args) == 2 {
+			if _, err := s.client("KILL", "ADDR", args[1]); err != nil {
+				return err
+			}
+			w.SimpleString("OK")
+			return nil
+		}
+		if len(args) != 3 {
+			return wrongArgs("client|kill")
+		}
+		if _, err := s.client(args...); err != nil {
+			return err
+		}
+		w.Integer(1)
+	case "SETINFO":
+		w.SimpleString("OK")
+	default:
+		return fmt.Errorf("unknown subcommand '%s'", args[0])
+	}
+	return nil
+}
+
+// respMonitor runs the registry MONITOR, which writes its events to the connection as simple strings. Pending
+// replies are flushed first, so they arrive before the events.
+func respMonitor(s *respSession, w *respWriter, args []string) error {
+	if s.transactionId != "" {
+		return errors.New("MONITOR is not allowed in MULTI")
+	}
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	_, err := executeCommand(s.dm, &common.Command{Operation: "MONITOR", Args: args, Connection: s.conn})
+	return err
+}
+
+func respGet(s *respSession, w *respWriter, args []string) error {
+	if len(args) != 1 {
+		return wrongArgs("get")
+	}
+	values, err := s.getValues(args)
+	if err != nil {
+		return err
+	}
+	writeBulkOrNull(w, values[0])
+	return nil
+}
+
+// respSet maps SET key value [NX|XX] to PUT. A condition that isn't met replies null, unless the SET runs in
+// a transaction which the failed condition has rolled back.
+func respSet(s *respSession, w *respWriter, args []string) error {
+	if len(args) < 2 {
+		return wrongArgs("set")
+	}
+
+	putArgs := []string{args[0], args[1]}
+	for _, option := range args[2:] {
+		option = strings.ToUpper(option)
+		if (option != "NX" && option != "XX") || len(putArgs) > 2 {
+			return errors.New("syntax error, SET supports only one of NX or XX")
+		}
+		putArgs = append(putArgs, option)
+	}
+
+	_, err := s.call("PUT", putArgs...)
+	if err != nil && len(putArgs) > 2 && s.transactionId == "" && strings.HasPrefix(err.Error(), "condition not met") {
+		w.Null()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	w.SimpleString("OK")
+	return nil
+}
+
+func respSetNx(s *respSession, w *respWriter, args []string) error {
+	if len(args) != 2 {
+		return wrongArgs("setnx")
+	}
+
+	_, err := s.call("PUT", args[0], args[1], "NX")
+	if err != nil && s.transactionId == "" && strings.HasPrefix(err.Error(), "condition not met") {
+		w.Integer(0)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	w.Integer(1)
+	return nil
+}
+
+func respMget(s *respSession, w *respWriter, args []string) error {
+	if len(args) == 0 {
+		return wrongArgs("mget")
+	}
+	values, err := s.getValues(args)
+	if err != nil {
+		return err
+	}
+	w.ArrayHeader(len(values))
+	for _, value := range values {
+		writeBulkOrNull(w, value)
+	}
+	return nil
+}
+
+func respMset(s *respSession, w *respWriter, args []string) error {
+	if len(args) == 0 || len(args)%2 != 0 {
+		return wrongArgs("mset")
+	}
+	if _, err := s.call("MSET", args...); err != nil {
+		return err
+	}
+	w.SimpleString("OK")
+	return nil
+}
+
+// respDel replies the number of keys that existed, so the keys are read and deleted in one transaction
+func respDel(s *respSession, w *respWriter, args []string) error {
+	if len(args) == 0 {
+		return wrongArgs("del")
+	}
+
+	var deleted int64
+	err := s.inTransaction(func() error {
+		keys := slices.Compact(slices.Sorted(slices.Values(args)))
+		values, err := s.getValues(keys)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			if value != nil {
+				deleted++
+			}
+		}
+		_, err = s.call("MDEL", keys...)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.Integer(deleted)
+	return nil
+}
+
+// respExists counts the keys that exist, keys given several times are counted every time
+func respExists(s *respSession, w *respWriter, args []string) error {
+	if len(args) == 0 {
+		return wrongArgs("exists")
+	}
+	values, err := s.getValues(args)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	for _, value := range values {
+		if value != nil {
+			count++
+		}
+	}
+	w.Integer(count)
+	return nil
+}
+
+// respCounter runs INCR, DECR, INCRBY, DECRBY and INCRBYFLOAT, which take the same arguments as the
+// registry commands of the same name
+func respCounter(name string) respCommand {
+	return func(s *respSession, w *respWriter, args []string) error {
+		res, err := s.call(name, args...)
+		if err != nil {
+			return err
+		}
+		if name == "INCRBYFLOAT" {
+			w.Bulk(string(res))
+			return nil
+		}
+		n, err := strconv.ParseInt(string(res), 10, 64)
+		if err != nil {
+			return errors.New("value is not an integer or out of range")
+		}
+		w.Integer(n)
+		return nil
+	}
+}
+
+// respScan maps SCAN cursor [MATCH pattern] [COUNT n] to a paged SCAN of the keys matching the pattern
+func respScan(s *respSession, w *respWriter, args []string) error {
+	if len(args) == 0 {
+		return wrongArgs("scan")
+	}
+
+	condition := "*"
+	count := 10
+	for rest := args[1:]; len(rest) > 0; rest = rest[2:] {
+		if len(rest) < 2 {
+			return errors.New("syntax error")
+		}
+		switch strings.ToUpper(rest[0]) {
+		case "MATCH":
+			likeCondition, err := globToCondition(rest[1])
+			if err != nil {
+				return err
+			}
+			condition = likeCondition
+		case "COUNT":
+			n, err := strconv.Atoi(rest[1])
+			if err != nil || n < 1 {
+				return errors.New("value is not an integer or out of range")
+			}
+			count = n
+		default:
+			return errors.New("syntax error")
+		}
+	}
+
+	scanArgs := []string{condition, "LIMIT", strconv.Itoa(count)}
+	if args[0] != "0" {
+		cursorId, err := strconv.ParseUint(args[0], 10, 64)
+		cursor, ok := s.cursors[cursorId]
+		if err != nil || !ok {
+			return errors.New("invalid cursor")
+		}
+		delete(s.cursors, cursorId)
+		scanArgs = append(scanArgs, "CURSOR", cursor)
+	}
+
+	res, err := s.call("SCAN", scanArgs...)
+	if err != nil {
+		return err
+	}
+	var page struct {
+		Results []struct {
+			Key string `json:"key"`
+		} `json:"results"`
+		Cursor string `json:"cursor"`
+	}
+	if err := json.Unmarshal(res, &page); err != nil {
+		return err
+	}
+
+	nextCursor := "0"
+	if page.Cursor != "" {
+		s.nextCursorId++
+		s.cursors[s.nextCursorId] = page.Cursor
+		nextCursor = strconv.FormatUint(s.nextCursorId, 10)
+	}
+
+	keys := make([]string, len(page.Results))
+	for i, row := range page.Results {
+		keys[i] = row.Key
+	}
+	w.ArrayHeader(2)
+	w.Bulk(nextCursor)
+	w.BulkArray(keys)
+	return nil
+}
+
+func respKeys(s *respSession, w *respWriter, args []string) error {
+	if len(args) != 1 {
+		return wrongArgs("keys")
+	}
+	condition, err := globToCondition(args[0])
+	if err != nil {
+		return err
+	}
+
+	res, err := s.call("SCAN", condition)
+	if err != nil {
+		return err
+	}
+	var results map[string]json.RawMessage
+	if err := json.Unmarshal(res, &results); err != nil {
+		return err
+	}
+
+	w.BulkArray(slices.Sorted(func(yield func(string) bool) {
+		for key := range results {
+			if !yield(key) {
+				return
+			}
+		}
+	}))
+	return nil
+}
+
+func respDbSize(s *respSession, w *respWriter, args []string) error {
+	if len(args) != 0 {
+		return wrongArgs("dbsize")
+	}
+	res, err := s.call("COUNT", "*")
+	if err != nil {
+		return err
+	}
+	n, err := strconv.ParseInt(string(res), 10, 64)
+	if err != nil {
+		return err
+	}
+	w.Integer(n)
+	return nil
+}
+
+func respMulti(s *respSession, w *respWriter, args []string) error {
+	if s.inMulti {
+		return errors.New("MULTI calls can not be nested")
+	}
+	s.inMulti = true
+	w.SimpleString("OK")
+	return nil
+}
+
+// respExec runs the queued commands in one transaction. A command failing rolls the whole transaction back,
+// so EXEC then replies an EXECABORT error instead of the replies of the commands.
+func respExec(s *respSession, w *respWriter, args []string) error {
+	if !s.inMulti {
+		return errors.New("EXEC without MULTI")
+	}
+	queued, failed := s.queued, s.multiFailed
+	s.inMulti, s.queued, s.multiFailed = false, nil, false
+
+	if failed {
+		w.CodedError("EXECABORT", "Transaction discarded because of previous errors.")
+		return nil
+	}
+
+	replies := make([]bytes.Buffer, len(queued))
+	err := s.inTransaction(func() error {
+		for i, cmd := range queued {
+			replyWriter := &respWriter{w: bufio.NewWriter(&replies[i]), protocol: s.protocol}
+			if err := s.run(replyWriter, strings.ToUpper(cmd.Operation), cmd.Args); err != nil {
+				return err
+			}
+			replyWriter.w.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		w.CodedError("EXECABORT", "Transaction discarded because of: "+err.Error())
+		return nil
+	}
+
+	w.ArrayHeader(len(replies))
+	for _, reply := range replies {
+		w.Raw(reply.Bytes())
+	}
+	return nil
+}
+
+func respDiscard(s *respSession, w *respWriter, args []string) error {
+	if !s.inMulti {
+		return errors.New("DISCARD without MULTI")
+	}
+	s.inMulti, s.queued, s.multiFailed = false, nil, false
+	w.SimpleString("OK")
+	return nil
+}
+
+// respWatch rejects optimistic locking, transactions take locks when EXEC runs them instead
+func respWatch(s *respSession, w *respWriter, args []string) error {
+	if s.inMulti {
+		return errors.New("WATCH inside MULTI is not allowed")
+	}
+	return errors.New("WATCH is not supported, MULTI/EXEC transactions take locks when they run")
+}
+
+func respUnwatch(s *respSession, w *respWriter, args []string) error {
+	w.SimpleString("OK")
+	return nil
+}
+
+// globToCondition translates a Redis glob pattern to a $key LIKE condition. * and ? become % and _, a backslash
+// escapes the next character. Character classes aren't supported.
+func globToCondition(pattern string) (string, error) {
+	var like strings.Builder
+	escaped := false
+	for _, char := range pattern {
+		switch {
+		case escaped:
+			if char == '%' || char == '_' || char == '\\' {
+				like.WriteRune('\\')
+			}
+			like.WriteRune(char)
+			escaped = false
+		case char == '\\':
+			escaped = true
+		case char == '*':
+			like.WriteRune('%')
+		case char == '?':
+			like.WriteRune('_')
+		case char == '[':
+			return "", errors.New("character classes are not supported in patterns")
+		case char == '%' || char == '_':
+			like.WriteRune('\\')
+			like.WriteRune(char)
+		default:
+			like.WriteRune(char)
+		}
+	}
+
+	if pattern == "*" {
+		return "*", nil
+	}
+	likePattern := like.String()
+	switch {
+	case !strings.ContainsRune(likePattern, '\''):
+		return "$key LIKE '" + likePattern + "'", nil
+	case !strings.ContainsRune(likePattern, '"'):
+		return "$key LIKE \"" + likePattern + "\"", nil
+	default:
+		return "", errors.New("patterns with both ' and \" are not supported")
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+)
+
+// respWriter encodes replies in the RESP version negotiated by the client with HELLO. RESP2 has no null
+// or map types, so they are sent as a null bulk string and a flat array of keys and values.
+type respWriter struct {
+	w        *bufio.Writer
+	protocol int
+}
+
+func (w *respWriter) SimpleString(s string) {
+	w.w.WriteString("+" + s + "\r\n")
+}
+
+// Error sends an error reply with the generic ERR code
+func (w *respWriter) Error(message string) {
+	w.CodedError("ERR", message)
+}
+
+// CodedError sends an error reply with a specific code, e.g. EXECABORT
+func (w *respWriter) CodedError(code, message string) {
+	// Error replies are a single line
+	message = strings.NewReplacer("\r", " ", "\n", " ").Replace(message)
+	w.w.WriteString("-" + code + " " + message + "\r\n")
+}
+
+func (w *respWriter) Integer(n int64) {
+	w.w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func (w *respWriter) Bulk(s string) {
+	w.w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func (w *respWriter) Null() {
+	if w.protocol >= 3 {
+		w.w.WriteString("_\r\n")
+		return
+	}
+	w.w.WriteString("$-1\r\n")
+}
+
+func (w *respWriter) ArrayHeader(n int) {
+	w.w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
+
+// MapHeader starts a map of n key value pairs, which follow as 2n replies
+func (w *respWriter) MapHeader(n int) {
+	if w.protocol >= 3 {
+		w.w.WriteString("%" + strconv.Itoa(n) + "\r\n")
+		return
+	}
+	w.ArrayHeader(2 * n)
+}
+
+// BulkArray sends an array of bulk strings
+func (w *respWriter) BulkArray(values []string) {
+	w.ArrayHeader(len(values))
+	for _, value := range values {
+		w.Bulk(value)
+	}
+}
+
+// Raw writes already encoded replies
+func (w *respWriter) Raw(encoded []byte) {
+	w.w.Write(encoded)
+}
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"log/slog"
@@ -10,6 +12,8 @@ import (
 	"meteor/internal/config"
 	"meteor/internal/dbmanager"
 	"meteor/internal/logger"
+	"meteor/internal/parser"
+	"meteor/internal/slowlog"
 	"net"
 	"os"
 	"os/signal"
@@ -23,37 +27,83 @@ func Init() {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	dm, err := dbmanager.NewDBManager()
+	dm, err := dbmanager.NewDBManager(dbmanager.Options{
+		Dir:         config.Config.DataDir,
+		UseWal:      config.Config.UseWal,
+		EnforceAuth: true,
+		SlowLog: slowlog.Options{
+			Threshold:  config.Config.SlowlogThreshold,
+			MaxEntries: config.Config.SlowlogMaxLen,
+			RedactArgs: config.Config.SlowlogRedactArgs,
+			File:       config.Config.SlowlogFile,
+		},
+	})
 	if err != nil {
 		slog.Error("Failed to initialize database", "error", err)
 		cancel()
-		return
+		// Supervisors running several instances need to see the failure, e.g. of a data directory in use
+		os.Exit(1)
+	}
+
+	tlsManager, err := newTlsManager(config.Config)
+	if err != nil {
+		slog.Error("Failed to configure TLS", "error", err)
+		cancel()
+		dm.Close()
+		os.Exit(1)
+	}
+	// All listeners use TLS once a certificate is configured
+	var tlsConfig *tls.Config
+	if tlsManager != nil {
+		tlsConfig = tlsManager.config()
+		go tlsManager.reloadOnHangup(ctx)
 	}
 
+	registerDbMetrics(dm)
 	go handleShutdown(cancel)
 
-	go runServer(dm, ctx, wg)
+	wg.Add(1)
+	go runServer(dm, ctx, wg, config.Config.Port, tlsConfig, handleConnection)
+
+	// Redis clients connect to a separate port, since the protocols can't be told apart reliably
+	if config.Config.RespPort != "" {
+		wg.Add(1)
+		go runServer(dm, ctx, wg, config.Config.RespPort, tlsConfig, handleRespConnection)
+	}
+
+	if config.Config.HttpPort != "" {
+		wg.Add(1)
+		go runHttpServer(dm, ctx, wg, config.Config.HttpPort, tlsConfig)
+	}
 
 	wg.Wait()
+	if err := dm.Close(); err != nil {
+		slog.Error("Failed to close database", "error", err)
+	}
 	slog.Info("Server stopped")
 }
 
-func runServer(dm *dbmanager.DBManager, ctx context.Context, wg *sync.WaitGroup) {
+// connectionHandler serves a client connection in one of the supported protocols
+type connectionHandler func(dm *dbmanager.DBManager, ctx context.Context, conn net.Conn)
+
+func runServer(dm *dbmanager.DBManager, ctx context.Context, wg *sync.WaitGroup, port string, tlsConfig *tls.Config, handler connectionHandler) {
 	defer wg.Done()
-	ln, err := net.Listen("tcp", config.Config.Host+":"+config.Config.Port)
+	ln, err := net.Listen("tcp", config.Config.Host+":"+port)
 
 	if err != nil {
 		slog.Error("Failed to listen", "error", err)
 		return
 	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
 	defer ln.Close()
-	slog.Info("Server started", "host", config.Config.Host, "port", config.Config.Port)
-	listenForConnections(dm, ctx, ln)
+	slog.Info("Server started", "host", config.Config.Host, "port", port, "tls", tlsConfig != nil)
+	listenForConnections(dm, ctx, ln, handler)
 }
 
-func listenForConnections(dm *dbmanager.DBManager, ctx context.Context, listener net.Listener) {
+func listenForConnections(dm *dbmanager.DBManager, ctx context.Context, listener net.Listener, handler connectionHandler) {
 	// When the context is cancelled, Close() the listener to unblock Accept()
 	go func() {
 		<-ctx.Done()
@@ -75,12 +125,51 @@ func listenForConnections(dm *dbmanager.DBManager, ctx context.Context, listener
 		}
 
 		slog.Info("Accepted connection", "remoteAddr", conn.RemoteAddr().String())
-		go handleConnection(dm, ctx, conn)
+		go func() {
+			if err := handshake(conn); err != nil {
+				slog.Error("TLS handshake failed", "remoteAddr", conn.RemoteAddr().String(), "error", err)
+				conn.Close()
+				return
+			}
+			handler(dm, ctx, conn)
+		}()
 	}
 }
 
+// handleConnection serves text and binary clients on the same port. Binary clients start with a hello whose
+// first byte is zero, which no text command starts with.
 func handleConnection(dm *dbmanager.DBManager, ctx context.Context, conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	first, err := reader.Peek(1)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	if first[0] == parser.BinaryMagic[0] {
+		handleBinaryConnection(dm, ctx, conn, reader)
+		return
+	}
+	// The peeked bytes are read again through the buffered connection
+	handleTextConnection(dm, ctx, &bufferedConn{Conn: conn, reader: reader})
+}
+
+// bufferedConn is a connection whose reads go through a reader that may already hold data from the connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// handleTextConnection serves the text protocol, which reads one command per read from the connection
+func handleTextConnection(dm *dbmanager.DBManager, ctx context.Context, conn net.Conn) {
 	defer conn.Close()
+	defer dm.AuthManager.Logout(&conn)
+	defer trackConnection(dm, &conn, "text")()
+	loginCertificateUser(dm, conn, &conn)
 
 	for {
 		select {
@@ -111,8 +200,6 @@ func handleConnection(dm *dbmanager.DBManager, ctx context.Context, conn net.Con
 				res = []byte(fmt.Sprintf("error: %s\n", err))
 			}
 
-			fmt.Println("Command parsed", "command", cmd)
-
 			_, err = conn.Write(res)
 			if err != nil {
 				slog.Error("Failed to write to connection", "error", err)
@@ -131,15 +218,24 @@ func handleShutdown(contextCancel context.CancelFunc) {
 }
 
 func performOperation(dm *dbmanager.DBManager, cmd *common.Command) ([]byte, error) {
-	spec, ok := commands.Get(cmd.Operation)
-    if !ok {
-        return nil, fmt.Errorf("unknown operation %q", cmd.Operation)
-    }
-
-    res, err := spec.Handler(dm, cmd)
+	res, err := executeCommand(dm, cmd)
 	if err != nil {
-        return nil, err
-    }
+		return nil, err
+	}
+
+	// Streamed results have already been written to the connection by the command
+	if res == nil {
+		return nil, nil
+	}
 
     return append(res, '\n'), nil
 }
+
+// executeCommand runs a command of the registry and returns its result unchanged
+func executeCommand(dm *dbmanager.DBManager, cmd *common.Command) ([]byte, error) {
+	spec, ok := commands.Get(cmd.Operation)
+	if !ok {
+		return nil, fmt.Errorf("unknown operation %q", cmd.Operation)
+	}
+	return spec.Handler(dm, cmd)
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"meteor/internal/config"
+	"meteor/internal/dbmanager"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// tlsHandshakeTimeout limits the handshake of new connections, so clients that stall it don't hold a goroutine
+const tlsHandshakeTimeout = 10 * time.Second
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsManager holds the server certificate and the CA of client certificates. They are read again on SIGHUP,
+// and new connections use the reloaded files while open connections keep theirs.
+type tlsManager struct {
+	certFile     string
+	keyFile      string
+	clientCaFile string
+	minVersion   uint16
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+}
+
+// newTlsManager loads the TLS files of the config. It returns nil if TLS isn't configured.
+func newTlsManager(cfg *config.MeteorDbConfig) (*tlsManager, error) {
+	if cfg.TlsCertFile == "" && cfg.TlsKeyFile == "" {
+		if cfg.TlsClientCaFile != "" {
+			return nil, errors.New("tlsClientCaFile needs tlsCertFile and tlsKeyFile")
+		}
+		return nil, nil
+	}
+	if cfg.TlsCertFile == "" || cfg.TlsKeyFile == "" {
+		return nil, errors.New("TLS needs both tlsCertFile and tlsKeyFile")
+	}
+
+	minVersion, ok := tlsVersions[cfg.TlsMinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tlsMinVersion %q, expected 1.0, 1.1, 1.2 or 1.3", cfg.TlsMinVersion)
+	}
+
+	m := &tlsManager{
+		certFile:     cfg.TlsCertFile,
+		keyFile:      cfg.TlsKeyFile,
+		clientCaFile: cfg.TlsClientCaFile,
+		minVersion:   minVersion,
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// load reads the certificate, key and client CA. The previous files stay in use if any of them is invalid.
+func (m *tlsManager) load() error {
+	certificate, err := tls.LoadX509KeyPair(m.certFile, m.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if m.clientCaFile != "" {
+		pem, err := os.ReadFile(m.clientCaFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS client CA: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in TLS client CA %s", m.clientCaFile)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.certificate = &certificate
+	m.clientCAs = clientCAs
+	return nil
+}
+
+// config returns the TLS config of the listeners. Every handshake gets the files loaded last. With a client
+// CA, clients must present a certificate it signed.
+func (m *tlsManager) config() *tls.Config {
+	return &tls.Config{
+		MinVersion: m.minVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			m.mu.RLock()
+			defer m.mu.RUnlock()
+
+			tlsConfig := &tls.Config{
+				MinVersion:   m.minVersion,
+				Certificates: []tls.Certificate{*m.certificate},
+			}
+			if m.clientCAs != nil {
+				tlsConfig.ClientCAs = m.clientCAs
+				tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return tlsConfig, nil
+		},
+	}
+}
+
+// reloadOnHangup reloads the TLS files whenever the process receives SIGHUP, e.g. after certificates were renewed
+func (m *tlsManager) reloadOnHangup(ctx context.Context) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			if err := m.load(); err != nil {
+				slog.Error("Failed to reload TLS files, keeping the previous ones", "error", err)
+				continue
+			}
+			slog.Info("Reloaded TLS files", "certFile", m.certFile)
+		}
+	}
+}
+
+// handshake completes the TLS handshake of a connection before it is handed to its protocol handler, so the
+// client certificate is known. Plain connections have nothing to do.
+func handshake(conn net.Conn) error {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	tlsConn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	defer tlsConn.SetDeadline(time.Time{})
+	return tlsConn.Handshake()
+}
+
+// certificateUser returns the common name of a verified client certificate, empty for connections without one
+func certificateUser(state *tls.ConnectionState) string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return state.VerifiedChains[0][0].Subject.CommonName
+}
+
+// loginCertificateUser runs the commands of a connection as the user its client certificate names, if the
+// user exists. Clients can still AUTH as another user.
+func loginCertificateUser(dm *dbmanager.DBManager, conn net.Conn, ref *net.Conn) {
+	if buffered, ok := conn.(*bufferedConn); ok {
+		conn = buffered.Conn
+	}
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return
+	}
+
+	state := tlsConn.ConnectionState()
+	name := certificateUser(&state)
+	if name == "" {
+		return
+	}
+	if _, ok := dm.AuthManager.User(name); ok {
+		dm.AuthManager.Login(ref, name)
+	}
+}